@@ -0,0 +1,37 @@
+// Command calibrate-argon2 picks Argon2id parameters for the machine it runs
+// on and prints them as environment variables for the server. Run it on the
+// production hardware, under the memory limits the server will have.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"runtime"
+	"time"
+	"todo-app/backend/internal/auth"
+)
+
+func main() {
+	target := flag.Duration("target", 500*time.Millisecond, "time one hash should take")
+	memory := flag.Uint("memory", uint(auth.DefaultArgon2Params.Memory), "memory per hash in KiB")
+	parallelism := flag.Uint("parallelism", uint(min(runtime.NumCPU(), 4)), "threads per hash")
+	flag.Parse()
+
+	if *parallelism < 1 || *parallelism > 255 {
+		log.Fatalf("parallelism must be between 1 and 255, got %d", *parallelism)
+	}
+	if *memory < 8*(*parallelism) {
+		log.Fatalf("memory must be at least %d KiB", 8*(*parallelism))
+	}
+
+	params, elapsed := auth.CalibrateArgon2(*target, uint32(*memory), uint8(*parallelism))
+	if elapsed < *target {
+		log.Printf("Warning: %v is the slowest hash reached; raise -memory to reach %v", elapsed, *target)
+	}
+
+	fmt.Printf("# One hash takes about %v\n", elapsed.Round(time.Millisecond))
+	fmt.Printf("PASSWORD_ARGON2_MEMORY=%d\n", params.Memory)
+	fmt.Printf("PASSWORD_ARGON2_ITERATIONS=%d\n", params.Iterations)
+	fmt.Printf("PASSWORD_ARGON2_PARALLELISM=%d\n", params.Parallelism)
+}
@@ -1,12 +1,25 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"math"
+	"os"
+	"strings"
+	"time"
+	"todo-app/backend/internal/auth"
 	"todo-app/backend/internal/config"
 	"todo-app/backend/internal/handler"
+	"todo-app/backend/internal/mail"
 	"todo-app/backend/internal/middleware"
+	"todo-app/backend/internal/model"
+	"todo-app/backend/internal/oidc"
+	"todo-app/backend/internal/password"
+	"todo-app/backend/internal/ratelimit"
 	"todo-app/backend/internal/service"
+	"todo-app/backend/internal/storage"
+	"todo-app/backend/internal/webauthn"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -18,26 +31,247 @@ func main() {
 
 	// Initialize Hasura GraphQL client
 	hasuraClient := service.NewHasuraClient(cfg.HasuraEndpoint, cfg.HasuraAdminSecret)
+	if cfg.HasuraForwardUserTokens {
+		hasuraClient.EnableTokenForwarding()
+	}
+
+	// Initialize attachment storage
+	blobStore, err := newBlobStore(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize attachment storage: %v", err)
+	}
+
+	switch cfg.JWTSigningAlgorithm {
+	case auth.AlgorithmRS256, auth.AlgorithmEdDSA:
+	default:
+		log.Fatalf("Unsupported JWT signing algorithm %q", cfg.JWTSigningAlgorithm)
+	}
+
+	// Signed attachment links must verify on every instance. Without a
+	// configured secret they are signed with a per-process one and stop
+	// working when the process restarts, so production requires one.
+	attachmentURLSecret := cfg.AttachmentURLSecret
+	if attachmentURLSecret == "" {
+		if gin.Mode() == gin.ReleaseMode {
+			log.Fatalf("ATTACHMENT_URL_SECRET must be set when GIN_MODE=release")
+		}
+		log.Printf("ATTACHMENT_URL_SECRET is not set; using a random secret for this process")
+		if attachmentURLSecret, err = auth.GenerateOpaqueToken(); err != nil {
+			log.Fatalf("Failed to generate attachment URL secret: %v", err)
+		}
+	}
+
+	apiLimit, err := ratelimit.ParseLimit(cfg.RateLimitAPI)
+	if err != nil {
+		log.Fatalf("Invalid RATE_LIMIT_API: %v", err)
+	}
+	adminLimit, err := ratelimit.ParseLimit(cfg.RateLimitAdmin)
+	if err != nil {
+		log.Fatalf("Invalid RATE_LIMIT_ADMIN: %v", err)
+	}
+	registerLimit, err := ratelimit.ParseLimit(cfg.RateLimitRegister)
+	if err != nil {
+		log.Fatalf("Invalid RATE_LIMIT_REGISTER: %v", err)
+	}
+	rateLimits := ratelimit.NewMemoryStore()
+
+	if cfg.PasswordMinScore < 0 || cfg.PasswordMinScore > 4 {
+		log.Fatalf("PASSWORD_MIN_SCORE must be between 0 and 4, got %d", cfg.PasswordMinScore)
+	}
+	passwordPolicy := password.Policy{
+		MinLength:     cfg.PasswordMinLength,
+		MaxLength:     cfg.PasswordMaxLength,
+		MinScore:      cfg.PasswordMinScore,
+		DisallowEmail: cfg.PasswordDisallowEmail,
+	}
+	if cfg.PasswordBreachDir != "" {
+		passwordPolicy.Breaches = password.NewRangeFiles(cfg.PasswordBreachDir)
+	}
+
+	switch cfg.UnverifiedAccountPolicy {
+	case middleware.UnverifiedAllow, middleware.UnverifiedNoSharing, middleware.UnverifiedReadOnly:
+	default:
+		log.Fatalf("Unknown unverified account policy %q", cfg.UnverifiedAccountPolicy)
+	}
+
+	// Initialize outgoing mail
+	baseMailer, err := newMailer(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize mailer: %v", err)
+	}
+	mailer := mail.Async(baseMailer, cfg.MailTimeout, func(msg mail.Message, err error) {
+		log.Printf("Failed to send mail to %s: %v", msg.To, err)
+	})
 
 	// Initialize services
-	authService := service.NewAuthService(hasuraClient, cfg.JWTSecret)
+	keyringService := service.NewKeyringService(hasuraClient, service.KeyringOptions{
+		Algorithm:        cfg.JWTSigningAlgorithm,
+		RotationInterval: cfg.JWTKeyRotationInterval,
+		ActivationDelay:  cfg.JWTKeyActivationDelay,
+		AccessTokenTTL:   cfg.AccessTokenTTL,
+	})
+	if _, err := keyringService.Sync(); err != nil {
+		log.Fatalf("Failed to load signing keys: %v", err)
+	}
+	keyring := keyringService.Keyring()
+	revocationService := service.NewRevocationService(hasuraClient, service.RevocationOptions{
+		CacheSize:      cfg.RevocationCacheSize,
+		CacheTTL:       cfg.RevocationCacheTTL,
+		AccessTokenTTL: cfg.AccessTokenTTL,
+	})
+	sessionService := service.NewSessionService(hasuraClient, revocationService, service.SessionOptions{
+		TouchInterval: cfg.SessionTouchInterval,
+		CacheSize:     cfg.RevocationCacheSize,
+	})
+	passwordHasher, err := newPasswordHasher(cfg)
+	if err != nil {
+		log.Fatalf("Invalid password hashing parameters: %v", err)
+	}
+
+	accountService := service.NewAccountService(hasuraClient, mailer, revocationService, service.AccountOptions{
+		AppURL:                     cfg.AppURL,
+		PasswordResetTTL:           cfg.PasswordResetTTL,
+		ForgotPasswordDuration:     cfg.ForgotPasswordDuration,
+		VerificationTTL:            cfg.VerificationTTL,
+		VerificationResendInterval: cfg.VerificationResendInterval,
+		VerificationDailyLimit:     cfg.VerificationDailyLimit,
+		DeletionGracePeriod:        cfg.AccountDeletionGracePeriod,
+		PasswordPolicy:             passwordPolicy,
+		PasswordHasher:             passwordHasher,
+	})
+	mfaService := service.NewMFAService(hasuraClient, accountService, service.MFAOptions{
+		Issuer:       cfg.MFAIssuer,
+		ChallengeTTL: cfg.MFAChallengeTTL,
+		MaxAttempts:  cfg.MFAMaxAttempts,
+	})
+	webauthnService := service.NewWebAuthnService(hasuraClient, accountService, &webauthn.Config{
+		RPID:    cfg.WebAuthnRPID,
+		RPName:  cfg.WebAuthnRPName,
+		Origins: cfg.WebAuthnOrigins,
+		Timeout: int(cfg.WebAuthnChallengeTTL.Milliseconds()),
+	}, service.WebAuthnOptions{
+		ChallengeTTL: cfg.WebAuthnChallengeTTL,
+	})
+	oidcProviders, err := newOIDCProviders(cfg)
+	if err != nil {
+		log.Fatalf("Failed to configure identity providers: %v", err)
+	}
+	auditService := service.NewAuditService(hasuraClient)
+	oidcService := service.NewOIDCService(hasuraClient, oidcProviders, auditService, service.OIDCOptions{
+		StateTTL:       cfg.OIDCStateTTL,
+		PasswordHasher: passwordHasher,
+	})
+	lockoutStore, err := newLockoutStore(cfg, hasuraClient)
+	if err != nil {
+		log.Fatalf("Failed to initialize login lockout: %v", err)
+	}
+	lockoutService := service.NewLockoutService(lockoutStore, service.LockoutOptions{
+		AccountThreshold: cfg.LoginLockoutThreshold,
+		IPThreshold:      cfg.LoginLockoutIPThreshold,
+		BaseDuration:     cfg.LoginLockoutDuration,
+		MaxDuration:      cfg.LoginLockoutMaxDuration,
+		FailureWindow:    cfg.LoginLockoutFailureWindow,
+	})
+	authService := service.NewAuthService(hasuraClient, keyring, revocationService, sessionService, accountService, mfaService, webauthnService, oidcService, lockoutService, service.AuthOptions{
+		AccessTokenTTL:   cfg.AccessTokenTTL,
+		RefreshTokenTTL:  cfg.RefreshTokenTTL,
+		MFARequiredRoles: cfg.MFARequiredRoles,
+	})
+	roleService := service.NewRoleService(hasuraClient, service.RoleOptions{
+		CacheSize: cfg.RevocationCacheSize,
+		CacheTTL:  cfg.RevocationCacheTTL,
+	})
+	accessTokenService := service.NewAccessTokenService(hasuraClient, roleService, service.AccessTokenOptions{
+		TouchInterval: cfg.SessionTouchInterval,
+		CacheSize:     cfg.RevocationCacheSize,
+	})
 	todoService := service.NewTodoService(hasuraClient)
-	userService := service.NewUserService(hasuraClient)
+	userService := service.NewUserService(hasuraClient, revocationService, sessionService, mfaService, roleService, auditService)
+	impersonationService := service.NewImpersonationService(keyring, userService, roleService, auditService, service.ImpersonationOptions{
+		TokenTTL: cfg.ImpersonationTTL,
+	})
+	commentService := service.NewCommentService(hasuraClient, todoService)
+	shareService := service.NewShareService(hasuraClient, todoService)
+	workspaceService := service.NewWorkspaceService(hasuraClient)
+	attachmentService := service.NewAttachmentService(hasuraClient, todoService, blobStore, service.AttachmentOptions{
+		MaxBytes:  cfg.AttachmentMaxBytes,
+		URLSecret: attachmentURLSecret,
+		URLTTL:    cfg.AttachmentURLTTL,
+	})
 
 	// Initialize handlers
 	authHandler := handler.NewAuthHandler(authService)
+	jwksHandler := handler.NewJWKSHandler(keyring, cfg.JWTKeyActivationDelay/2)
+	sessionHandler := handler.NewSessionHandler(sessionService)
+	accountHandler := handler.NewAccountHandler(accountService)
+	mfaHandler := handler.NewMFAHandler(mfaService)
+	webauthnHandler := handler.NewWebAuthnHandler(webauthnService, authService)
+	oidcHandler := handler.NewOIDCHandler(oidcService, authService)
+	accessTokenHandler := handler.NewAccessTokenHandler(accessTokenService)
 	todoHandler := handler.NewTodoHandler(todoService)
 	adminHandler := handler.NewAdminHandler(userService)
+	roleHandler := handler.NewRoleHandler(roleService)
+	lockoutHandler := handler.NewLockoutHandler(lockoutService)
+	impersonationHandler := handler.NewImpersonationHandler(impersonationService, auditService)
+	auditHandler := handler.NewAuditHandler(auditService)
+	commentHandler := handler.NewCommentHandler(commentService)
+	attachmentHandler := handler.NewAttachmentHandler(attachmentService)
+	shareHandler := handler.NewShareHandler(shareService)
+	workspaceHandler := handler.NewWorkspaceHandler(workspaceService)
+
+	// Pick up keys added by other instances, and rotate when due
+	go runPeriodically(cfg.JWTKeySyncInterval, func() {
+		rotated, err := keyringService.Sync()
+		if err != nil {
+			log.Printf("Failed to sync signing keys: %v", err)
+		} else if rotated {
+			log.Printf("Rotated access token signing key")
+		}
+	})
+
+	// Remove blobs left behind by deleted todos
+	go runPeriodically(cfg.AttachmentCleanupInterval, func() {
+		if _, err := attachmentService.PurgeOrphans(context.Background()); err != nil {
+			log.Printf("Failed to purge orphaned attachments: %v", err)
+		}
+	})
+
+	// Forget revocations of tokens that have expired anyway, challenges of
+	// abandoned passkey and identity provider logins, and old failed logins
+	go runPeriodically(cfg.RevocationCleanupInterval, func() {
+		if _, err := revocationService.PurgeExpired(); err != nil {
+			log.Printf("Failed to purge expired token revocations: %v", err)
+		}
+		if _, err := webauthnService.PurgeExpiredChallenges(); err != nil {
+			log.Printf("Failed to purge expired passkey challenges: %v", err)
+		}
+		if _, err := oidcService.PurgeExpiredStates(); err != nil {
+			log.Printf("Failed to purge expired identity provider logins: %v", err)
+		}
+		if _, err := lockoutService.PurgeExpired(); err != nil {
+			log.Printf("Failed to purge expired login failures: %v", err)
+		}
+	})
+
+	// Remove accounts whose deletion grace period has passed
+	go runPeriodically(cfg.AccountPurgeInterval, func() {
+		if _, err := accountService.PurgeDeletedAccounts(); err != nil {
+			log.Printf("Failed to purge deleted accounts: %v", err)
+		}
+	})
 
 	// Initialize Gin router
-	r := gin.Default()
+	r, err := newRouter(cfg)
+	if err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
 
 	// CORS middleware
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:3000", "http://frontend:3000"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", middleware.WorkspaceHeader},
+		ExposeHeaders:    []string{"Content-Length", "Content-Disposition", "Retry-After", "RateLimit-Policy", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", middleware.ImpersonatedByHeader, handler.TotalCountHeader},
 		AllowCredentials: true,
 	}))
 
@@ -46,41 +280,184 @@ func main() {
 		c.JSON(200, gin.H{"status": "ok"})
 	})
 
-	// Public routes
+	// Access token verification keys, for Hasura and other services
+	r.GET("/.well-known/jwks.json", jwksHandler.GetJWKS)
+
+	// Public routes, limited per client address
 	public := r.Group("/api")
+	public.Use(middleware.RateLimit(rateLimits, "api", apiLimit))
 	{
-		public.POST("/register", authHandler.Register)
+		public.POST("/register", middleware.RateLimit(rateLimits, "register", registerLimit), authHandler.Register)
 		public.POST("/login", authHandler.Login)
+		public.POST("/login/mfa", authHandler.VerifyMFA)
+		public.POST("/webauthn/login/begin", webauthnHandler.BeginLogin)
+		public.POST("/webauthn/login/finish", webauthnHandler.FinishLogin)
+		public.GET("/oidc/providers", oidcHandler.GetProviders)
+		public.GET("/oidc/:provider/login", oidcHandler.BeginLogin)
+		public.POST("/oidc/callback", oidcHandler.Callback)
+		public.POST("/token/refresh", authHandler.RefreshToken)
+		public.POST("/password/forgot", accountHandler.ForgotPassword)
+		public.POST("/password/reset", accountHandler.ResetPassword)
+		public.POST("/email/verify", accountHandler.VerifyEmail)
+
+		// Signed attachment downloads
+		public.GET("/attachments/:id/download", attachmentHandler.DownloadAttachment)
 	}
 
-	// Protected routes
+	// Protected routes, limited per user
 	protected := r.Group("/api")
-	protected.Use(middleware.AuthMiddleware(cfg.JWTSecret))
+	protected.Use(middleware.AuthMiddleware(keyring, revocationService, sessionService, accessTokenService))
+	protected.Use(middleware.Impersonation(auditService))
+	protected.Use(middleware.RateLimit(rateLimits, "api", apiLimit))
+	protected.Use(middleware.WorkspaceMiddleware(workspaceService))
+
+	// Access tokens may read the profile, so scripts can check who they act
+	// as
+	protected.GET("/profile", middleware.RequireScopes(auth.ScopeProfileRead), authHandler.GetProfile)
+
+	// Managing the account and its credentials, access tokens included,
+	// needs a signed-in session of the user themselves
+	account := protected.Group("")
+	account.Use(middleware.RequireSession())
+	account.Use(middleware.RejectImpersonation())
 	{
+		// Session
+		account.POST("/logout", authHandler.Logout)
+		account.POST("/logout-all", authHandler.LogoutAll)
+		account.GET("/sessions", sessionHandler.GetSessions)
+		account.DELETE("/sessions/:id", sessionHandler.DeleteSession)
+
 		// User profile
-		protected.GET("/profile", authHandler.GetProfile)
+		account.PUT("/profile/password", accountHandler.ChangePassword)
+		account.PUT("/profile/email", accountHandler.ChangeEmail)
+		account.DELETE("/profile", accountHandler.DeleteAccount)
+		account.GET("/profile/oidc/:provider/link", oidcHandler.BeginLink)
+		account.POST("/email/resend", accountHandler.ResendVerification)
+		account.GET("/profile/impersonations", impersonationHandler.GetImpersonations)
+
+		// Two-factor authentication
+		account.GET("/mfa", mfaHandler.GetStatus)
+		account.POST("/mfa/totp", mfaHandler.BeginTOTP)
+		account.POST("/mfa/totp/confirm", mfaHandler.ConfirmTOTP)
+		account.POST("/mfa/recovery-codes", mfaHandler.RegenerateRecoveryCodes)
+		account.DELETE("/mfa", mfaHandler.Disable)
+
+		// Passkeys
+		account.POST("/webauthn/register/begin", webauthnHandler.BeginRegistration)
+		account.POST("/webauthn/register/finish", webauthnHandler.FinishRegistration)
+		account.GET("/webauthn/credentials", webauthnHandler.ListCredentials)
+		account.DELETE("/webauthn/credentials/:id", webauthnHandler.DeleteCredential)
+
+		// Personal access tokens
+		account.GET("/tokens", accessTokenHandler.GetTokens)
+		account.POST("/tokens", accessTokenHandler.CreateToken)
+		account.DELETE("/tokens/:id", accessTokenHandler.DeleteToken)
+	}
 
+	// Roles in MFA_REQUIRED_ROLES must pass a second factor before they can
+	// reach content or administration; the routes above stay open so they
+	// can enroll
+	requireMFA := middleware.RequireMFA(cfg.MFARequiredRoles)
+
+	// Content routes are subject to the unverified-account policy
+	content := protected.Group("")
+	content.Use(requireMFA)
+	content.Use(middleware.UnverifiedAccountMiddleware(cfg.UnverifiedAccountPolicy, false))
+	sharing := middleware.UnverifiedAccountMiddleware(cfg.UnverifiedAccountPolicy, true)
+
+	todos := content.Group("")
+	todos.Use(middleware.RequireReadWriteScopes(auth.ScopeTodosRead, auth.ScopeTodosWrite))
+	{
 		// Todo routes
-		protected.GET("/todos", todoHandler.GetTodos)
-		protected.GET("/todos/:id", todoHandler.GetTodo)
-		protected.POST("/todos", todoHandler.CreateTodo)
-		protected.PUT("/todos/:id", todoHandler.UpdateTodo)
-		protected.DELETE("/todos/:id", todoHandler.DeleteTodo)
+		todos.GET("/todos", todoHandler.GetTodos)
+		todos.GET("/todos/:id", todoHandler.GetTodo)
+		todos.POST("/todos", todoHandler.CreateTodo)
+		todos.PUT("/todos/:id", todoHandler.UpdateTodo)
+		todos.DELETE("/todos/:id", todoHandler.DeleteTodo)
+		todos.PUT("/todos/:id/assignee", todoHandler.AssignTodo)
+		todos.GET("/todos/:id/assignments", todoHandler.GetAssignmentEvents)
+		todos.GET("/inbox", todoHandler.GetInbox)
+
+		// Comment routes
+		todos.GET("/todos/:id/comments", commentHandler.GetComments)
+		todos.POST("/todos/:id/comments", commentHandler.CreateComment)
+		todos.PUT("/todos/:id/comments/:commentId", commentHandler.UpdateComment)
+		todos.DELETE("/todos/:id/comments/:commentId", commentHandler.DeleteComment)
+
+		// Attachment routes
+		todos.GET("/todos/:id/attachments", attachmentHandler.GetAttachments)
+		todos.GET("/todos/:id/attachments/:attachmentId", attachmentHandler.GetAttachment)
+		todos.POST("/todos/:id/attachments", attachmentHandler.UploadAttachment)
+		todos.DELETE("/todos/:id/attachments/:attachmentId", attachmentHandler.DeleteAttachment)
+
+		// Share routes
+		todos.GET("/shares", shareHandler.GetShares)
+		todos.POST("/shares", sharing, shareHandler.CreateShare)
+		todos.DELETE("/shares/:id", shareHandler.DeleteShare)
 	}
 
-	// Admin routes
+	workspaces := content.Group("")
+	workspaces.Use(middleware.RequireReadWriteScopes(auth.ScopeWorkspacesRead, auth.ScopeWorkspacesWrite))
+	{
+		// Workspace routes
+		workspaces.GET("/workspaces", workspaceHandler.GetWorkspaces)
+		workspaces.POST("/workspaces", workspaceHandler.CreateWorkspace)
+		workspaces.GET("/workspaces/:workspaceId", workspaceHandler.GetWorkspace)
+		workspaces.PUT("/workspaces/:workspaceId", middleware.RequireWorkspaceRole(model.WorkspaceRoleOwner, model.WorkspaceRoleAdmin), workspaceHandler.UpdateWorkspace)
+		workspaces.DELETE("/workspaces/:workspaceId", middleware.RequireWorkspaceRole(model.WorkspaceRoleOwner), workspaceHandler.DeleteWorkspace)
+		workspaces.GET("/workspaces/:workspaceId/members", workspaceHandler.GetMembers)
+		workspaces.POST("/workspaces/:workspaceId/members", sharing, middleware.RequireWorkspaceRole(model.WorkspaceRoleOwner, model.WorkspaceRoleAdmin), workspaceHandler.AddMember)
+		workspaces.PUT("/workspaces/:workspaceId/members/:userId", middleware.RequireWorkspaceRole(model.WorkspaceRoleOwner, model.WorkspaceRoleAdmin), workspaceHandler.UpdateMember)
+		workspaces.DELETE("/workspaces/:workspaceId/members/:userId", workspaceHandler.RemoveMember)
+	}
+
+	// Admin routes, each limited to the roles granting its permission
 	admin := r.Group("/api/admin")
-	admin.Use(middleware.AuthMiddleware(cfg.JWTSecret))
-	admin.Use(middleware.AdminMiddleware())
+	admin.Use(middleware.AuthMiddleware(keyring, revocationService, sessionService, accessTokenService))
+	admin.Use(middleware.Impersonation(auditService))
+	admin.Use(middleware.RejectImpersonation())
+	admin.Use(middleware.RateLimit(rateLimits, "admin", adminLimit))
+	admin.Use(requireMFA)
+	admin.Use(middleware.RequireScopes(auth.ScopeAdmin))
+	usersRead := middleware.RequirePermission(roleService, auth.PermissionUsersRead)
+	usersWrite := middleware.RequirePermission(roleService, auth.PermissionUsersWrite)
+	todosReadAll := middleware.RequirePermission(roleService, auth.PermissionTodosReadAll)
+	todosWriteAll := middleware.RequirePermission(roleService, auth.PermissionTodosWriteAll)
+	rolesManage := middleware.RequirePermission(roleService, auth.PermissionRolesManage)
+	usersImpersonate := middleware.RequirePermission(roleService, auth.PermissionUsersImpersonate)
+	auditRead := middleware.RequirePermission(roleService, auth.PermissionAuditRead)
 	{
 		// User management
-		admin.GET("/users", adminHandler.GetAllUsers)
-		admin.GET("/users/:id", adminHandler.GetUser)
-		admin.PUT("/users/:id/role", adminHandler.UpdateUserRole)
-		admin.DELETE("/users/:id", adminHandler.DeleteUser)
+		admin.GET("/users", usersRead, adminHandler.SearchUsers)
+		admin.GET("/users/:id", usersRead, adminHandler.GetUser)
+		admin.PUT("/users/:id/role", usersWrite, adminHandler.UpdateUserRole)
+		admin.DELETE("/users/:id", usersWrite, adminHandler.DeleteUser)
+		admin.POST("/users/:id/reassign-todos", usersWrite, todosWriteAll, adminHandler.ReassignTodos)
+		admin.POST("/users/:id/revoke-sessions", usersWrite, adminHandler.RevokeSessions)
+		admin.GET("/users/:id/sessions", usersRead, adminHandler.GetUserSessions)
+		admin.DELETE("/users/:id/sessions/:sessionId", usersWrite, adminHandler.DeleteUserSession)
+		admin.DELETE("/users/:id/mfa", usersWrite, adminHandler.ResetUserMFA)
+		admin.POST("/users/:id/suspend", usersWrite, adminHandler.SuspendUser)
+		admin.POST("/users/:id/reactivate", usersWrite, adminHandler.ReactivateUser)
+		admin.POST("/users/:id/impersonate", middleware.RequireSession(), usersImpersonate, impersonationHandler.Impersonate)
+
+		// Audit log
+		admin.GET("/audit-logs", auditRead, auditHandler.GetAuditLogs)
+
+		// Login lockouts
+		admin.GET("/lockouts", usersRead, lockoutHandler.GetLockouts)
+		admin.DELETE("/lockouts/:scope/:subject", usersWrite, lockoutHandler.DeleteLockout)
 
 		// All todos
-		admin.GET("/todos", adminHandler.GetAllTodos)
+		admin.GET("/todos", todosReadAll, adminHandler.GetAllTodos)
+		admin.PUT("/todos/:id/assignee", todosWriteAll, adminHandler.AssignTodo)
+
+		// Roles
+		admin.GET("/permissions", rolesManage, roleHandler.GetPermissions)
+		admin.GET("/roles", rolesManage, roleHandler.GetRoles)
+		admin.POST("/roles", rolesManage, roleHandler.CreateRole)
+		admin.PUT("/roles/:name", rolesManage, roleHandler.UpdateRole)
+		admin.DELETE("/roles/:name", rolesManage, roleHandler.DeleteRole)
 	}
 
 	// Start server
@@ -90,3 +467,133 @@ func main() {
 		log.Fatalf("Failed to start server: %v", err)
 	}
 }
+
+// newRouter creates the Gin engine. Client addresses, which lockouts, rate
+// limits, sessions and the audit log rely on, are only taken from
+// X-Forwarded-For when the request comes through a trusted proxy.
+func newRouter(cfg *config.Config) (*gin.Engine, error) {
+	r := gin.Default()
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// newMailer selects how outgoing email is delivered from configuration
+func newMailer(cfg *config.Config) (mail.Mailer, error) {
+	switch cfg.MailBackend {
+	case "log":
+		return mail.NewLogMailer(os.Stdout, cfg.MailFrom), nil
+	case "file":
+		return mail.NewFileMailer(cfg.MailFileDir, cfg.MailFrom)
+	case "smtp":
+		return mail.NewSMTPMailer(mail.SMTPConfig{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.MailFrom,
+		}), nil
+	default:
+		return nil, fmt.Errorf("unknown mail backend %q", cfg.MailBackend)
+	}
+}
+
+// newBlobStore selects the attachment storage backend from configuration
+func newBlobStore(cfg *config.Config) (storage.BlobStore, error) {
+	switch cfg.StorageBackend {
+	case "local":
+		return storage.NewLocalStore(cfg.StorageLocalDir)
+	case "s3":
+		return storage.NewS3Store(storage.S3Config{
+			Endpoint:        cfg.S3Endpoint,
+			Region:          cfg.S3Region,
+			Bucket:          cfg.S3Bucket,
+			AccessKeyID:     cfg.S3AccessKeyID,
+			SecretAccessKey: cfg.S3SecretAccessKey,
+		}), nil
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", cfg.StorageBackend)
+	}
+}
+
+// newLockoutStore selects where failed logins are counted
+func newLockoutStore(cfg *config.Config, hasura *service.HasuraClient) (service.LockoutStore, error) {
+	switch cfg.LoginLockoutStore {
+	case "memory":
+		return service.NewMemoryLockoutStore(), nil
+	case "postgres":
+		return service.NewPostgresLockoutStore(hasura), nil
+	default:
+		return nil, fmt.Errorf("unknown lockout store %q", cfg.LoginLockoutStore)
+	}
+}
+
+// newPasswordHasher builds the Argon2id hasher new passwords are stored with
+func newPasswordHasher(cfg *config.Config) (*auth.PasswordHasher, error) {
+	if cfg.PasswordArgon2Parallelism < 1 || cfg.PasswordArgon2Parallelism > 255 {
+		return nil, fmt.Errorf("PASSWORD_ARGON2_PARALLELISM must be between 1 and 255, got %d", cfg.PasswordArgon2Parallelism)
+	}
+	if cfg.PasswordArgon2Iterations < 1 || cfg.PasswordArgon2Iterations > math.MaxUint32 {
+		return nil, fmt.Errorf("PASSWORD_ARGON2_ITERATIONS must be positive, got %d", cfg.PasswordArgon2Iterations)
+	}
+	// Argon2 needs at least 8 KiB per lane
+	if cfg.PasswordArgon2Memory < 8*cfg.PasswordArgon2Parallelism || cfg.PasswordArgon2Memory > math.MaxUint32 {
+		return nil, fmt.Errorf("PASSWORD_ARGON2_MEMORY must be at least %d KiB, got %d", 8*cfg.PasswordArgon2Parallelism, cfg.PasswordArgon2Memory)
+	}
+
+	params := auth.DefaultArgon2Params
+	params.Memory = uint32(cfg.PasswordArgon2Memory)
+	params.Iterations = uint32(cfg.PasswordArgon2Iterations)
+	params.Parallelism = uint8(cfg.PasswordArgon2Parallelism)
+	return auth.NewPasswordHasher(params), nil
+}
+
+// newOIDCProviders builds the identity providers users can sign in with
+func newOIDCProviders(cfg *config.Config) ([]service.OIDCProvider, error) {
+	providers := make([]service.OIDCProvider, 0, len(cfg.OIDCProviders))
+	for _, p := range cfg.OIDCProviders {
+		if p.Issuer == "" || p.ClientID == "" {
+			return nil, fmt.Errorf("provider %q needs an issuer and a client id", p.Name)
+		}
+
+		mappings := make([]service.RoleMapping, 0, len(p.RoleMap))
+		for _, entry := range p.RoleMap {
+			value, role, ok := strings.Cut(entry, "=")
+			if !ok || value == "" || role == "" {
+				return nil, fmt.Errorf("provider %q: role mapping %q is not claim-value=role", p.Name, entry)
+			}
+			mappings = append(mappings, service.RoleMapping{Value: value, Role: role})
+		}
+
+		providers = append(providers, service.OIDCProvider{
+			Name: p.Name,
+			Provider: oidc.NewProvider(oidc.Config{
+				Issuer:       p.Issuer,
+				ClientID:     p.ClientID,
+				ClientSecret: p.ClientSecret,
+				RedirectURL:  cfg.OIDCRedirectURL,
+				Scopes:       p.Scopes,
+			}, nil),
+			Trusted:      p.Trusted,
+			RoleClaim:    p.RoleClaim,
+			RoleMappings: mappings,
+		})
+	}
+	return providers, nil
+}
+
+// runPeriodically calls fn every interval until the process exits. A
+// non-positive interval disables the job.
+func runPeriodically(interval time.Duration, fn func()) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		fn()
+	}
+}
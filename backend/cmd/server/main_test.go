@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"todo-app/backend/internal/config"
+	"todo-app/backend/internal/middleware"
+	"todo-app/backend/internal/ratelimit"
+	"todo-app/backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestNewRouter_ClientAddress(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newLogin := func(t *testing.T, cfg *config.Config) *gin.Engine {
+		r, err := newRouter(cfg)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		// Every login fails, the way the lockout counts them
+		lockouts := service.NewLockoutService(service.NewMemoryLockoutStore(), service.LockoutOptions{
+			AccountThreshold: 100,
+			IPThreshold:      3,
+			BaseDuration:     time.Minute,
+			MaxDuration:      time.Hour,
+			FailureWindow:    24 * time.Hour,
+		})
+		r.POST("/login", func(c *gin.Context) {
+			email := c.Query("email")
+			if err := lockouts.Check(email, c.ClientIP()); err != nil {
+				c.Status(http.StatusTooManyRequests)
+				return
+			}
+			lockouts.RecordFailure(email, c.ClientIP())
+			c.Header("X-Client-IP", c.ClientIP())
+			c.Status(http.StatusUnauthorized)
+		})
+		return r
+	}
+
+	login := func(r *gin.Engine, i int) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/login?email=user%d@example.com", i), nil)
+		req.Header.Set("X-Forwarded-For", fmt.Sprintf("203.0.113.%d", i))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("spoofed forwarded address", func(t *testing.T) {
+		r := newLogin(t, &config.Config{})
+		for i := 0; i < 3; i++ {
+			if w := login(r, i); w.Code != http.StatusUnauthorized || w.Header().Get("X-Client-IP") != "192.0.2.1" {
+				t.Fatalf("attempt %d: expected the connection's address, got %d from %q", i, w.Code, w.Header().Get("X-Client-IP"))
+			}
+		}
+		if w := login(r, 3); w.Code != http.StatusTooManyRequests {
+			t.Fatalf("expected the address to be locked out, got %d", w.Code)
+		}
+	})
+
+	t.Run("trusted proxy", func(t *testing.T) {
+		r := newLogin(t, &config.Config{TrustedProxies: []string{"192.0.2.0/24"}})
+		for i := 0; i < 4; i++ {
+			if w := login(r, i); w.Code != http.StatusUnauthorized || w.Header().Get("X-Client-IP") != fmt.Sprintf("203.0.113.%d", i) {
+				t.Fatalf("attempt %d: expected the forwarded address, got %d from %q", i, w.Code, w.Header().Get("X-Client-IP"))
+			}
+		}
+	})
+}
+
+func TestNewRouter_RateLimitByAddress(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r, err := newRouter(&config.Config{})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	limit, err := ratelimit.ParseLimit("2/1m")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	r.POST("/register", middleware.RateLimit(ratelimit.NewMemoryStore(), "register", limit), func(c *gin.Context) {
+		c.Status(http.StatusCreated)
+	})
+
+	// A client rotating X-Forwarded-For still shares one bucket
+	codes := make([]int, 0, 3)
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/register", nil)
+		req.Header.Set("X-Forwarded-For", fmt.Sprintf("203.0.113.%d", i))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		codes = append(codes, w.Code)
+	}
+	if codes[0] != http.StatusCreated || codes[1] != http.StatusCreated || codes[2] != http.StatusTooManyRequests {
+		t.Fatalf("expected the third request to be limited, got %v", codes)
+	}
+}
+
+func TestRunPeriodically_Disabled(t *testing.T) {
+	for _, interval := range []time.Duration{0, -time.Minute} {
+		// Returns instead of panicking in time.NewTicker
+		runPeriodically(interval, func() {
+			t.Fatalf("expected a disabled job not to run")
+		})
+	}
+}
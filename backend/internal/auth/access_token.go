@@ -0,0 +1,51 @@
+package auth
+
+import "strings"
+
+// AccessTokenPrefix starts every personal access token, so leaked tokens are
+// easy to recognise and scan for
+const AccessTokenPrefix = "tdp_"
+
+// accessTokenDisplayLength is how much of a token is kept to identify it in
+// listings
+const accessTokenDisplayLength = len(AccessTokenPrefix) + 6
+
+// Scopes a personal access token can be limited to
+const (
+	ScopeTodosRead       = "todos:read"
+	ScopeTodosWrite      = "todos:write"
+	ScopeWorkspacesRead  = "workspaces:read"
+	ScopeWorkspacesWrite = "workspaces:write"
+	ScopeProfileRead     = "profile:read"
+	ScopeAdmin           = "admin"
+)
+
+// Scopes lists every scope, in the order they are documented
+var Scopes = []string{ScopeTodosRead, ScopeTodosWrite, ScopeWorkspacesRead, ScopeWorkspacesWrite, ScopeProfileRead, ScopeAdmin}
+
+// ValidScope reports whether scope is one of Scopes
+func ValidScope(scope string) bool {
+	for _, s := range Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// GenerateAccessToken returns a new personal access token and the leading
+// part of it that is safe to display
+func GenerateAccessToken() (token, displayPrefix string, err error) {
+	random, err := GenerateOpaqueToken()
+	if err != nil {
+		return "", "", err
+	}
+	token = AccessTokenPrefix + random
+	return token, token[:accessTokenDisplayLength], nil
+}
+
+// IsAccessToken reports whether a bearer token is a personal access token
+// rather than a JWT
+func IsAccessToken(token string) bool {
+	return strings.HasPrefix(token, AccessTokenPrefix)
+}
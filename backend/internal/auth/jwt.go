@@ -8,37 +8,100 @@ import (
 	"github.com/google/uuid"
 )
 
+// Claims are the access token claims. The registered ID (jti) identifies the
+// token for revocation and SessionID ties it to the login it was issued for.
+// MFA is set when that login passed a second factor. Scopes is only set for
+// personal access tokens, which never travel as JWTs; a session has every
+// scope. Hasura, when set, lets the token be sent to Hasura directly. Act,
+// when set, names the administrator impersonating the user.
 type Claims struct {
-	UserID uuid.UUID `json:"user_id"`
-	Email  string    `json:"email"`
-	Role   string    `json:"role"`
+	UserID        uuid.UUID     `json:"user_id"`
+	SessionID     uuid.UUID     `json:"sid"`
+	Email         string        `json:"email"`
+	EmailVerified bool          `json:"email_verified"`
+	Role          string        `json:"role"`
+	MFA           bool          `json:"mfa"`
+	Scopes        []string      `json:"-"`
+	Hasura        *HasuraClaims `json:"https://hasura.io/jwt/claims,omitempty"`
+	Act           *Actor        `json:"act,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func GenerateToken(userID uuid.UUID, email, role, secret string) (string, error) {
-	claims := &Claims{
-		UserID: userID,
-		Email:  email,
-		Role:   role,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-		},
+// Actor is the party acting on the subject's behalf, as in the act claim of
+// RFC 8693
+type Actor struct {
+	Subject uuid.UUID `json:"sub"`
+	Email   string    `json:"email"`
+}
+
+// Impersonated reports whether someone other than the subject is acting
+func (c *Claims) Impersonated() bool {
+	return c.Act != nil
+}
+
+// HasuraRoleUser is the only Hasura role tokens carry. Administration goes
+// through the backend, which checks permissions, suspensions and the audit
+// log that Hasura knows nothing about.
+const HasuraRoleUser = "user"
+
+// HasuraClaims are the session variables Hasura reads from its claims
+// namespace and evaluates permissions against
+type HasuraClaims struct {
+	AllowedRoles []string `json:"x-hasura-allowed-roles"`
+	DefaultRole  string   `json:"x-hasura-default-role"`
+	UserID       string   `json:"x-hasura-user-id"`
+}
+
+// NewHasuraClaims lets the user act in Hasura's "user" role, whatever their
+// role here
+func NewHasuraClaims(userID uuid.UUID) *HasuraClaims {
+	return &HasuraClaims{
+		AllowedRoles: []string{HasuraRoleUser},
+		DefaultRole:  HasuraRoleUser,
+		UserID:       userID.String(),
+	}
+}
+
+// GenerateToken issues an access token carrying the subject's claims that
+// expires after ttl, and returns it with its expiry. The registered claims
+// are filled in here, and the token is signed with the keyring's current key,
+// named in the kid header.
+func GenerateToken(subject Claims, keys *Keyring, ttl time.Duration) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(ttl)
+
+	key, err := keys.signingKey(now)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	claims := subject
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		ID:        uuid.NewString(),
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+		IssuedAt:  jwt.NewNumericDate(now),
+	}
+
+	token := jwt.NewWithClaims(key.method(), &claims)
+	token.Header["kid"] = key.ID
+	signed, err := token.SignedString(key.PrivateKey)
+	if err != nil {
+		return "", time.Time{}, err
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secret))
+	return signed, expiresAt, nil
 }
 
-func ValidateToken(tokenString, secret string) (*Claims, error) {
+// ValidateToken verifies an access token against the keyring key named by
+// its kid header. Only the asymmetric algorithms keys are generated for are
+// accepted.
+func ValidateToken(tokenString string, keys *Keyring) (*Claims, error) {
 	claims := &Claims{}
 
 	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("invalid signing method")
-		}
-		return []byte(secret), nil
-	})
+		kid, _ := token.Header["kid"].(string)
+		return keys.publicKey(kid, token.Method.Alg(), time.Now())
+	}, jwt.WithValidMethods([]string{AlgorithmRS256, AlgorithmEdDSA}), jwt.WithExpirationRequired())
 
 	if err != nil {
 		return nil, err
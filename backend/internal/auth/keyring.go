@@ -0,0 +1,231 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Signing algorithms, as named in the JWT alg header
+const (
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+// rsaKeyBits is the size of generated RSA keys
+const rsaKeyBits = 2048
+
+var (
+	ErrUnsupportedAlgorithm = errors.New("unsupported signing algorithm")
+	ErrNoSigningKey         = errors.New("no active signing key")
+	ErrUnknownSigningKey    = errors.New("unknown signing key")
+)
+
+// SigningKey is one key of the keyring. A key is published for verification
+// as soon as it is in the keyring, signs from ActivatesAt, and is dropped at
+// RetiresAt once every token it signed has expired.
+type SigningKey struct {
+	ID          string
+	Algorithm   string
+	PrivateKey  crypto.Signer
+	ActivatesAt time.Time
+	RetiresAt   *time.Time
+}
+
+func (k *SigningKey) retired(now time.Time) bool {
+	return k.RetiresAt != nil && !now.Before(*k.RetiresAt)
+}
+
+func (k *SigningKey) method() jwt.SigningMethod {
+	if k.Algorithm == AlgorithmRS256 {
+		return jwt.SigningMethodRS256
+	}
+	return jwt.SigningMethodEdDSA
+}
+
+// GenerateSigningKey creates a key with a random id that starts signing at
+// activatesAt
+func GenerateSigningKey(algorithm string, activatesAt time.Time) (SigningKey, error) {
+	var private crypto.Signer
+	var err error
+	switch algorithm {
+	case AlgorithmRS256:
+		private, err = rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	case AlgorithmEdDSA:
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return SigningKey{}, fmt.Errorf("%w: %q", ErrUnsupportedAlgorithm, algorithm)
+	}
+	if err != nil {
+		return SigningKey{}, err
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return SigningKey{}, err
+	}
+
+	return SigningKey{
+		ID:          base64.RawURLEncoding.EncodeToString(id),
+		Algorithm:   algorithm,
+		PrivateKey:  private,
+		ActivatesAt: activatesAt,
+	}, nil
+}
+
+// MarshalPrivateKey encodes a private key as PKCS #8 PEM for storage
+func MarshalPrivateKey(key crypto.Signer) (string, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})), nil
+}
+
+// ParsePrivateKey decodes a key written by MarshalPrivateKey and checks that
+// it suits the algorithm
+func ParsePrivateKey(algorithm, encoded string) (crypto.Signer, error) {
+	block, _ := pem.Decode([]byte(encoded))
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, errors.New("private key is not PKCS #8 PEM")
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		if algorithm == AlgorithmRS256 {
+			return k, nil
+		}
+	case ed25519.PrivateKey:
+		if algorithm == AlgorithmEdDSA {
+			return k, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %T key for %q", ErrUnsupportedAlgorithm, key, algorithm)
+}
+
+// Keyring holds the keys access tokens are signed and verified with. It is
+// safe for concurrent use and its keys can be swapped while in use.
+type Keyring struct {
+	mu   sync.RWMutex
+	keys []SigningKey
+}
+
+func NewKeyring(keys ...SigningKey) *Keyring {
+	return &Keyring{keys: keys}
+}
+
+// Replace swaps in a new set of keys
+func (k *Keyring) Replace(keys []SigningKey) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.keys = keys
+}
+
+// Keys returns a copy of the keys
+func (k *Keyring) Keys() []SigningKey {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return append([]SigningKey(nil), k.keys...)
+}
+
+// signingKey returns the most recently activated key that has not retired
+func (k *Keyring) signingKey(now time.Time) (SigningKey, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	var current *SigningKey
+	for i := range k.keys {
+		key := &k.keys[i]
+		if key.ActivatesAt.After(now) || key.retired(now) {
+			continue
+		}
+		if current == nil || key.ActivatesAt.After(current.ActivatesAt) {
+			current = key
+		}
+	}
+	if current == nil {
+		return SigningKey{}, ErrNoSigningKey
+	}
+	return *current, nil
+}
+
+// publicKey finds the verification key for a token's kid and alg headers.
+// Keys that are not yet active are accepted: another instance may have
+// started signing with them before this one noticed.
+func (k *Keyring) publicKey(kid, algorithm string, now time.Time) (crypto.PublicKey, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	for i := range k.keys {
+		key := &k.keys[i]
+		if key.ID == kid && key.Algorithm == algorithm && !key.retired(now) {
+			return key.PrivateKey.Public(), nil
+		}
+	}
+	return nil, ErrUnknownSigningKey
+}
+
+// JWK is a public key in JSON Web Key form (RFC 7517, RFC 8037 for OKP)
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Use       string `json:"use"`
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS publishes the public halves of every key that has not retired,
+// including those not yet signing, so verifiers that cache the set already
+// know a key when tokens signed with it arrive
+func (k *Keyring) JWKS(now time.Time) JWKS {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	set := JWKS{Keys: []JWK{}}
+	for i := range k.keys {
+		key := &k.keys[i]
+		if key.retired(now) {
+			continue
+		}
+
+		jwk := JWK{Use: "sig", KeyID: key.ID, Algorithm: key.Algorithm}
+		switch public := key.PrivateKey.Public().(type) {
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		default:
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
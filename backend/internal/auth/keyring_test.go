@@ -0,0 +1,171 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+func mustGenerateKey(t *testing.T, algorithm string, activatesAt time.Time) SigningKey {
+	t.Helper()
+	key, err := GenerateSigningKey(algorithm, activatesAt)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	return key
+}
+
+func TestGenerateAndValidateToken(t *testing.T) {
+	for _, algorithm := range []string{AlgorithmRS256, AlgorithmEdDSA} {
+		t.Run(algorithm, func(t *testing.T) {
+			key := mustGenerateKey(t, algorithm, time.Now().Add(-time.Minute))
+			keys := NewKeyring(key)
+			userID := uuid.New()
+
+			token, _, err := GenerateToken(Claims{UserID: userID, Role: "user"}, keys, time.Minute)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			parsed, _, err := jwt.NewParser().ParseUnverified(token, &Claims{})
+			if err != nil {
+				t.Fatalf("expected a parseable token, got %v", err)
+			}
+			if parsed.Header["kid"] != key.ID || parsed.Header["alg"] != algorithm {
+				t.Fatalf("unexpected header: %v", parsed.Header)
+			}
+
+			claims, err := ValidateToken(token, keys)
+			if err != nil {
+				t.Fatalf("expected a valid token, got %v", err)
+			}
+			if claims.UserID != userID {
+				t.Fatalf("expected user %s, got %s", userID, claims.UserID)
+			}
+		})
+	}
+}
+
+func TestKeyring_Rotation(t *testing.T) {
+	now := time.Now()
+	retiresAt := now.Add(time.Hour)
+	old := mustGenerateKey(t, AlgorithmEdDSA, now.Add(-24*time.Hour))
+	old.RetiresAt = &retiresAt
+	next := mustGenerateKey(t, AlgorithmEdDSA, now.Add(10*time.Minute))
+	keys := NewKeyring(old, next)
+
+	// The old key signs until the new one activates
+	token, _, err := GenerateToken(Claims{UserID: uuid.New()}, keys, time.Minute)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	parsed, _, _ := jwt.NewParser().ParseUnverified(token, &Claims{})
+	if parsed.Header["kid"] != old.ID {
+		t.Fatalf("expected the old key to sign, got %v", parsed.Header["kid"])
+	}
+
+	// Both are published
+	if set := keys.JWKS(now); len(set.Keys) != 2 {
+		t.Fatalf("expected two published keys, got %d", len(set.Keys))
+	}
+
+	// Another instance may already sign with the new key
+	other := NewKeyring(SigningKey{ID: next.ID, Algorithm: next.Algorithm, PrivateKey: next.PrivateKey, ActivatesAt: now.Add(-time.Second)})
+	token, _, err = GenerateToken(Claims{UserID: uuid.New()}, other, time.Minute)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err := ValidateToken(token, keys); err != nil {
+		t.Fatalf("expected a token from the pending key to verify, got %v", err)
+	}
+
+	// A retired key neither signs nor verifies
+	past := now.Add(-time.Second)
+	old.RetiresAt = &past
+	keys.Replace([]SigningKey{old})
+	if _, _, err := GenerateToken(Claims{UserID: uuid.New()}, keys, time.Minute); !errors.Is(err, ErrNoSigningKey) {
+		t.Fatalf("expected ErrNoSigningKey, got %v", err)
+	}
+	if set := keys.JWKS(now); len(set.Keys) != 0 {
+		t.Fatalf("expected the retired key to be unpublished, got %d keys", len(set.Keys))
+	}
+}
+
+func TestValidateToken_RejectsForeignTokens(t *testing.T) {
+	keys := NewKeyring(mustGenerateKey(t, AlgorithmEdDSA, time.Now().Add(-time.Minute)))
+	claims := &Claims{UserID: uuid.New()}
+	claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(time.Minute))
+
+	hmac := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	hmac.Header["kid"] = keys.Keys()[0].ID
+	signed, _ := hmac.SignedString([]byte("secret"))
+	if _, err := ValidateToken(signed, keys); err == nil {
+		t.Fatalf("expected an HS256 token to be rejected")
+	}
+
+	stranger := mustGenerateKey(t, AlgorithmEdDSA, time.Now().Add(-time.Minute))
+	foreign := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+	foreign.Header["kid"] = stranger.ID
+	signed, _ = foreign.SignedString(stranger.PrivateKey)
+	if _, err := ValidateToken(signed, keys); !errors.Is(err, ErrUnknownSigningKey) {
+		t.Fatalf("expected ErrUnknownSigningKey, got %v", err)
+	}
+
+	// A known kid does not help a token signed with another key
+	foreign.Header["kid"] = keys.Keys()[0].ID
+	signed, _ = foreign.SignedString(stranger.PrivateKey)
+	if _, err := ValidateToken(signed, keys); err == nil {
+		t.Fatalf("expected a forged token to be rejected")
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	eddsa := mustGenerateKey(t, AlgorithmEdDSA, time.Now())
+	rsa := mustGenerateKey(t, AlgorithmRS256, time.Now())
+	set := NewKeyring(eddsa, rsa).JWKS(time.Now())
+
+	if len(set.Keys) != 2 {
+		t.Fatalf("expected two keys, got %d", len(set.Keys))
+	}
+
+	okp := set.Keys[0]
+	x, _ := base64.RawURLEncoding.DecodeString(okp.X)
+	if okp.KeyType != "OKP" || okp.Curve != "Ed25519" || okp.KeyID != eddsa.ID || !ed25519.PublicKey(x).Equal(eddsa.PrivateKey.Public()) {
+		t.Fatalf("unexpected Ed25519 JWK: %+v", okp)
+	}
+
+	jwk := set.Keys[1]
+	if jwk.KeyType != "RSA" || jwk.Algorithm != AlgorithmRS256 || jwk.E != "AQAB" || jwk.N == "" {
+		t.Fatalf("unexpected RSA JWK: %+v", jwk)
+	}
+}
+
+func TestPrivateKeyRoundTrip(t *testing.T) {
+	for _, algorithm := range []string{AlgorithmRS256, AlgorithmEdDSA} {
+		key := mustGenerateKey(t, algorithm, time.Now())
+		encoded, err := MarshalPrivateKey(key.PrivateKey)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		decoded, err := ParsePrivateKey(algorithm, encoded)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if !decoded.Public().(interface{ Equal(crypto.PublicKey) bool }).Equal(key.PrivateKey.Public()) {
+			t.Fatalf("%s: decoded key does not match", algorithm)
+		}
+	}
+
+	ed := mustGenerateKey(t, AlgorithmEdDSA, time.Now())
+	encoded, _ := MarshalPrivateKey(ed.PrivateKey)
+	if _, err := ParsePrivateKey(AlgorithmRS256, encoded); !errors.Is(err, ErrUnsupportedAlgorithm) {
+		t.Fatalf("expected an Ed25519 key to be refused for RS256, got %v", err)
+	}
+}
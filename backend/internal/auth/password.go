@@ -1,15 +1,220 @@
 package auth
 
 import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/bcrypt"
 )
 
-func HashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	return string(bytes), err
+// ErrInvalidHash is returned for stored hashes no hasher can read
+var ErrInvalidHash = errors.New("invalid password hash")
+
+// Hasher is one password hashing algorithm
+type Hasher interface {
+	// Hash encodes a new hash of password, parameters included
+	Hash(password string) (string, error)
+	// Identifies reports whether encoded was produced by this algorithm
+	Identifies(encoded string) bool
+	// Verify reports whether password matches encoded
+	Verify(password, encoded string) (bool, error)
+	// Current reports whether encoded uses this hasher's parameters
+	Current(encoded string) bool
+}
+
+// Argon2Params tune Argon2id. Memory is in KiB.
+type Argon2Params struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultArgon2Params is the second recommended option of RFC 9106, for
+// hardware where 2 GiB per hash is too much
+var DefaultArgon2Params = Argon2Params{
+	Memory:      64 * 1024,
+	Iterations:  3,
+	Parallelism: 4,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+// Argon2idHasher stores hashes in the PHC string format, such as
+// $argon2id$v=19$m=65536,t=3,p=4$<salt>$<key>
+type Argon2idHasher struct {
+	Params Argon2Params
+}
+
+func (h Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.Params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	p := h.Params
+	key := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.Memory, p.Iterations, p.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func (h Argon2idHasher) Identifies(encoded string) bool {
+	return strings.HasPrefix(encoded, "$argon2id$")
+}
+
+func (h Argon2idHasher) Verify(password, encoded string) (bool, error) {
+	p, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return false, err
+	}
+
+	actual := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, uint32(len(key)))
+	return subtle.ConstantTimeCompare(actual, key) == 1, nil
+}
+
+func (h Argon2idHasher) Current(encoded string) bool {
+	p, _, _, err := decodeArgon2id(encoded)
+	return err == nil && p == h.Params
+}
+
+// decodeArgon2id parses a PHC string into its parameters, salt and key
+func decodeArgon2id(encoded string) (Argon2Params, []byte, []byte, error) {
+	var p Argon2Params
+
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return p, nil, nil, ErrInvalidHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return p, nil, nil, ErrInvalidHash
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Iterations, &p.Parallelism); err != nil {
+		return p, nil, nil, ErrInvalidHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return p, nil, nil, ErrInvalidHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return p, nil, nil, ErrInvalidHash
+	}
+
+	p.SaltLength = uint32(len(salt))
+	p.KeyLength = uint32(len(key))
+	return p, salt, key, nil
+}
+
+// BcryptHasher reads the hashes stored before Argon2id was introduced
+type BcryptHasher struct {
+	Cost int
+}
+
+func (h BcryptHasher) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), h.Cost)
+	return string(hash), err
+}
+
+func (h BcryptHasher) Identifies(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") || strings.HasPrefix(encoded, "$2b$") || strings.HasPrefix(encoded, "$2y$")
+}
+
+func (h BcryptHasher) Verify(password, encoded string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	return err == nil, err
 }
 
-func CheckPassword(password, hash string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
-	return err == nil
+func (h BcryptHasher) Current(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return err == nil && cost == h.Cost
+}
+
+// PasswordHasher hashes new passwords with the current hasher and verifies
+// stored hashes of any known algorithm, so that hashes can be upgraded as
+// users sign in
+type PasswordHasher struct {
+	current Hasher
+	legacy  []Hasher
+}
+
+// NewPasswordHasher hashes with Argon2id and still verifies bcrypt hashes
+func NewPasswordHasher(params Argon2Params) *PasswordHasher {
+	return &PasswordHasher{
+		current: Argon2idHasher{Params: params},
+		legacy:  []Hasher{BcryptHasher{Cost: bcrypt.DefaultCost}},
+	}
+}
+
+// Hash hashes a new password
+func (h *PasswordHasher) Hash(password string) (string, error) {
+	return h.current.Hash(password)
+}
+
+// Verify reports whether password matches the stored hash, and whether the
+// hash should be replaced because it uses an older algorithm or parameters.
+// Unreadable hashes never match.
+func (h *PasswordHasher) Verify(password, encoded string) (ok, rehash bool) {
+	for _, hasher := range append([]Hasher{h.current}, h.legacy...) {
+		if !hasher.Identifies(encoded) {
+			continue
+		}
+		ok, err := hasher.Verify(password, encoded)
+		if err != nil || !ok {
+			return false, false
+		}
+		return true, hasher != h.current || !hasher.Current(encoded)
+	}
+	return false, false
+}
+
+// CalibrateArgon2 finds the Argon2id parameters for this machine: with the
+// given memory and parallelism, the fewest iterations (at least one) that
+// make a hash take target or longer. Measurements use the median of a few
+// runs to ride out noise.
+func CalibrateArgon2(target time.Duration, memory uint32, parallelism uint8) (Argon2Params, time.Duration) {
+	params := DefaultArgon2Params
+	params.Memory = memory
+	params.Parallelism = parallelism
+	params.Iterations = 1
+
+	for {
+		elapsed := measureArgon2(params)
+		if elapsed >= target || params.Iterations >= 64 {
+			return params, elapsed
+		}
+		params.Iterations++
+	}
+}
+
+// measureArgon2 returns the median time of three hashes with params
+func measureArgon2(params Argon2Params) time.Duration {
+	salt := make([]byte, params.SaltLength)
+	var runs [3]time.Duration
+	for i := range runs {
+		start := time.Now()
+		argon2.IDKey([]byte("calibration"), salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
+		runs[i] = time.Since(start)
+	}
+
+	for i := 1; i < len(runs); i++ {
+		for j := i; j > 0 && runs[j] < runs[j-1]; j-- {
+			runs[j], runs[j-1] = runs[j-1], runs[j]
+		}
+	}
+	return runs[1]
 }
@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// cheapParams keep the tests fast
+var cheapParams = Argon2Params{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+func TestPasswordHasher_Verify(t *testing.T) {
+	hasher := NewPasswordHasher(cheapParams)
+
+	hash, err := hasher.Hash("password")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=64,t=1,p=1$") {
+		t.Fatalf("unexpected hash format %q", hash)
+	}
+
+	if ok, rehash := hasher.Verify("password", hash); !ok || rehash {
+		t.Fatalf("expected a current match, got ok=%v rehash=%v", ok, rehash)
+	}
+	if ok, _ := hasher.Verify("wrong", hash); ok {
+		t.Fatal("expected a wrong password not to match")
+	}
+
+	t.Run("outdated parameters", func(t *testing.T) {
+		stronger := cheapParams
+		stronger.Iterations = 2
+		if ok, rehash := NewPasswordHasher(stronger).Verify("password", hash); !ok || !rehash {
+			t.Fatalf("expected a match needing a rehash, got ok=%v rehash=%v", ok, rehash)
+		}
+	})
+
+	t.Run("legacy bcrypt", func(t *testing.T) {
+		legacy, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok, rehash := hasher.Verify("password", string(legacy)); !ok || !rehash {
+			t.Fatalf("expected a match needing a rehash, got ok=%v rehash=%v", ok, rehash)
+		}
+		if ok, rehash := hasher.Verify("wrong", string(legacy)); ok || rehash {
+			t.Fatalf("expected no match, got ok=%v rehash=%v", ok, rehash)
+		}
+	})
+
+	t.Run("unreadable hashes", func(t *testing.T) {
+		for _, encoded := range []string{"", "plain", "$argon2id$v=19$m=64,t=1,p=1$!!$!!", "$argon2id$v=16$m=64,t=1,p=1$c2FsdA$a2V5"} {
+			if ok, _ := hasher.Verify("password", encoded); ok {
+				t.Fatalf("expected %q not to match", encoded)
+			}
+		}
+	})
+}
+
+func TestCalibrateArgon2(t *testing.T) {
+	params, elapsed := CalibrateArgon2(time.Millisecond, 64, 1)
+	if params.Iterations < 1 || params.Memory != 64 || params.Parallelism != 1 {
+		t.Fatalf("unexpected parameters %+v", params)
+	}
+	if elapsed < time.Millisecond && params.Iterations < 64 {
+		t.Fatalf("expected the target to be reached, took %v with %+v", elapsed, params)
+	}
+}
+
+// BenchmarkPasswordHasher measures one hash with the default parameters,
+// the cost of every sign-in. Run it on the production hardware, or use
+// cmd/calibrate-argon2 to pick parameters for a target time.
+func BenchmarkPasswordHasher(b *testing.B) {
+	hasher := NewPasswordHasher(DefaultArgon2Params)
+	for i := 0; i < b.N; i++ {
+		if _, err := hasher.Hash("password"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package auth
+
+// Built-in roles. Administrators hold every permission and users none;
+// custom roles hold the permissions they are given.
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// Permissions gate the administration routes
+const (
+	PermissionUsersRead        = "users.read"
+	PermissionUsersWrite       = "users.write"
+	PermissionUsersImpersonate = "users.impersonate"
+	PermissionTodosReadAll     = "todos.read_all"
+	PermissionTodosWriteAll    = "todos.write_all"
+	PermissionRolesManage      = "roles.manage"
+	PermissionAuditRead        = "audit.read"
+)
+
+// Permissions lists every permission, in the order they are documented
+var Permissions = []string{
+	PermissionUsersRead,
+	PermissionUsersWrite,
+	PermissionUsersImpersonate,
+	PermissionTodosReadAll,
+	PermissionTodosWriteAll,
+	PermissionRolesManage,
+	PermissionAuditRead,
+}
+
+// ValidPermission reports whether permission is one of Permissions
+func ValidPermission(permission string) bool {
+	for _, p := range Permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// BuiltInRole reports whether role is one that cannot be changed or deleted
+func BuiltInRole(role string) bool {
+	return role == RoleUser || role == RoleAdmin
+}
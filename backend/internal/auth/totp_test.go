@@ -0,0 +1,98 @@
+package auth
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+// rfcKey is the shared secret used by the RFC 4226 and RFC 6238 test vectors
+var rfcKey = []byte("12345678901234567890")
+
+func TestHOTP_RFC4226Vectors(t *testing.T) {
+	want := []string{
+		"755224", "287082", "359152", "969429", "338314",
+		"254676", "287922", "162583", "399871", "520489",
+	}
+
+	for counter, code := range want {
+		if got := hotp(rfcKey, uint64(counter), 6); got != code {
+			t.Errorf("counter %d: expected %s, got %s", counter, code, got)
+		}
+	}
+}
+
+func TestTOTP_RFC6238Vectors(t *testing.T) {
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+
+	for _, tt := range tests {
+		if got := hotp(rfcKey, uint64(tt.unix/totpPeriod), 8); got != tt.code {
+			t.Errorf("time %d: expected %s, got %s", tt.unix, tt.code, got)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret := totpEncoding.EncodeToString(rfcKey)
+	now := time.Unix(1111111111, 0)
+
+	code, err := TOTPCode(secret, now)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	step, ok := ValidateTOTP(secret, code, now, 1)
+	if !ok || step != now.Unix()/totpPeriod {
+		t.Fatalf("expected the current step to match, got %d, %v", step, ok)
+	}
+
+	if _, ok := ValidateTOTP(secret, code, now.Add(totpPeriod*time.Second), 1); !ok {
+		t.Fatalf("expected the previous step to be accepted within the skew")
+	}
+
+	if _, ok := ValidateTOTP(secret, code, now.Add(2*totpPeriod*time.Second), 1); ok {
+		t.Fatalf("expected a code two steps old to be rejected")
+	}
+
+	if _, ok := ValidateTOTP(secret, "12345", now, 1); ok {
+		t.Fatalf("expected a short code to be rejected")
+	}
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri, err := url.Parse(TOTPURI("Todo App", "alice@example.com", "JBSWY3DPEHPK3PXP"))
+	if err != nil {
+		t.Fatalf("expected a valid URI, got %v", err)
+	}
+
+	if uri.Scheme != "otpauth" || uri.Host != "totp" || uri.Path != "/Todo App:alice@example.com" {
+		t.Fatalf("unexpected URI: %s", uri)
+	}
+	if query := uri.Query(); query.Get("secret") != "JBSWY3DPEHPK3PXP" || query.Get("issuer") != "Todo App" {
+		t.Fatalf("unexpected query: %s", uri.RawQuery)
+	}
+}
+
+func TestRecoveryCode(t *testing.T) {
+	code, err := GenerateRecoveryCode()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(code) != 19 {
+		t.Fatalf("expected four groups of four, got %q", code)
+	}
+	if NormalizeRecoveryCode(code) != NormalizeRecoveryCode(" "+code[:9]+code[10:]) {
+		t.Fatalf("expected formatting to be ignored")
+	}
+}
@@ -0,0 +1,54 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLRU_EvictsLeastRecentlyUsed(t *testing.T) {
+	c := NewLRU[string, int](2)
+	expiresAt := time.Now().Add(time.Hour)
+
+	c.Set("a", 1, expiresAt)
+	c.Set("b", 2, expiresAt)
+
+	// Touch "a" so "b" becomes the eviction candidate
+	if _, ok := c.Get("a"); !ok {
+		t.Fatalf("expected a to be cached")
+	}
+
+	c.Set("c", 3, expiresAt)
+
+	if _, ok := c.Get("b"); ok {
+		t.Fatalf("expected b to be evicted")
+	}
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Fatalf("expected a=1, got %v (%v)", v, ok)
+	}
+	if v, ok := c.Get("c"); !ok || v != 3 {
+		t.Fatalf("expected c=3, got %v (%v)", v, ok)
+	}
+}
+
+func TestLRU_Expiry(t *testing.T) {
+	now := time.Now()
+	c := NewLRU[string, int](10)
+	c.now = func() time.Time { return now }
+
+	c.Set("short", 1, now.Add(time.Minute))
+	c.Set("long", 2, now.Add(time.Hour))
+
+	now = now.Add(2 * time.Minute)
+
+	if _, ok := c.Get("short"); ok {
+		t.Fatalf("expected short to have expired")
+	}
+
+	c.Set("stale", 3, now.Add(-time.Second))
+	if removed := c.Prune(); removed != 1 {
+		t.Fatalf("expected 1 pruned entry, got %d", removed)
+	}
+	if c.Len() != 1 {
+		t.Fatalf("expected 1 remaining entry, got %d", c.Len())
+	}
+}
@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"todo-app/backend/internal/model"
+	"todo-app/backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type AccessTokenHandler struct {
+	accessTokenService *service.AccessTokenService
+}
+
+func NewAccessTokenHandler(accessTokenService *service.AccessTokenService) *AccessTokenHandler {
+	return &AccessTokenHandler{accessTokenService: accessTokenService}
+}
+
+// CreateToken issues a personal access token. The token is in this response
+// only.
+func (h *AccessTokenHandler) CreateToken(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	var req model.CreateAccessTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	token, err := h.accessTokenService.CreateToken(userID, c.GetString("role"), c.GetBool("mfa"), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidScope), errors.Is(err, service.ErrInvalidExpiry):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create token"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, token)
+}
+
+func (h *AccessTokenHandler) GetTokens(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	tokens, err := h.accessTokenService.ListTokens(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch tokens"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+func (h *AccessTokenHandler) DeleteToken(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	tokenID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid token id"})
+		return
+	}
+
+	if err := h.accessTokenService.DeleteToken(userID, tokenID); err != nil {
+		if errors.Is(err, service.ErrAccessTokenNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "token not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "token deleted"})
+}
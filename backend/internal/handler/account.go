@@ -0,0 +1,169 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"todo-app/backend/internal/model"
+	"todo-app/backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type AccountHandler struct {
+	accountService *service.AccountService
+}
+
+func NewAccountHandler(accountService *service.AccountService) *AccountHandler {
+	return &AccountHandler{accountService: accountService}
+}
+
+// ForgotPassword sends a reset link. The response never says whether the
+// email is registered.
+func (h *AccountHandler) ForgotPassword(c *gin.Context) {
+	var req model.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.accountService.ForgotPassword(c.Request.Context(), req.Email); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to process request"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "if the email is registered, a reset link has been sent"})
+}
+
+func (h *AccountHandler) ResetPassword(c *gin.Context) {
+	var req model.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := h.accountService.ResetPassword(req.Token, req.Password)
+	if err != nil {
+		respondAccountError(c, err, "failed to reset password")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "password reset successfully"})
+}
+
+func (h *AccountHandler) VerifyEmail(c *gin.Context) {
+	var req model.VerifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := h.accountService.VerifyEmail(req.Token)
+	if err != nil {
+		respondAccountError(c, err, "failed to verify email")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "email verified successfully"})
+}
+
+func (h *AccountHandler) ResendVerification(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	err := h.accountService.ResendVerification(c.Request.Context(), userID)
+	if err != nil {
+		respondAccountError(c, err, "failed to send verification email")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "verification email sent"})
+}
+
+// ChangePassword requires the current password and signs out the user's
+// other sessions
+func (h *AccountHandler) ChangePassword(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+	sessionID := c.MustGet("session_id").(uuid.UUID)
+
+	var req model.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := h.accountService.ChangePassword(userID, sessionID, req.CurrentPassword, req.NewPassword)
+	if err != nil {
+		respondAccountError(c, err, "failed to change password")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "password changed successfully"})
+}
+
+// ChangeEmail sends a confirmation link to the new address; the change takes
+// effect once it is opened
+func (h *AccountHandler) ChangeEmail(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	var req model.ChangeEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := h.accountService.ChangeEmail(c.Request.Context(), userID, req.Password, req.Email)
+	if err != nil {
+		respondAccountError(c, err, "failed to change email")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "confirmation email sent to the new address"})
+}
+
+// DeleteAccount schedules the signed-in user's account for deletion
+func (h *AccountHandler) DeleteAccount(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	var req model.DeleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	scheduledAt, err := h.accountService.DeleteAccount(c.Request.Context(), userID, req.Password)
+	if err != nil {
+		respondAccountError(c, err, "failed to delete account")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, model.DeleteAccountResponse{DeletionScheduledAt: scheduledAt})
+}
+
+func respondAccountError(c *gin.Context, err error, fallback string) {
+	var policy *service.PasswordPolicyError
+	switch {
+	case errors.As(err, &policy):
+		respondPasswordPolicyError(c, policy)
+	case errors.Is(err, service.ErrInvalidAccountToken):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid or expired token"})
+	case errors.Is(err, service.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+	case errors.Is(err, service.ErrEmailAlreadyVerified):
+		c.JSON(http.StatusConflict, gin.H{"error": "email already verified"})
+	case errors.Is(err, service.ErrVerificationRateLimited):
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "too many verification emails, try again later"})
+	case errors.Is(err, service.ErrIncorrectPassword):
+		c.JSON(http.StatusForbidden, gin.H{"error": "incorrect password"})
+	case errors.Is(err, service.ErrEmailUnchanged):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "new email is the same as the current one"})
+	case errors.Is(err, service.ErrUserAlreadyExists):
+		c.JSON(http.StatusConflict, gin.H{"error": "email already in use"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
+
+// respondPasswordPolicyError explains every rule a new password breaks
+func respondPasswordPolicyError(c *gin.Context, err *service.PasswordPolicyError) {
+	c.JSON(http.StatusBadRequest, gin.H{"error": "password does not meet the policy", "violations": err.Violations})
+}
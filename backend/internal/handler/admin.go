@@ -3,7 +3,11 @@ package handler
 import (
 	"errors"
 	"net/http"
+	"strconv"
+	"time"
 
+	"todo-app/backend/internal/auth"
+	"todo-app/backend/internal/model"
 	"todo-app/backend/internal/service"
 
 	"github.com/gin-gonic/gin"
@@ -18,13 +22,61 @@ func NewAdminHandler(userService *service.UserService) *AdminHandler {
 	return &AdminHandler{userService: userService}
 }
 
-func (h *AdminHandler) GetAllUsers(c *gin.Context) {
-	users, err := h.userService.GetAllUsers()
+// TotalCountHeader carries the number of matching items across all pages
+const TotalCountHeader = "X-Total-Count"
+
+func (h *AdminHandler) SearchUsers(c *gin.Context) {
+	filter := model.UserFilter{
+		Query: c.Query("q"),
+		Role:  c.Query("role"),
+		Sort:  c.Query("sort"),
+	}
+	switch status := c.Query("status"); status {
+	case "", model.UserStatusActive, model.UserStatusSuspended, model.UserStatusPendingDeletion:
+		filter.Status = status
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid status"})
+		return
+	}
+	if createdAfter := c.Query("created_after"); createdAfter != "" {
+		t, err := time.Parse(time.RFC3339, createdAfter)
+		if err != nil {
+			t, err = time.Parse(time.DateOnly, createdAfter)
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid created_after"})
+			return
+		}
+		filter.CreatedAfter = &t
+	}
+	if limit := c.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+			return
+		}
+		filter.Limit = n
+	}
+	if offset := c.Query("offset"); offset != "" {
+		n, err := strconv.Atoi(offset)
+		if err != nil || n < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid offset"})
+			return
+		}
+		filter.Offset = n
+	}
+
+	users, total, err := h.userService.SearchUsers(filter)
 	if err != nil {
+		if errors.Is(err, service.ErrInvalidUserSort) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid sort"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch users"})
 		return
 	}
 
+	c.Header(TotalCountHeader, strconv.Itoa(total))
 	c.JSON(http.StatusOK, users)
 }
 
@@ -60,7 +112,7 @@ func (h *AdminHandler) UpdateUserRole(c *gin.Context) {
 	}
 
 	var req struct {
-		Role string `json:"role" binding:"required,oneof=user admin"`
+		Role string `json:"role" binding:"required,max=50"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -68,12 +120,20 @@ func (h *AdminHandler) UpdateUserRole(c *gin.Context) {
 		return
 	}
 
-	user, err := h.userService.UpdateUserRole(userUUID, req.Role)
+	user, err := h.userService.UpdateUserRole(c.GetString("role"), userUUID, req.Role)
 	if err != nil {
 		if errors.Is(err, service.ErrUserNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
 			return
 		}
+		if errors.Is(err, service.ErrRoleNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "role not found"})
+			return
+		}
+		if errors.Is(err, service.ErrPermissionNotHeld) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update user"})
 		return
 	}
@@ -90,9 +150,25 @@ func (h *AdminHandler) DeleteUser(c *gin.Context) {
 		return
 	}
 
-	currentUserID := c.MustGet("user_id").(uuid.UUID)
+	actor := c.MustGet("claims").(*auth.Claims)
+
+	if actor.UserID == userUUID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cannot delete your own account"})
+		return
+	}
 
-	err = h.userService.DeleteUser(currentUserID, userUUID)
+	// Optionally hand the user's todos to someone else before they cascade away
+	var reassignTo *uuid.UUID
+	if target := c.Query("reassign_to"); target != "" {
+		targetUUID, err := uuid.Parse(target)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid reassign_to"})
+			return
+		}
+		reassignTo = &targetUUID
+	}
+
+	err = h.userService.DeleteUser(actor, userUUID, reassignTo)
 	if err != nil {
 		if errors.Is(err, service.ErrCannotDeleteSelf) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "cannot delete your own account"})
@@ -102,6 +178,14 @@ func (h *AdminHandler) DeleteUser(c *gin.Context) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
 			return
 		}
+		if errors.Is(err, service.ErrPermissionNotHeld) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, service.ErrInvalidReassignTarget) || errors.Is(err, service.ErrReassignTargetNotFound) {
+			respondReassignError(c, err)
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete user"})
 		return
 	}
@@ -109,8 +193,97 @@ func (h *AdminHandler) DeleteUser(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "user deleted successfully"})
 }
 
+// RevokeSessions revokes every token issued to a user
+func (h *AdminHandler) RevokeSessions(c *gin.Context) {
+	userUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	err = h.userService.RevokeSessions(c.MustGet("claims").(*auth.Claims), userUUID)
+	if err != nil {
+		if errors.Is(err, service.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+			return
+		}
+		if errors.Is(err, service.ErrPermissionNotHeld) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "sessions revoked successfully"})
+}
+
+func (h *AdminHandler) GetUserSessions(c *gin.Context) {
+	userUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	sessions, err := h.userService.GetUserSessions(userUUID)
+	if err != nil {
+		if errors.Is(err, service.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, sessions)
+}
+
+func (h *AdminHandler) DeleteUserSession(c *gin.Context) {
+	userUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	sessionUUID, err := uuid.Parse(c.Param("sessionId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid session id"})
+		return
+	}
+
+	err = h.userService.RevokeUserSession(c.MustGet("claims").(*auth.Claims), userUUID, sessionUUID)
+	if err != nil {
+		if errors.Is(err, service.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+			return
+		}
+		if errors.Is(err, service.ErrPermissionNotHeld) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, service.ErrSessionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "session not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke session"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "session revoked successfully"})
+}
+
 func (h *AdminHandler) GetAllTodos(c *gin.Context) {
-	todos, err := h.userService.GetAllTodos()
+	var filter model.TodoFilter
+	if assigneeID := c.Query("assignee_id"); assigneeID != "" {
+		assigneeUUID, err := uuid.Parse(assigneeID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid assignee id"})
+			return
+		}
+		filter.AssigneeID = &assigneeUUID
+	}
+
+	todos, err := h.userService.GetAllTodos(filter)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch todos"})
 		return
@@ -118,3 +291,171 @@ func (h *AdminHandler) GetAllTodos(c *gin.Context) {
 
 	c.JSON(http.StatusOK, todos)
 }
+
+func (h *AdminHandler) AssignTodo(c *gin.Context) {
+	todoID := c.Param("id")
+
+	todoUUID, err := uuid.Parse(todoID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid todo id"})
+		return
+	}
+
+	var req model.AssignTodoRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	currentUserID := c.MustGet("user_id").(uuid.UUID)
+
+	todo, err := h.userService.AssignTodo(currentUserID, todoUUID, req.AssigneeID)
+	if err != nil {
+		if errors.Is(err, service.ErrTodoNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "todo not found"})
+			return
+		}
+		if errors.Is(err, service.ErrAssigneeNoAccess) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "assignee does not have access to the todo"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to assign todo"})
+		return
+	}
+
+	c.JSON(http.StatusOK, todo)
+}
+
+// ReassignTodos moves all todos owned by or assigned to a user to another user
+func (h *AdminHandler) ReassignTodos(c *gin.Context) {
+	userID := c.Param("id")
+
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	var req struct {
+		ToUserID uuid.UUID `json:"to_user_id" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	count, err := h.userService.ReassignTodos(claims, userUUID, req.ToUserID)
+	if err != nil {
+		respondReassignError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"reassigned": count})
+}
+
+func respondReassignError(c *gin.Context, err error) {
+	if errors.Is(err, service.ErrInvalidReassignTarget) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "todos cannot be reassigned to the same user"})
+		return
+	}
+	if errors.Is(err, service.ErrReassignTargetNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "target user not found"})
+		return
+	}
+	if errors.Is(err, service.ErrUserNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+	if errors.Is(err, service.ErrPermissionNotHeld) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to reassign todos"})
+}
+
+// ResetUserMFA removes a user's second factor
+func (h *AdminHandler) ResetUserMFA(c *gin.Context) {
+	userUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	err = h.userService.ResetMFA(c.MustGet("claims").(*auth.Claims), userUUID)
+	if err != nil {
+		if errors.Is(err, service.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+			return
+		}
+		if errors.Is(err, service.ErrPermissionNotHeld) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, service.ErrMFANotEnrolled) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "two-factor authentication not enabled"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to reset two-factor authentication"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "two-factor authentication reset successfully"})
+}
+
+// SuspendUser stops a user from signing in and ends their sessions
+func (h *AdminHandler) SuspendUser(c *gin.Context) {
+	userUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	var req model.SuspendUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.userService.SuspendUser(c.MustGet("claims").(*auth.Claims), userUUID, req.Reason)
+	if err != nil {
+		respondSuspensionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// ReactivateUser lets a suspended user sign in again
+func (h *AdminHandler) ReactivateUser(c *gin.Context) {
+	userUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	user, err := h.userService.ReactivateUser(c.MustGet("claims").(*auth.Claims), userUUID)
+	if err != nil {
+		respondSuspensionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+func respondSuspensionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+	case errors.Is(err, service.ErrCannotSuspendSelf):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrPermissionNotHeld):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrUserAlreadySuspended), errors.Is(err, service.ErrUserNotSuspended):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update user status"})
+	}
+}
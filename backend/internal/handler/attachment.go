@@ -0,0 +1,180 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"todo-app/backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// multipartOverhead allows for part headers and boundaries around the file
+const multipartOverhead = 1 << 20
+
+type AttachmentHandler struct {
+	attachmentService *service.AttachmentService
+}
+
+func NewAttachmentHandler(attachmentService *service.AttachmentService) *AttachmentHandler {
+	return &AttachmentHandler{attachmentService: attachmentService}
+}
+
+func (h *AttachmentHandler) GetAttachments(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	todoUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid todo id"})
+		return
+	}
+
+	attachments, err := h.attachmentService.ListAttachments(userID, todoUUID)
+	if err != nil {
+		respondAttachmentError(c, err, "failed to fetch attachments")
+		return
+	}
+
+	c.JSON(http.StatusOK, attachments)
+}
+
+func (h *AttachmentHandler) GetAttachment(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	todoUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid todo id"})
+		return
+	}
+
+	attachmentUUID, err := uuid.Parse(c.Param("attachmentId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid attachment id"})
+		return
+	}
+
+	attachment, err := h.attachmentService.GetAttachment(userID, todoUUID, attachmentUUID)
+	if err != nil {
+		respondAttachmentError(c, err, "failed to fetch attachment")
+		return
+	}
+
+	c.JSON(http.StatusOK, attachment)
+}
+
+// UploadAttachment streams the "file" part of a multipart request to storage
+// without buffering the whole request in memory.
+func (h *AttachmentHandler) UploadAttachment(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	todoUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid todo id"})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.attachmentService.MaxBytes()+multipartOverhead)
+
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "multipart form data required"})
+		return
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			respondAttachmentError(c, err, "failed to read upload")
+			return
+		}
+
+		if part.FormName() != "file" {
+			part.Close()
+			continue
+		}
+
+		attachment, err := h.attachmentService.Upload(c.Request.Context(), userID, todoUUID, part.FileName(), part)
+		part.Close()
+		if err != nil {
+			respondAttachmentError(c, err, "failed to upload attachment")
+			return
+		}
+
+		c.JSON(http.StatusCreated, attachment)
+		return
+	}
+
+	c.JSON(http.StatusBadRequest, gin.H{"error": "file field required"})
+}
+
+func (h *AttachmentHandler) DeleteAttachment(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	todoUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid todo id"})
+		return
+	}
+
+	attachmentUUID, err := uuid.Parse(c.Param("attachmentId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid attachment id"})
+		return
+	}
+
+	err = h.attachmentService.DeleteAttachment(c.Request.Context(), userID, todoUUID, attachmentUUID)
+	if err != nil {
+		respondAttachmentError(c, err, "failed to delete attachment")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "attachment deleted successfully"})
+}
+
+// DownloadAttachment serves a blob through a signed, short-lived link. It is
+// mounted outside the authenticated group so links work in plain <a> tags.
+func (h *AttachmentHandler) DownloadAttachment(c *gin.Context) {
+	attachmentUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid attachment id"})
+		return
+	}
+
+	attachment, blob, err := h.attachmentService.Open(c.Request.Context(), attachmentUUID, c.Query("expires"), c.Query("signature"))
+	if err != nil {
+		respondAttachmentError(c, err, "failed to download attachment")
+		return
+	}
+	defer blob.Close()
+
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename}))
+	c.Header("Content-Length", strconv.FormatInt(attachment.SizeBytes, 10))
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Cache-Control", "private, no-store")
+	c.DataFromReader(http.StatusOK, attachment.SizeBytes, attachment.ContentType, blob, nil)
+}
+
+func respondAttachmentError(c *gin.Context, err error, fallback string) {
+	var maxBytesErr *http.MaxBytesError
+
+	switch {
+	case errors.Is(err, service.ErrTodoNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "todo not found"})
+	case errors.Is(err, service.ErrAttachmentNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "attachment not found"})
+	case errors.Is(err, service.ErrAttachmentTooLarge), errors.As(err, &maxBytesErr):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "attachment exceeds the size limit"})
+	case errors.Is(err, service.ErrUnsupportedMediaType):
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "unsupported attachment type"})
+	case errors.Is(err, service.ErrInvalidDownloadLink):
+		c.JSON(http.StatusForbidden, gin.H{"error": "invalid or expired download link"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
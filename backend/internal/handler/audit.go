@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"todo-app/backend/internal/model"
+	"todo-app/backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type AuditHandler struct {
+	auditService *service.AuditService
+}
+
+func NewAuditHandler(auditService *service.AuditService) *AuditHandler {
+	return &AuditHandler{auditService: auditService}
+}
+
+// GetAuditLogs lists the newest audit log entries, optionally narrowed by
+// user_id, actor_id and action, up to limit
+func (h *AuditHandler) GetAuditLogs(c *gin.Context) {
+	var filter model.AuditFilter
+	if userID := c.Query("user_id"); userID != "" {
+		userUUID, err := uuid.Parse(userID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+			return
+		}
+		filter.UserID = &userUUID
+	}
+	if actorID := c.Query("actor_id"); actorID != "" {
+		actorUUID, err := uuid.Parse(actorID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid actor id"})
+			return
+		}
+		filter.ActorID = &actorUUID
+	}
+	filter.Action = c.Query("action")
+	if limit := c.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+			return
+		}
+		filter.Limit = n
+	}
+
+	logs, err := h.auditService.List(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch audit logs"})
+		return
+	}
+
+	c.JSON(http.StatusOK, logs)
+}
@@ -2,7 +2,10 @@ package handler
 
 import (
 	"errors"
+	"math"
 	"net/http"
+	"strconv"
+	"todo-app/backend/internal/auth"
 	"todo-app/backend/internal/model"
 	"todo-app/backend/internal/service"
 
@@ -27,8 +30,13 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
-	response, err := h.authService.Register(req.Email, req.Password)
+	response, err := h.authService.Register(c.Request.Context(), req.Email, req.Password, clientInfo(c))
 	if err != nil {
+		var policy *service.PasswordPolicyError
+		if errors.As(err, &policy) {
+			respondPasswordPolicyError(c, policy)
+			return
+		}
 		if errors.Is(err, service.ErrUserAlreadyExists) {
 			c.JSON(http.StatusConflict, gin.H{"error": "user already exists"})
 			return
@@ -47,19 +55,114 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	response, err := h.authService.Login(req.Email, req.Password)
+	response, challenge, err := h.authService.Login(req.Email, req.Password, clientInfo(c))
 	if err != nil {
+		var locked *service.LockedOutError
+		if errors.As(err, &locked) {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(locked.RetryAfter.Seconds()))))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "too many failed logins, try again later"})
+			return
+		}
 		if errors.Is(err, service.ErrInvalidCredentials) {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid email or password"})
 			return
 		}
+		if errors.Is(err, service.ErrUserSuspended) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "account suspended"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to login"})
 		return
 	}
 
+	if challenge != nil {
+		c.JSON(http.StatusOK, challenge)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// VerifyMFA completes a login that returned an MFA challenge
+func (h *AuthHandler) VerifyMFA(c *gin.Context) {
+	var req model.VerifyMFARequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, err := h.authService.VerifyMFA(req.MFAToken, req.Code, clientInfo(c))
+	if err != nil {
+		var locked *service.LockedOutError
+		switch {
+		case errors.As(err, &locked):
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(locked.RetryAfter.Seconds()))))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "too many failed logins, try again later"})
+		case errors.Is(err, service.ErrInvalidMFAToken):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired mfa token"})
+		case errors.Is(err, service.ErrInvalidMFACode):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid two-factor code"})
+		case errors.Is(err, service.ErrUserSuspended):
+			c.JSON(http.StatusForbidden, gin.H{"error": "account suspended"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to login"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// RefreshToken rotates a refresh token and issues a new access token
+func (h *AuthHandler) RefreshToken(c *gin.Context) {
+	var req model.RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, err := h.authService.Refresh(req.RefreshToken)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidRefreshToken) || errors.Is(err, service.ErrRefreshTokenReused) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired refresh token"})
+			return
+		}
+		if errors.Is(err, service.ErrUserSuspended) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "account suspended"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to refresh token"})
+		return
+	}
+
 	c.JSON(http.StatusOK, response)
 }
 
+// Logout revokes the caller's access token and the refresh tokens of its
+// session
+func (h *AuthHandler) Logout(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	if err := h.authService.Logout(claims); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to logout"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "logged out successfully"})
+}
+
+// LogoutAll revokes every token issued to the caller
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	if err := h.authService.LogoutAll(userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to logout"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "logged out of all sessions successfully"})
+}
+
 func (h *AuthHandler) GetProfile(c *gin.Context) {
 	userID := c.MustGet("user_id").(uuid.UUID)
 
@@ -75,3 +178,11 @@ func (h *AuthHandler) GetProfile(c *gin.Context) {
 
 	c.JSON(http.StatusOK, user)
 }
+
+// clientInfo describes the device making the request
+func clientInfo(c *gin.Context) model.ClientInfo {
+	return model.ClientInfo{
+		UserAgent: c.Request.UserAgent(),
+		IPAddress: c.ClientIP(),
+	}
+}
@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"todo-app/backend/internal/auth"
+	"todo-app/backend/internal/model"
+	"todo-app/backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type ImpersonationHandler struct {
+	impersonationService *service.ImpersonationService
+	auditService         *service.AuditService
+}
+
+func NewImpersonationHandler(impersonationService *service.ImpersonationService, auditService *service.AuditService) *ImpersonationHandler {
+	return &ImpersonationHandler{
+		impersonationService: impersonationService,
+		auditService:         auditService,
+	}
+}
+
+// Impersonate issues a short-lived token acting as the user
+func (h *ImpersonationHandler) Impersonate(c *gin.Context) {
+	userUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	var req model.ImpersonateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	response, err := h.impersonationService.Impersonate(claims, userUUID, req.Reason, clientInfo(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrUserNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		case errors.Is(err, service.ErrUserSuspended):
+			c.JSON(http.StatusConflict, gin.H{"error": "account suspended"})
+		case errors.Is(err, service.ErrCannotImpersonateSelf):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrImpersonationForbidden):
+			c.JSON(http.StatusForbidden, gin.H{"error": "cannot impersonate a user holding permissions you do not have"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to impersonate user"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetImpersonations shows users when an administrator signed in as them
+func (h *ImpersonationHandler) GetImpersonations(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	impersonations, err := h.auditService.Impersonations(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch impersonations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, impersonations)
+}
@@ -0,0 +1,28 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+	"todo-app/backend/internal/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+type JWKSHandler struct {
+	keys   *auth.Keyring
+	maxAge time.Duration
+}
+
+// NewJWKSHandler serves the keyring's public keys. maxAge is how long
+// verifiers may cache them; it must be shorter than the key activation delay
+// so that new keys are picked up before they sign.
+func NewJWKSHandler(keys *auth.Keyring, maxAge time.Duration) *JWKSHandler {
+	return &JWKSHandler{keys: keys, maxAge: maxAge}
+}
+
+// GetJWKS publishes the access token verification keys
+func (h *JWKSHandler) GetJWKS(c *gin.Context) {
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", int(h.maxAge.Seconds())))
+	c.JSON(http.StatusOK, h.keys.JWKS(time.Now()))
+}
@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"todo-app/backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type LockoutHandler struct {
+	lockoutService *service.LockoutService
+}
+
+func NewLockoutHandler(lockoutService *service.LockoutService) *LockoutHandler {
+	return &LockoutHandler{lockoutService: lockoutService}
+}
+
+// GetLockouts lists the accounts and client addresses locked out of login
+func (h *LockoutHandler) GetLockouts(c *gin.Context) {
+	lockouts, err := h.lockoutService.Locked()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch lockouts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, lockouts)
+}
+
+// DeleteLockout lifts the lockout of an account, given by email, or of a
+// client address
+func (h *LockoutHandler) DeleteLockout(c *gin.Context) {
+	err := h.lockoutService.Clear(c.Param("scope"), c.Param("subject"))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidLockoutScope):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "scope must be account or ip"})
+		case errors.Is(err, service.ErrLockoutNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "lockout not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to clear lockout"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "lockout cleared successfully"})
+}
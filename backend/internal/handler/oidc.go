@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"todo-app/backend/internal/model"
+	"todo-app/backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type OIDCHandler struct {
+	oidcService *service.OIDCService
+	authService *service.AuthService
+}
+
+func NewOIDCHandler(oidcService *service.OIDCService, authService *service.AuthService) *OIDCHandler {
+	return &OIDCHandler{
+		oidcService: oidcService,
+		authService: authService,
+	}
+}
+
+// GetProviders lists the identity providers to offer on the login page
+func (h *OIDCHandler) GetProviders(c *gin.Context) {
+	providers := h.oidcService.Providers()
+	if providers == nil {
+		providers = []string{}
+	}
+
+	c.JSON(http.StatusOK, model.OIDCProviders{Providers: providers})
+}
+
+// BeginLogin returns the provider URL to redirect the browser to
+func (h *OIDCHandler) BeginLogin(c *gin.Context) {
+	authURL, err := h.oidcService.BeginLogin(c.Request.Context(), c.Param("provider"))
+	if err != nil {
+		if errors.Is(err, service.ErrUnknownOIDCProvider) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "unknown identity provider"})
+			return
+		}
+		c.JSON(http.StatusBadGateway, gin.H{"error": "identity provider unavailable"})
+		return
+	}
+
+	c.JSON(http.StatusOK, model.OIDCAuthorization{AuthorizationURL: authURL})
+}
+
+// BeginLink returns the provider URL to redirect the browser to, so the
+// provider account the caller signs in with is linked to them
+func (h *OIDCHandler) BeginLink(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	authURL, err := h.oidcService.BeginLink(c.Request.Context(), c.Param("provider"), userID)
+	if err != nil {
+		if errors.Is(err, service.ErrUnknownOIDCProvider) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "unknown identity provider"})
+			return
+		}
+		c.JSON(http.StatusBadGateway, gin.H{"error": "identity provider unavailable"})
+		return
+	}
+
+	c.JSON(http.StatusOK, model.OIDCAuthorization{AuthorizationURL: authURL})
+}
+
+// Callback completes the login with the code the provider redirected back
+// with. Like a password login, it may return an MFA challenge instead of
+// tokens.
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	var req model.OIDCCallbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, challenge, err := h.authService.LoginWithOIDC(c.Request.Context(), req.Code, req.State, clientInfo(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidOIDCState), errors.Is(err, service.ErrUnknownOIDCProvider):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid or expired login, please try again"})
+		case errors.Is(err, service.ErrOIDCVerification):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "identity provider login failed"})
+		case errors.Is(err, service.ErrOIDCEmailRequired):
+			c.JSON(http.StatusForbidden, gin.H{"error": "identity provider did not confirm an email address"})
+		case errors.Is(err, service.ErrOIDCAccountExists):
+			c.JSON(http.StatusConflict, gin.H{"error": "an account with this email already exists, sign in and link the identity provider from your profile"})
+		case errors.Is(err, service.ErrOIDCIdentityInUse):
+			c.JSON(http.StatusConflict, gin.H{"error": "identity provider account is linked to another user"})
+		case errors.Is(err, service.ErrUserSuspended):
+			c.JSON(http.StatusForbidden, gin.H{"error": "account suspended"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to login"})
+		}
+		return
+	}
+
+	if challenge != nil {
+		c.JSON(http.StatusOK, challenge)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
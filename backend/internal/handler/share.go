@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"todo-app/backend/internal/model"
+	"todo-app/backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type ShareHandler struct {
+	shareService *service.ShareService
+}
+
+func NewShareHandler(shareService *service.ShareService) *ShareHandler {
+	return &ShareHandler{shareService: shareService}
+}
+
+// GetShares lists the grants on ?todo_id=, or the grants the caller has
+// received when no todo is given.
+func (h *ShareHandler) GetShares(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	var (
+		shares []model.Share
+		err    error
+	)
+
+	if todoID := c.Query("todo_id"); todoID != "" {
+		todoUUID, parseErr := uuid.Parse(todoID)
+		if parseErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid todo id"})
+			return
+		}
+		shares, err = h.shareService.ListTodoShares(userID, todoUUID)
+	} else {
+		shares, err = h.shareService.ListReceivedShares(userID)
+	}
+
+	if err != nil {
+		respondShareError(c, err, "failed to fetch shares")
+		return
+	}
+
+	c.JSON(http.StatusOK, shares)
+}
+
+func (h *ShareHandler) CreateShare(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	var req model.CreateShareRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	share, err := h.shareService.CreateShare(userID, req)
+	if err != nil {
+		respondShareError(c, err, "failed to share todo")
+		return
+	}
+
+	c.JSON(http.StatusCreated, share)
+}
+
+func (h *ShareHandler) DeleteShare(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	shareUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid share id"})
+		return
+	}
+
+	err = h.shareService.DeleteShare(userID, shareUUID)
+	if err != nil {
+		respondShareError(c, err, "failed to revoke share")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "share revoked successfully"})
+}
+
+func respondShareError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrTodoNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "todo not found"})
+	case errors.Is(err, service.ErrShareNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "share not found"})
+	case errors.Is(err, service.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+	case errors.Is(err, service.ErrShareForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": "only owners can manage shares"})
+	case errors.Is(err, service.ErrCannotShareWithSelf):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cannot share a todo with its owner"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
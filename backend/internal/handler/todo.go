@@ -21,7 +21,18 @@ func NewTodoHandler(todoService *service.TodoService) *TodoHandler {
 func (h *TodoHandler) GetTodos(c *gin.Context) {
 	userID := c.MustGet("user_id").(uuid.UUID)
 
-	todos, err := h.todoService.GetTodos(userID)
+	var filter model.TodoFilter
+	if assignedTo := c.Query("assigned_to"); assignedTo != "" {
+		assigneeID, err := parseAssignee(assignedTo, userID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid assigned_to"})
+			return
+		}
+		filter.AssigneeID = &assigneeID
+	}
+	filter.WorkspaceID = activeWorkspace(c)
+
+	todos, err := h.todoService.ForCaller(c.GetString("token")).ListTodos(userID, filter)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch todos"})
 		return
@@ -40,7 +51,7 @@ func (h *TodoHandler) GetTodo(c *gin.Context) {
 		return
 	}
 
-	todo, err := h.todoService.GetTodo(userID, todoUUID)
+	todo, err := h.todoService.ForCaller(c.GetString("token")).GetTodo(userID, todoUUID)
 	if err != nil {
 		if errors.Is(err, service.ErrTodoNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "todo not found"})
@@ -62,7 +73,13 @@ func (h *TodoHandler) CreateTodo(c *gin.Context) {
 		return
 	}
 
-	todo, err := h.todoService.CreateTodo(userID, req.Title, req.Description)
+	// Guests can read a workspace's todos but not add to it
+	if c.GetString("workspace_role") == model.WorkspaceRoleGuest {
+		c.JSON(http.StatusForbidden, gin.H{"error": "guests cannot create todos in this workspace"})
+		return
+	}
+
+	todo, err := h.todoService.CreateTodo(userID, activeWorkspace(c), req.Title, req.Description)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create todo"})
 		return
@@ -122,3 +139,90 @@ func (h *TodoHandler) DeleteTodo(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "todo deleted successfully"})
 }
+
+// GetInbox lists the open todos assigned to the caller
+func (h *TodoHandler) GetInbox(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	todos, err := h.todoService.ForCaller(c.GetString("token")).Inbox(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch inbox"})
+		return
+	}
+
+	c.JSON(http.StatusOK, todos)
+}
+
+func (h *TodoHandler) AssignTodo(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+	todoID := c.Param("id")
+
+	todoUUID, err := uuid.Parse(todoID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid todo id"})
+		return
+	}
+
+	var req model.AssignTodoRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	todo, err := h.todoService.AssignTodo(userID, todoUUID, req.AssigneeID)
+	if err != nil {
+		if errors.Is(err, service.ErrTodoNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "todo not found"})
+			return
+		}
+		if errors.Is(err, service.ErrAssigneeNoAccess) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "assignee does not have access to the todo"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to assign todo"})
+		return
+	}
+
+	c.JSON(http.StatusOK, todo)
+}
+
+func (h *TodoHandler) GetAssignmentEvents(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+	todoID := c.Param("id")
+
+	todoUUID, err := uuid.Parse(todoID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid todo id"})
+		return
+	}
+
+	events, err := h.todoService.ListAssignmentEvents(userID, todoUUID)
+	if err != nil {
+		if errors.Is(err, service.ErrTodoNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "todo not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch assignment history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, events)
+}
+
+// parseAssignee accepts "me" or a user id
+func parseAssignee(value string, userID uuid.UUID) (uuid.UUID, error) {
+	if value == "me" {
+		return userID, nil
+	}
+	return uuid.Parse(value)
+}
+
+// activeWorkspace returns the workspace selected by WorkspaceMiddleware, if any
+func activeWorkspace(c *gin.Context) *uuid.UUID {
+	value, ok := c.Get("workspace_id")
+	if !ok {
+		return nil
+	}
+	workspaceID := value.(uuid.UUID)
+	return &workspaceID
+}
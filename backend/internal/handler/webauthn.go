@@ -0,0 +1,144 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"todo-app/backend/internal/model"
+	"todo-app/backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type WebAuthnHandler struct {
+	webauthnService *service.WebAuthnService
+	authService     *service.AuthService
+}
+
+func NewWebAuthnHandler(webauthnService *service.WebAuthnService, authService *service.AuthService) *WebAuthnHandler {
+	return &WebAuthnHandler{
+		webauthnService: webauthnService,
+		authService:     authService,
+	}
+}
+
+// BeginRegistration returns the options for navigator.credentials.create
+func (h *WebAuthnHandler) BeginRegistration(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	var req model.PasswordConfirmationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	options, err := h.webauthnService.BeginRegistration(userID, req.Password)
+	if err != nil {
+		respondWebAuthnError(c, err, "failed to start passkey registration")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"publicKey": options})
+}
+
+// FinishRegistration stores the passkey the browser created
+func (h *WebAuthnHandler) FinishRegistration(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	var req model.FinishWebAuthnRegistrationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	credential, err := h.webauthnService.FinishRegistration(userID, req.Name, &req.Credential)
+	if err != nil {
+		respondWebAuthnError(c, err, "failed to register passkey")
+		return
+	}
+
+	c.JSON(http.StatusCreated, credential)
+}
+
+func (h *WebAuthnHandler) ListCredentials(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	credentials, err := h.webauthnService.ListCredentials(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch passkeys"})
+		return
+	}
+
+	c.JSON(http.StatusOK, credentials)
+}
+
+func (h *WebAuthnHandler) DeleteCredential(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	credentialID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid passkey id"})
+		return
+	}
+
+	if err := h.webauthnService.DeleteCredential(userID, credentialID); err != nil {
+		respondWebAuthnError(c, err, "failed to delete passkey")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "passkey deleted"})
+}
+
+// BeginLogin returns the options for navigator.credentials.get
+func (h *WebAuthnHandler) BeginLogin(c *gin.Context) {
+	options, err := h.webauthnService.BeginLogin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start passkey login"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"publicKey": options})
+}
+
+// FinishLogin signs the user in with the passkey assertion
+func (h *WebAuthnHandler) FinishLogin(c *gin.Context) {
+	var req model.FinishWebAuthnLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, err := h.authService.LoginWithPasskey(&req.Credential, clientInfo(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidWebAuthnChallenge), errors.Is(err, service.ErrWebAuthnVerification):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "passkey login failed"})
+		case errors.Is(err, service.ErrUserSuspended):
+			c.JSON(http.StatusForbidden, gin.H{"error": "account suspended"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to login"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func respondWebAuthnError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrIncorrectPassword):
+		c.JSON(http.StatusForbidden, gin.H{"error": "incorrect password"})
+	case errors.Is(err, service.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+	case errors.Is(err, service.ErrInvalidWebAuthnChallenge):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid or expired challenge"})
+	case errors.Is(err, service.ErrWebAuthnVerification):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrWebAuthnCredentialExists):
+		c.JSON(http.StatusConflict, gin.H{"error": "passkey already registered"})
+	case errors.Is(err, service.ErrWebAuthnCredentialNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "passkey not found"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
@@ -0,0 +1,191 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"todo-app/backend/internal/model"
+	"todo-app/backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type WorkspaceHandler struct {
+	workspaceService *service.WorkspaceService
+}
+
+func NewWorkspaceHandler(workspaceService *service.WorkspaceService) *WorkspaceHandler {
+	return &WorkspaceHandler{workspaceService: workspaceService}
+}
+
+func (h *WorkspaceHandler) GetWorkspaces(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	workspaces, err := h.workspaceService.ListWorkspaces(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch workspaces"})
+		return
+	}
+
+	c.JSON(http.StatusOK, workspaces)
+}
+
+func (h *WorkspaceHandler) CreateWorkspace(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	var req model.CreateWorkspaceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	workspace, err := h.workspaceService.CreateWorkspace(userID, req.Name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create workspace"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, workspace)
+}
+
+func (h *WorkspaceHandler) GetWorkspace(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+	workspaceID := c.MustGet("workspace_id").(uuid.UUID)
+
+	workspace, err := h.workspaceService.GetWorkspace(userID, workspaceID)
+	if err != nil {
+		respondWorkspaceError(c, err, "failed to fetch workspace")
+		return
+	}
+
+	c.JSON(http.StatusOK, workspace)
+}
+
+func (h *WorkspaceHandler) UpdateWorkspace(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+	workspaceID := c.MustGet("workspace_id").(uuid.UUID)
+
+	var req model.UpdateWorkspaceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	workspace, err := h.workspaceService.UpdateWorkspace(userID, workspaceID, req.Name)
+	if err != nil {
+		respondWorkspaceError(c, err, "failed to update workspace")
+		return
+	}
+
+	c.JSON(http.StatusOK, workspace)
+}
+
+func (h *WorkspaceHandler) DeleteWorkspace(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+	workspaceID := c.MustGet("workspace_id").(uuid.UUID)
+
+	err := h.workspaceService.DeleteWorkspace(userID, workspaceID)
+	if err != nil {
+		respondWorkspaceError(c, err, "failed to delete workspace")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "workspace deleted successfully"})
+}
+
+func (h *WorkspaceHandler) GetMembers(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+	workspaceID := c.MustGet("workspace_id").(uuid.UUID)
+
+	members, err := h.workspaceService.ListMembers(userID, workspaceID)
+	if err != nil {
+		respondWorkspaceError(c, err, "failed to fetch members")
+		return
+	}
+
+	c.JSON(http.StatusOK, members)
+}
+
+func (h *WorkspaceHandler) AddMember(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+	workspaceID := c.MustGet("workspace_id").(uuid.UUID)
+
+	var req model.AddWorkspaceMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	member, err := h.workspaceService.AddMember(userID, workspaceID, req)
+	if err != nil {
+		respondWorkspaceError(c, err, "failed to add member")
+		return
+	}
+
+	c.JSON(http.StatusCreated, member)
+}
+
+func (h *WorkspaceHandler) UpdateMember(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+	workspaceID := c.MustGet("workspace_id").(uuid.UUID)
+
+	memberUUID, err := uuid.Parse(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	var req model.UpdateWorkspaceMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	member, err := h.workspaceService.UpdateMemberRole(userID, workspaceID, memberUUID, req.Role)
+	if err != nil {
+		respondWorkspaceError(c, err, "failed to update member")
+		return
+	}
+
+	c.JSON(http.StatusOK, member)
+}
+
+func (h *WorkspaceHandler) RemoveMember(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+	workspaceID := c.MustGet("workspace_id").(uuid.UUID)
+
+	memberUUID, err := uuid.Parse(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	err = h.workspaceService.RemoveMember(userID, workspaceID, memberUUID)
+	if err != nil {
+		respondWorkspaceError(c, err, "failed to remove member")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "member removed successfully"})
+}
+
+func respondWorkspaceError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrWorkspaceNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "workspace not found"})
+	case errors.Is(err, service.ErrNotWorkspaceMember):
+		c.JSON(http.StatusForbidden, gin.H{"error": "not a member of this workspace"})
+	case errors.Is(err, service.ErrWorkspaceForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient workspace role"})
+	case errors.Is(err, service.ErrMemberNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "workspace member not found"})
+	case errors.Is(err, service.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+	case errors.Is(err, service.ErrAlreadyMember):
+		c.JSON(http.StatusConflict, gin.H{"error": "user is already a member of this workspace"})
+	case errors.Is(err, service.ErrLastWorkspaceOwner):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "a workspace must keep at least one owner"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
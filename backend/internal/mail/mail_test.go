@@ -0,0 +1,87 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileMailer(t *testing.T) {
+	dir := t.TempDir()
+
+	mailer, err := NewFileMailer(dir, "noreply@example.com")
+	if err != nil {
+		t.Fatalf("failed to create mailer: %v", err)
+	}
+
+	err = mailer.Send(context.Background(), Message{To: "alice@example.com", Subject: "Hello", Body: "line one\nline two"})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("expected one message file, got %d (%v)", len(entries), err)
+	}
+
+	data, err := os.ReadFile(dir + "/" + entries[0].Name())
+	if err != nil {
+		t.Fatalf("failed to read message: %v", err)
+	}
+
+	for _, want := range []string{"From: noreply@example.com\r\n", "To: alice@example.com\r\n", "Subject: Hello\r\n", "\r\n\r\nline one\r\nline two\r\n"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("expected message to contain %q, got %q", want, data)
+		}
+	}
+}
+
+func TestFormat_RejectsHeaderInjection(t *testing.T) {
+	_, err := format("noreply@example.com", Message{To: "alice@example.com\r\nBcc: eve@example.com", Subject: "Hi"}, time.Now())
+	if !errors.Is(err, ErrInvalidHeader) {
+		t.Fatalf("expected ErrInvalidHeader, got %v", err)
+	}
+}
+
+func TestAsync_ReportsErrors(t *testing.T) {
+	errs := make(chan error, 1)
+	failing := mailerFunc(func(ctx context.Context, msg Message) error {
+		return errors.New("relay down")
+	})
+
+	mailer := Async(failing, time.Second, func(msg Message, err error) { errs <- err })
+	if err := mailer.Send(context.Background(), Message{To: "alice@example.com"}); err != nil {
+		t.Fatalf("expected Send to return immediately without error, got %v", err)
+	}
+
+	select {
+	case err := <-errs:
+		if err == nil || err.Error() != "relay down" {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected the delivery error to be reported")
+	}
+}
+
+func TestLogMailer(t *testing.T) {
+	var buf bytes.Buffer
+	mailer := NewLogMailer(&buf, "noreply@example.com")
+
+	if err := mailer.Send(context.Background(), Message{To: "alice@example.com", Subject: "Hi", Body: "body"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !strings.Contains(buf.String(), "To: alice@example.com") {
+		t.Fatalf("expected message in log output, got %q", buf.String())
+	}
+}
+
+type mailerFunc func(ctx context.Context, msg Message) error
+
+func (f mailerFunc) Send(ctx context.Context, msg Message) error {
+	return f(ctx, msg)
+}
@@ -0,0 +1,80 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"strings"
+	"time"
+)
+
+// ErrInvalidHeader is returned for header values that could inject headers
+var ErrInvalidHeader = errors.New("mail header contains line breaks")
+
+// Message is a plain-text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers messages
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// format renders msg as an RFC 5322 message
+func format(from string, msg Message, date time.Time) ([]byte, error) {
+	for _, value := range []string{from, msg.To, msg.Subject} {
+		if strings.ContainsAny(value, "\r\n") {
+			return nil, ErrInvalidHeader
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	buf.WriteString("\r\n")
+
+	body := strings.ReplaceAll(msg.Body, "\r\n", "\n")
+	for _, line := range strings.Split(body, "\n") {
+		// Dot-stuffing is handled by the SMTP client; only normalize endings
+		buf.WriteString(line)
+		buf.WriteString("\r\n")
+	}
+
+	return buf.Bytes(), nil
+}
+
+// asyncMailer hands messages to a background goroutine
+type asyncMailer struct {
+	next    Mailer
+	timeout time.Duration
+	onError func(Message, error)
+}
+
+// Async returns a Mailer whose Send returns immediately. Delivery happens in
+// the background with its own timeout and failures are reported to onError.
+// It keeps slow mail servers out of request latency and response timing.
+func Async(next Mailer, timeout time.Duration, onError func(Message, error)) Mailer {
+	return &asyncMailer{next: next, timeout: timeout, onError: onError}
+}
+
+func (m *asyncMailer) Send(ctx context.Context, msg Message) error {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+		defer cancel()
+
+		if err := m.next.Send(ctx, msg); err != nil && m.onError != nil {
+			m.onError(msg, err)
+		}
+	}()
+	return nil
+}
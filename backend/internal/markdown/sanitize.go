@@ -0,0 +1,94 @@
+package markdown
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	htmlCommentPattern = regexp.MustCompile(`(?s)<!--.*?-->`)
+	htmlTagPattern     = regexp.MustCompile(`</?[A-Za-z][^>]*>`)
+	tagOpenPattern     = regexp.MustCompile(`<([A-Za-z/!?])`)
+	linkPattern        = regexp.MustCompile(`(!?\[[^\]]*\]\()\s*((?:[^\s()]|\([^\s()]*\))*)([^)]*\))`)
+	referencePattern   = regexp.MustCompile(`(?m)^( {0,3}\[[^\]]+\]:[ \t]*\n?[ \t]*)(\S+)`)
+	mentionPattern     = regexp.MustCompile(`(?:^|[^\w@])@([A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,})`)
+)
+
+var allowedSchemes = []string{"http:", "https:", "mailto:"}
+
+// Sanitize strips raw HTML and unsafe link targets from a markdown body so
+// that it can be rendered by clients without further escaping.
+func Sanitize(body string) string {
+	body = strings.ReplaceAll(body, "\r\n", "\n")
+	body = strings.Map(func(r rune) rune {
+		if r < 0x20 && r != '\n' && r != '\t' {
+			return -1
+		}
+		return r
+	}, body)
+
+	body = htmlCommentPattern.ReplaceAllString(body, "")
+	body = htmlTagPattern.ReplaceAllString(body, "")
+	// Whatever could still open a tag, such as one left unclosed, is escaped
+	body = tagOpenPattern.ReplaceAllString(body, "&lt;$1")
+	body = linkPattern.ReplaceAllStringFunc(body, func(link string) string {
+		parts := linkPattern.FindStringSubmatch(link)
+		if isSafeURL(parts[2]) {
+			return link
+		}
+		return parts[1] + "#" + parts[3]
+	})
+	body = referencePattern.ReplaceAllStringFunc(body, func(definition string) string {
+		parts := referencePattern.FindStringSubmatch(definition)
+		if isSafeURL(parts[2]) {
+			return definition
+		}
+		return parts[1] + "#"
+	})
+
+	return strings.TrimSpace(body)
+}
+
+// Mentions returns the distinct email addresses mentioned as @email in the
+// body, as written, in the order they first appear.
+func Mentions(body string) []string {
+	seen := map[string]bool{}
+	var emails []string
+
+	for _, match := range mentionPattern.FindAllStringSubmatch(body, -1) {
+		email := strings.TrimRight(match[1], ".")
+		if seen[email] {
+			continue
+		}
+		seen[email] = true
+		emails = append(emails, email)
+	}
+
+	return emails
+}
+
+// isSafeURL reports whether a link target is relative or uses an allowed
+// scheme, as a browser would read it: entities decoded and whitespace
+// dropped
+func isSafeURL(url string) bool {
+	lower := strings.ToLower(strings.Join(strings.Fields(html.UnescapeString(url)), ""))
+	lower = strings.TrimPrefix(lower, "<")
+	colon := strings.Index(lower, ":")
+	if colon == -1 {
+		return true
+	}
+
+	// A colon after a path, query or fragment delimiter is not a scheme
+	if slash := strings.IndexAny(lower, "/?#"); slash != -1 && slash < colon {
+		return true
+	}
+
+	for _, scheme := range allowedSchemes {
+		if strings.HasPrefix(lower, scheme) {
+			return true
+		}
+	}
+
+	return false
+}
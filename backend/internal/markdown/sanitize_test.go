@@ -0,0 +1,39 @@
+package markdown
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSanitize(t *testing.T) {
+	cases := map[string]string{
+		"plain **bold** text":                      "plain **bold** text",
+		"hi <script>alert(1)</script>":             "hi alert(1)",
+		"<!-- hidden -->visible":                   "visible",
+		"[ok](https://example.com)":                "[ok](https://example.com)",
+		"[relative](/todos/1)":                     "[relative](/todos/1)",
+		"[bad](javascript:alert(1))":               "[bad](#)",
+		"![img](data:image/png;base64,AAAA \"t\")": "![img](# \"t\")",
+		"a < b and c > d":                          "a < b and c > d",
+		"<img src=x onerror=alert(1)":              "&lt;img src=x onerror=alert(1)",
+		"[x][1]\n\n[1]: javascript:alert(1)":       "[x][1]\n\n[1]: #",
+		"[x][1]\n\n[1]: https://example.com":       "[x][1]\n\n[1]: https://example.com",
+		"[x](javascript&#58;alert(1))":             "[x](#)",
+		"[x](java&#x09;script:alert(1))":           "[x](#)",
+	}
+
+	for input, want := range cases {
+		if got := Sanitize(input); got != want {
+			t.Errorf("Sanitize(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestMentions(t *testing.T) {
+	got := Mentions("hey @alice@example.com and @bob@example.org, cc @alice@example.com. mail carol@example.com @Dave@Example.com")
+	want := []string{"alice@example.com", "bob@example.org", "Dave@Example.com"}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Mentions = %v, want %v", got, want)
+	}
+}
@@ -1,14 +1,34 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
 	"strings"
 	"todo-app/backend/internal/auth"
+	"todo-app/backend/internal/service"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
-func AuthMiddleware(jwtSecret string) gin.HandlerFunc {
+// TokenRevocations reports whether a validly signed token has been revoked
+type TokenRevocations interface {
+	IsRevoked(claims *auth.Claims) (bool, error)
+}
+
+// SessionTracker records that a session was just used
+type SessionTracker interface {
+	Touch(sessionID uuid.UUID) error
+}
+
+// AccessTokens resolves personal access tokens to their owner's claims
+type AccessTokens interface {
+	Authenticate(token string) (*auth.Claims, error)
+}
+
+// AuthMiddleware accepts session JWTs and personal access tokens. Routes
+// limit what access tokens can do with RequireScopes or RequireSession.
+func AuthMiddleware(keys *auth.Keyring, revocations TokenRevocations, sessions SessionTracker, tokens AccessTokens) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -24,28 +44,68 @@ func AuthMiddleware(jwtSecret string) gin.HandlerFunc {
 			return
 		}
 
-		claims, err := auth.ValidateToken(parts[1], jwtSecret)
+		if auth.IsAccessToken(parts[1]) {
+			claims, err := tokens.Authenticate(parts[1])
+			if err != nil {
+				if errors.Is(err, service.ErrInvalidAccessToken) {
+					c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+				} else if errors.Is(err, service.ErrUserSuspended) {
+					c.JSON(http.StatusForbidden, gin.H{"error": "account suspended"})
+				} else {
+					c.JSON(http.StatusServiceUnavailable, gin.H{"error": "failed to verify token"})
+				}
+				c.Abort()
+				return
+			}
+
+			setClaims(c, claims)
+			c.Set("scopes", claims.Scopes)
+			c.Next()
+			return
+		}
+
+		claims, err := auth.ValidateToken(parts[1], keys)
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
 			c.Abort()
 			return
 		}
 
-		c.Set("user_id", claims.UserID)
-		c.Set("email", claims.Email)
-		c.Set("role", claims.Role)
-		c.Next()
-	}
-}
+		revoked, err := revocations.IsRevoked(claims)
+		if errors.Is(err, service.ErrUserSuspended) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "account suspended"})
+			c.Abort()
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "failed to verify token"})
+			c.Abort()
+			return
+		}
 
-func AdminMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		role, exists := c.Get("role")
-		if !exists || role != "admin" {
-			c.JSON(http.StatusForbidden, gin.H{"error": "admin access required"})
+		if revoked {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "token has been revoked"})
 			c.Abort()
 			return
 		}
+
+		// Last-seen times are informational; a failed write must not fail
+		// the request
+		_ = sessions.Touch(claims.SessionID)
+
+		setClaims(c, claims)
+		// Kept so requests to Hasura can be made as the caller
+		c.Set("token", parts[1])
 		c.Next()
 	}
 }
+
+func setClaims(c *gin.Context, claims *auth.Claims) {
+	c.Set("claims", claims)
+	c.Set("session_id", claims.SessionID)
+	c.Set("user_id", claims.UserID)
+	c.Set("email", claims.Email)
+	c.Set("email_verified", claims.EmailVerified)
+	c.Set("role", claims.Role)
+	c.Set("mfa", claims.MFA)
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"net/http"
+	"todo-app/backend/internal/auth"
+	"todo-app/backend/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ImpersonatedByHeader names the administrator on every response to an
+// impersonation token
+const ImpersonatedByHeader = "X-Impersonated-By"
+
+// ImpersonationAudit records requests made while impersonating a user
+type ImpersonationAudit interface {
+	RecordImpersonatedRequest(claims *auth.Claims, method, path string, client model.ClientInfo) error
+}
+
+// Impersonation records every request made with an impersonation token in
+// the audit log before serving it, and marks the response with the
+// administrator. A request that cannot be recorded is not served.
+func Impersonation(audit ImpersonationAudit) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := c.MustGet("claims").(*auth.Claims)
+		if !ok || !claims.Impersonated() {
+			c.Next()
+			return
+		}
+
+		err := audit.RecordImpersonatedRequest(claims, c.Request.Method, c.Request.URL.Path, model.ClientInfo{
+			UserAgent: c.Request.UserAgent(),
+			IPAddress: c.ClientIP(),
+		})
+		if err != nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "failed to record impersonated request"})
+			c.Abort()
+			return
+		}
+
+		c.Header(ImpersonatedByHeader, claims.Act.Email)
+		c.Next()
+	}
+}
+
+// RejectImpersonation keeps impersonation tokens away from routes that
+// change the account, its credentials or anyone else's
+func RejectImpersonation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if claims, ok := c.MustGet("claims").(*auth.Claims); ok && claims.Impersonated() {
+			c.JSON(http.StatusForbidden, gin.H{"error": "not allowed while impersonating a user"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireMFA rejects users in one of the given roles whose session did not
+// pass a second factor. They can still reach the routes outside it, which
+// include enrolling.
+func RequireMFA(roles []string) gin.HandlerFunc {
+	required := make(map[string]bool, len(roles))
+	for _, role := range roles {
+		required[role] = true
+	}
+
+	return func(c *gin.Context) {
+		if required[c.GetString("role")] && !c.GetBool("mfa") {
+			c.JSON(http.StatusForbidden, gin.H{"error": "two-factor authentication required"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
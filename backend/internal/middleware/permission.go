@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Permissions resolves what a platform role is allowed to do
+type Permissions interface {
+	HasPermission(role, permission string) (bool, error)
+}
+
+// RequirePermission only lets through users whose role grants every one of
+// the permissions
+func RequirePermission(roles Permissions, permissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := c.GetString("role")
+		for _, permission := range permissions {
+			ok, err := roles.HasPermission(role, permission)
+			if err != nil {
+				c.JSON(http.StatusServiceUnavailable, gin.H{"error": "failed to check permissions"})
+				c.Abort()
+				return
+			}
+			if !ok {
+				c.JSON(http.StatusForbidden, gin.H{"error": "permission " + permission + " required"})
+				c.Abort()
+				return
+			}
+		}
+		c.Next()
+	}
+}
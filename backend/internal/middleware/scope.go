@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireScopes rejects personal access tokens that lack any of the scopes.
+// Sessions have every scope.
+func RequireScopes(scopes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, isAccessToken := c.Get("scopes")
+		if !isAccessToken {
+			c.Next()
+			return
+		}
+
+		granted := value.([]string)
+		for _, scope := range scopes {
+			if !hasScope(granted, scope) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "token lacks scope " + scope, "required_scopes": strings.Join(scopes, " ")})
+				c.Abort()
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// RequireReadWriteScopes requires the read scope for safe methods and the
+// write scope for everything else, for groups mixing both
+func RequireReadWriteScopes(read, write string) gin.HandlerFunc {
+	readOnly := RequireScopes(read)
+	readWrite := RequireScopes(write)
+
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			readOnly(c)
+		default:
+			readWrite(c)
+		}
+	}
+}
+
+// RequireSession rejects personal access tokens outright, for routes that
+// manage the account and its credentials
+func RequireSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, isAccessToken := c.Get("scopes"); isAccessToken {
+			c.JSON(http.StatusForbidden, gin.H{"error": "this endpoint requires signing in"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+func hasScope(granted []string, scope string) bool {
+	for _, s := range granted {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
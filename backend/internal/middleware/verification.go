@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Policies for accounts that have not confirmed their email address
+const (
+	// UnverifiedAllow places no restrictions on unverified accounts
+	UnverifiedAllow = "allow"
+	// UnverifiedNoSharing keeps unverified accounts from sharing todos or
+	// inviting workspace members
+	UnverifiedNoSharing = "no_sharing"
+	// UnverifiedReadOnly keeps unverified accounts from changing anything
+	UnverifiedReadOnly = "read_only"
+)
+
+// UnverifiedAccountMiddleware enforces the unverified-account policy.
+// Routes that share content with other users pass sharing=true. Safe methods
+// are always allowed so unverified users can still see their data.
+func UnverifiedAccountMiddleware(policy string, sharing bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetBool("email_verified") || isSafeMethod(c.Request.Method) {
+			c.Next()
+			return
+		}
+
+		if policy == UnverifiedReadOnly || (policy == UnverifiedNoSharing && sharing) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "email verification required"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func isSafeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"todo-app/backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// WorkspaceHeader selects the active workspace on routes without a
+// :workspaceId parameter
+const WorkspaceHeader = "X-Workspace-ID"
+
+// WorkspaceMembership resolves a user's role in a workspace
+type WorkspaceMembership interface {
+	MemberRole(workspaceID, userID uuid.UUID) (string, error)
+}
+
+// WorkspaceMiddleware resolves the active workspace from the :workspaceId
+// route parameter or the X-Workspace-ID header and rejects callers who are
+// not members. Requests that name no workspace run in the caller's personal
+// scope. Must run after AuthMiddleware.
+func WorkspaceMiddleware(memberships WorkspaceMembership) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		workspaceID := c.Param("workspaceId")
+		if workspaceID == "" {
+			workspaceID = c.GetHeader(WorkspaceHeader)
+		}
+		if workspaceID == "" {
+			c.Next()
+			return
+		}
+
+		workspaceUUID, err := uuid.Parse(workspaceID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid workspace id"})
+			c.Abort()
+			return
+		}
+
+		userID := c.MustGet("user_id").(uuid.UUID)
+
+		role, err := memberships.MemberRole(workspaceUUID, userID)
+		if err != nil {
+			if errors.Is(err, service.ErrNotWorkspaceMember) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "not a member of this workspace"})
+			} else {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check workspace membership"})
+			}
+			c.Abort()
+			return
+		}
+
+		c.Set("workspace_id", workspaceUUID)
+		c.Set("workspace_role", role)
+		c.Next()
+	}
+}
+
+// RequireWorkspaceRole only lets members holding one of the given roles in
+// the active workspace through. Must run after WorkspaceMiddleware.
+func RequireWorkspaceRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := c.GetString("workspace_role")
+		for _, allowed := range roles {
+			if role == allowed {
+				c.Next()
+				return
+			}
+		}
+
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient workspace role"})
+		c.Abort()
+	}
+}
@@ -0,0 +1,63 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Audited actions
+const (
+	AuditActionImpersonationStart  = "impersonation.start"
+	AuditActionImpersonatedRequest = "impersonation.request"
+	AuditActionUserSuspend         = "user.suspend"
+	AuditActionUserReactivate      = "user.reactivate"
+	AuditActionUserRoleSync        = "user.role_sync"
+	AuditActionUserReassignTodos   = "user.reassign_todos"
+)
+
+// AuditLog is one recorded action by an actor to or as a user
+type AuditLog struct {
+	ID         uuid.UUID              `json:"id"`
+	Action     string                 `json:"action"`
+	ActorID    *uuid.UUID             `json:"actor_id"`
+	ActorEmail string                 `json:"actor_email"`
+	UserID     uuid.UUID              `json:"user_id"`
+	Method     string                 `json:"method"`
+	Path       string                 `json:"path"`
+	IPAddress  string                 `json:"ip_address"`
+	UserAgent  string                 `json:"user_agent"`
+	Details    map[string]interface{} `json:"details"`
+	CreatedAt  time.Time              `json:"created_at"`
+}
+
+// AuditFilter narrows audit log listings. Nil and empty fields are not
+// applied.
+type AuditFilter struct {
+	UserID  *uuid.UUID
+	ActorID *uuid.UUID
+	Action  string
+	Limit   int
+}
+
+type ImpersonateRequest struct {
+	// Reason is shown to the user and kept in the audit log
+	Reason string `json:"reason" binding:"required,max=500"`
+}
+
+// ImpersonationResponse carries a short-lived access token acting as the
+// user. There is no refresh token; impersonation ends when it expires.
+type ImpersonationResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+	User      User      `json:"user"`
+}
+
+// Impersonation is what a user is shown about an administrator having
+// signed in as them
+type Impersonation struct {
+	ActorEmail string    `json:"actor_email"`
+	Reason     string    `json:"reason"`
+	StartedAt  time.Time `json:"started_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
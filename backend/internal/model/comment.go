@@ -0,0 +1,30 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Comment struct {
+	ID        uuid.UUID        `json:"id"`
+	TodoID    uuid.UUID        `json:"todo_id"`
+	AuthorID  uuid.UUID        `json:"author_id"`
+	Body      string           `json:"body"`
+	Mentions  []CommentMention `json:"mentions"`
+	EditedAt  *time.Time       `json:"edited_at"`
+	CreatedAt time.Time        `json:"created_at"`
+	UpdatedAt time.Time        `json:"updated_at"`
+}
+
+type CommentMention struct {
+	UserID uuid.UUID `json:"user_id"`
+}
+
+type CreateCommentRequest struct {
+	Body string `json:"body" binding:"required,max=10000"`
+}
+
+type UpdateCommentRequest struct {
+	Body string `json:"body" binding:"required,max=10000"`
+}
@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// Lockouts are kept per account and per client address
+const (
+	LockoutScopeAccount = "account"
+	LockoutScopeIP      = "ip"
+)
+
+// Lockout counts the failed logins of an account, keyed by email, or of a
+// client address
+type Lockout struct {
+	Scope        string     `json:"scope"`
+	Subject      string     `json:"subject"`
+	Failures     int        `json:"failures"`
+	LastFailedAt time.Time  `json:"last_failed_at"`
+	LockedUntil  *time.Time `json:"locked_until"`
+}
@@ -0,0 +1,18 @@
+package model
+
+// OIDCProviders lists the identity providers users can sign in with
+type OIDCProviders struct {
+	Providers []string `json:"providers"`
+}
+
+// OIDCAuthorization is where to send the user to sign in at a provider
+type OIDCAuthorization struct {
+	AuthorizationURL string `json:"authorization_url"`
+}
+
+// OIDCCallbackRequest carries the parameters the provider redirected back
+// with
+type OIDCCallbackRequest struct {
+	Code  string `json:"code" binding:"required"`
+	State string `json:"state" binding:"required"`
+}
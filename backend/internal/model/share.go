@@ -0,0 +1,23 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Share struct {
+	ID        uuid.UUID  `json:"id"`
+	TodoID    uuid.UUID  `json:"todo_id"`
+	UserID    uuid.UUID  `json:"user_id"`
+	Email     string     `json:"email"`
+	Role      string     `json:"role"`
+	GrantedBy *uuid.UUID `json:"granted_by"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type CreateShareRequest struct {
+	TodoID uuid.UUID `json:"todo_id" binding:"required"`
+	Email  string    `json:"email" binding:"required,email"`
+	Role   string    `json:"role" binding:"required,oneof=viewer editor owner"`
+}
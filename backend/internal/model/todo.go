@@ -7,13 +7,16 @@ import (
 )
 
 type Todo struct {
-	ID          uuid.UUID `json:"id"`
-	UserID      uuid.UUID `json:"user_id"`
-	Title       string    `json:"title"`
-	Description *string   `json:"description"`
-	Completed   bool      `json:"completed"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID          uuid.UUID  `json:"id"`
+	UserID      uuid.UUID  `json:"user_id"`
+	Title       string     `json:"title"`
+	Description *string    `json:"description"`
+	Completed   bool       `json:"completed"`
+	AssigneeID  *uuid.UUID `json:"assignee_id"`
+	WorkspaceID *uuid.UUID `json:"workspace_id"`
+	Permission  string     `json:"permission,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
 }
 
 type CreateTodoRequest struct {
@@ -26,3 +29,31 @@ type UpdateTodoRequest struct {
 	Description *string `json:"description"`
 	Completed   *bool   `json:"completed"`
 }
+
+type AssignTodoRequest struct {
+	// AssigneeID is the user to assign; null clears the assignment
+	AssigneeID *uuid.UUID `json:"assignee_id"`
+}
+
+// TodoFilter narrows todo listings. Nil fields are not applied.
+type TodoFilter struct {
+	AssigneeID  *uuid.UUID
+	Completed   *bool
+	WorkspaceID *uuid.UUID
+}
+
+type AssignmentEvent struct {
+	ID                 uuid.UUID  `json:"id"`
+	TodoID             uuid.UUID  `json:"todo_id"`
+	AssigneeID         *uuid.UUID `json:"assignee_id"`
+	PreviousAssigneeID *uuid.UUID `json:"previous_assignee_id"`
+	AssignedBy         *uuid.UUID `json:"assigned_by"`
+	CreatedAt          time.Time  `json:"created_at"`
+}
+
+// Permission levels a user can hold on a todo, from weakest to strongest
+const (
+	PermissionViewer = "viewer"
+	PermissionEditor = "editor"
+	PermissionOwner  = "owner"
+)
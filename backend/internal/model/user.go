@@ -6,18 +6,64 @@ import (
 	"github.com/google/uuid"
 )
 
+// Account statuses. Only active accounts, and accounts pending deletion
+// during the grace period, can sign in.
+const (
+	UserStatusActive          = "active"
+	UserStatusSuspended       = "suspended"
+	UserStatusPendingDeletion = "pending_deletion"
+)
+
 type User struct {
-	ID           uuid.UUID `json:"id"`
-	Email        string    `json:"email"`
-	PasswordHash string    `json:"-"`
-	Role         string    `json:"role"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID              uuid.UUID  `json:"id"`
+	Email           string     `json:"email"`
+	PasswordHash    string     `json:"-"`
+	Role            string     `json:"role"`
+	Status          string     `json:"status"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	// SuspendedAt and SuspensionReason are only shown to administrators
+	SuspendedAt      *time.Time `json:"suspended_at,omitempty"`
+	SuspensionReason string     `json:"suspension_reason,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+// UserSummary is a user as listed to administrators, with their activity
+type UserSummary struct {
+	User
+	TodoCount      int        `json:"todo_count"`
+	CompletedCount int        `json:"completed_count"`
+	LastLoginAt    *time.Time `json:"last_login_at"`
+}
+
+// User listing orders. A leading "-" sorts descending.
+const (
+	UserSortCreatedAt = "created_at"
+	UserSortEmail     = "email"
+	UserSortTodoCount = "todo_count"
+	UserSortLastLogin = "last_login"
+)
+
+// UserFilter narrows and orders user listings. Empty fields are not applied.
+type UserFilter struct {
+	// Query matches part of the email address, ignoring case
+	Query        string
+	Role         string
+	Status       string
+	CreatedAfter *time.Time
+	Sort         string
+	Limit        int
+	Offset       int
+}
+
+type SuspendUserRequest struct {
+	// Reason is kept for administrators and the audit log
+	Reason string `json:"reason" binding:"required,max=500"`
 }
 
 type RegisterRequest struct {
 	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,min=6"`
+	Password string `json:"password" binding:"required"`
 }
 
 type LoginRequest struct {
@@ -26,6 +72,44 @@ type LoginRequest struct {
 }
 
 type LoginResponse struct {
-	Token string `json:"token"`
-	User  User   `json:"user"`
+	Token            string    `json:"token"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	RefreshToken     string    `json:"refresh_token"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+	User             User      `json:"user"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
+
+type ChangeEmailRequest struct {
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required"`
+}
+
+type DeleteAccountRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+type DeleteAccountResponse struct {
+	DeletionScheduledAt time.Time `json:"deletion_scheduled_at"`
 }
@@ -0,0 +1,26 @@
+package model
+
+import (
+	"time"
+	"todo-app/backend/internal/webauthn"
+
+	"github.com/google/uuid"
+)
+
+// WebAuthnCredential is a registered passkey
+type WebAuthnCredential struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+type FinishWebAuthnRegistrationRequest struct {
+	// Name helps the user tell their passkeys apart, e.g. "Work laptop"
+	Name       string                          `json:"name" binding:"max=100"`
+	Credential webauthn.RegistrationCredential `json:"credential" binding:"required"`
+}
+
+type FinishWebAuthnLoginRequest struct {
+	Credential webauthn.AssertionCredential `json:"credential" binding:"required"`
+}
@@ -0,0 +1,178 @@
+package oidc_test
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+	"todo-app/backend/internal/oidc"
+	"todo-app/backend/internal/oidc/oidctest"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const redirectURL = "http://localhost:3000/auth/oidc/callback"
+
+func newProvider(t *testing.T) (*oidctest.Provider, *oidc.Provider) {
+	t.Helper()
+
+	mock, err := oidctest.New("todo-app", "client-secret")
+	if err != nil {
+		t.Fatalf("failed to start provider: %v", err)
+	}
+	t.Cleanup(mock.Close)
+
+	provider := oidc.NewProvider(oidc.Config{
+		Issuer:       mock.Issuer,
+		ClientID:     mock.ClientID,
+		ClientSecret: mock.ClientSecret,
+		RedirectURL:  redirectURL,
+		Scopes:       []string{"email", "profile"},
+	}, nil)
+	return mock, provider
+}
+
+func TestProvider_AuthorizationCodeFlow(t *testing.T) {
+	ctx := context.Background()
+	mock, provider := newProvider(t)
+	mock.SignIn(map[string]interface{}{
+		"sub":            "user-123",
+		"email":          "user@example.com",
+		"email_verified": true,
+		"groups":         []string{"staff", "todo-admins"},
+	})
+
+	verifier, challenge, err := oidc.NewPKCE()
+	if err != nil {
+		t.Fatalf("failed to create PKCE pair: %v", err)
+	}
+
+	authURL, err := provider.AuthCodeURL(ctx, "state-1", "nonce-1", challenge)
+	if err != nil {
+		t.Fatalf("failed to build authorization URL: %v", err)
+	}
+	parsed, _ := url.Parse(authURL)
+	if got := parsed.Query().Get("scope"); got != "openid email profile" {
+		t.Fatalf("unexpected scope %q", got)
+	}
+
+	code, state, err := mock.Authorize(authURL)
+	if err != nil {
+		t.Fatalf("failed to authorize: %v", err)
+	}
+	if state != "state-1" {
+		t.Fatalf("expected state to round-trip, got %q", state)
+	}
+
+	t.Run("wrong code verifier", func(t *testing.T) {
+		otherVerifier, _, _ := oidc.NewPKCE()
+		_, err := provider.Exchange(ctx, code, otherVerifier, "nonce-1")
+		if !errors.Is(err, oidc.ErrTokenExchange) {
+			t.Fatalf("expected ErrTokenExchange, got %v", err)
+		}
+	})
+
+	// The failed attempt used up the code, as providers do
+	code, _, err = mock.Authorize(authURL)
+	if err != nil {
+		t.Fatalf("failed to authorize: %v", err)
+	}
+
+	token, err := provider.Exchange(ctx, code, verifier, "nonce-1")
+	if err != nil {
+		t.Fatalf("failed to exchange code: %v", err)
+	}
+	if token.Subject != "user-123" || token.Email != "user@example.com" || !token.EmailVerified {
+		t.Fatalf("unexpected token %+v", token)
+	}
+	if groups := oidc.StringsClaim(token.Claims, "groups"); len(groups) != 2 || groups[1] != "todo-admins" {
+		t.Fatalf("unexpected groups %v", groups)
+	}
+}
+
+func TestProvider_VerifyIDToken(t *testing.T) {
+	ctx := context.Background()
+	mock, provider := newProvider(t)
+
+	valid := func() map[string]interface{} {
+		now := time.Now()
+		return map[string]interface{}{
+			"iss":   mock.Issuer,
+			"aud":   mock.ClientID,
+			"sub":   "user-123",
+			"iat":   now.Unix(),
+			"exp":   now.Add(time.Hour).Unix(),
+			"nonce": "nonce-1",
+		}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(claims map[string]interface{})
+		nonce  string
+	}{
+		{name: "wrong nonce", modify: func(map[string]interface{}) {}, nonce: "other"},
+		{name: "wrong audience", modify: func(c map[string]interface{}) { c["aud"] = "someone-else" }, nonce: "nonce-1"},
+		{name: "wrong issuer", modify: func(c map[string]interface{}) { c["iss"] = "https://evil.example.com" }, nonce: "nonce-1"},
+		{name: "expired", modify: func(c map[string]interface{}) { c["exp"] = time.Now().Add(-time.Hour).Unix() }, nonce: "nonce-1"},
+		{name: "missing expiry", modify: func(c map[string]interface{}) { delete(c, "exp") }, nonce: "nonce-1"},
+		{name: "other authorized party", modify: func(c map[string]interface{}) {
+			c["aud"] = []string{mock.ClientID, "someone-else"}
+			c["azp"] = "someone-else"
+		}, nonce: "nonce-1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := valid()
+			tt.modify(claims)
+
+			raw, err := mock.IDToken(claims)
+			if err != nil {
+				t.Fatalf("failed to sign token: %v", err)
+			}
+
+			if _, err := provider.VerifyIDToken(ctx, raw, tt.nonce); !errors.Is(err, oidc.ErrInvalidIDToken) {
+				t.Fatalf("expected ErrInvalidIDToken, got %v", err)
+			}
+		})
+	}
+
+	t.Run("symmetric signature", func(t *testing.T) {
+		// Signed with the client secret, which the client knows too
+		raw, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims(valid())).SignedString([]byte(mock.ClientSecret))
+		if err != nil {
+			t.Fatalf("failed to sign token: %v", err)
+		}
+
+		if _, err := provider.VerifyIDToken(ctx, raw, "nonce-1"); !errors.Is(err, oidc.ErrInvalidIDToken) {
+			t.Fatalf("expected ErrInvalidIDToken, got %v", err)
+		}
+	})
+
+	t.Run("valid", func(t *testing.T) {
+		raw, err := mock.IDToken(valid())
+		if err != nil {
+			t.Fatalf("failed to sign token: %v", err)
+		}
+
+		token, err := provider.VerifyIDToken(ctx, raw, "nonce-1")
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if token.Subject != "user-123" {
+			t.Fatalf("expected subject user-123, got %q", token.Subject)
+		}
+	})
+}
+
+func TestProvider_DiscoveryFailure(t *testing.T) {
+	mock, _ := newProvider(t)
+
+	// The mock serves no discovery document under /tenant
+	provider := oidc.NewProvider(oidc.Config{Issuer: mock.Issuer + "/tenant", ClientID: mock.ClientID}, nil)
+	if _, err := provider.Metadata(context.Background()); !errors.Is(err, oidc.ErrDiscovery) {
+		t.Fatalf("expected ErrDiscovery, got %v", err)
+	}
+}
@@ -0,0 +1,50 @@
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// RangeFiles checks passwords against an offline copy of the Pwned
+// Passwords range files: one file per five-character SHA-1 prefix, named
+// like 21BD1.txt, listing the remaining hash suffixes as SUFFIX:COUNT. A
+// lookup reads the one file for its prefix, as the online k-anonymity API
+// would answer it.
+type RangeFiles struct {
+	dir string
+}
+
+func NewRangeFiles(dir string) *RangeFiles {
+	return &RangeFiles{dir: dir}
+}
+
+func (r *RangeFiles) Breached(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:5], hash[5:]
+
+	f, err := os.Open(filepath.Join(r.dir, prefix+".txt"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		candidate, count, _ := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		// Padding entries have a count of zero
+		if strings.EqualFold(candidate, suffix) && count != "0" {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
@@ -0,0 +1,118 @@
+package password
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Rules a password can break
+const (
+	RuleMinLength     = "min_length"
+	RuleMaxLength     = "max_length"
+	RuleContainsEmail = "contains_email"
+	RuleTooWeak       = "too_weak"
+	RuleBreached      = "breached"
+)
+
+// Violation explains one rule a password breaks
+type Violation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// BreachChecker reports whether a password has appeared in a data breach
+type BreachChecker interface {
+	Breached(password string) (bool, error)
+}
+
+// Policy is what new passwords must satisfy. The zero Policy accepts
+// anything.
+type Policy struct {
+	// MinLength and MaxLength count characters; a MaxLength of zero is no
+	// limit
+	MinLength int
+	MaxLength int
+	// MinScore is the lowest acceptable Estimate score, from 0 to 4
+	MinScore int
+	// DisallowEmail rejects passwords containing the account's email or
+	// the name part of it
+	DisallowEmail bool
+	// Breaches, when set, rejects passwords known from breaches
+	Breaches BreachChecker
+}
+
+// Check returns every rule the password breaks for the account with the
+// given email. Only a failing breach lookup is an error.
+func (p Policy) Check(password, email string) ([]Violation, error) {
+	var violations []Violation
+
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		violations = append(violations, Violation{
+			Rule:    RuleMinLength,
+			Message: fmt.Sprintf("password must be at least %d characters", p.MinLength),
+		})
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		violations = append(violations, Violation{
+			Rule:    RuleMaxLength,
+			Message: fmt.Sprintf("password must be at most %d characters", p.MaxLength),
+		})
+	}
+
+	if p.DisallowEmail && containsEmail(password, email) {
+		violations = append(violations, Violation{
+			Rule:    RuleContainsEmail,
+			Message: "password must not contain your email address",
+		})
+	}
+
+	if p.MinScore > 0 {
+		if estimate := Estimate(password, emailInputs(email)...); estimate.Score < p.MinScore {
+			message := fmt.Sprintf("password is too easy to guess (strength %d of 4, at least %d required)", estimate.Score, p.MinScore)
+			if estimate.Warning != "" {
+				message += ": " + estimate.Warning
+			}
+			violations = append(violations, Violation{Rule: RuleTooWeak, Message: message})
+		}
+	}
+
+	if p.Breaches != nil {
+		breached, err := p.Breaches.Breached(password)
+		if err != nil {
+			return nil, err
+		}
+		if breached {
+			violations = append(violations, Violation{
+				Rule:    RuleBreached,
+				Message: "password has appeared in a data breach and must not be used",
+			})
+		}
+	}
+
+	return violations, nil
+}
+
+// containsEmail reports whether the password contains the email or its
+// name part. Very short names are ignored, since they turn up by chance.
+func containsEmail(password, email string) bool {
+	password = strings.ToLower(password)
+	for _, input := range emailInputs(email) {
+		if len(input) >= 3 && strings.Contains(password, input) {
+			return true
+		}
+	}
+	return false
+}
+
+// emailInputs are the parts of an email a password should not be built
+// from: the address and its name part
+func emailInputs(email string) []string {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		return nil
+	}
+	name, _, _ := strings.Cut(email, "@")
+	return []string{email, name}
+}
@@ -0,0 +1,104 @@
+package password
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func rules(violations []Violation) []string {
+	var names []string
+	for _, v := range violations {
+		names = append(names, v.Rule)
+	}
+	return names
+}
+
+func TestEstimate(t *testing.T) {
+	weak := []string{"password", "P@ssw0rd1", "abcd1234", "qwertyuiop", "aaaaaaaa", "1234567890"}
+	for _, password := range weak {
+		if s := Estimate(password); s.Score > 0 || s.Warning == "" {
+			t.Fatalf("expected %q to score 0 with a warning, got %+v", password, s)
+		}
+	}
+
+	strong := []string{"correct horse battery staple", "x7#Lq9!vB2mZ"}
+	for _, password := range strong {
+		if s := Estimate(password); s.Score < 4 {
+			t.Fatalf("expected %q to score 4, got %+v", password, s)
+		}
+	}
+
+	if s := Estimate("alice2024", "alice"); s.Score > 0 || !strings.Contains(s.Warning, "email") {
+		t.Fatalf("expected user inputs to count as guessable, got %+v", s)
+	}
+}
+
+func TestPolicy_Check(t *testing.T) {
+	policy := Policy{MinLength: 8, MaxLength: 64, MinScore: 2, DisallowEmail: true}
+
+	t.Run("reports every broken rule", func(t *testing.T) {
+		violations, err := policy.Check("alice1", "alice@example.com")
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if got := strings.Join(rules(violations), ","); got != "min_length,contains_email,too_weak" {
+			t.Fatalf("unexpected violations %s", got)
+		}
+		for _, v := range violations {
+			if v.Message == "" {
+				t.Fatalf("expected a message for %s", v.Rule)
+			}
+		}
+	})
+
+	t.Run("accepts a strong password", func(t *testing.T) {
+		violations, err := policy.Check("violet kettle marching", "alice@example.com")
+		if err != nil || len(violations) != 0 {
+			t.Fatalf("expected no violations, got %+v, %v", violations, err)
+		}
+	})
+
+	t.Run("limits length", func(t *testing.T) {
+		violations, _ := policy.Check(strings.Repeat("x7#Lq9!v", 9), "")
+		if got := strings.Join(rules(violations), ","); got != "max_length" {
+			t.Fatalf("unexpected violations %s", got)
+		}
+	})
+
+	t.Run("zero policy accepts anything", func(t *testing.T) {
+		if violations, err := (Policy{}).Check("a", "a@example.com"); err != nil || len(violations) != 0 {
+			t.Fatalf("expected no violations, got %+v, %v", violations, err)
+		}
+	})
+}
+
+func TestRangeFiles_Breached(t *testing.T) {
+	dir := t.TempDir()
+
+	sum := sha1.Sum([]byte("violet kettle marching"))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	contents := fmt.Sprintf("0000000000000000000000000000000000A:3\r\n%s:12\r\n", strings.ToLower(hash[5:]))
+	if err := os.WriteFile(filepath.Join(dir, hash[:5]+".txt"), []byte(contents), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	policy := Policy{Breaches: NewRangeFiles(dir)}
+
+	violations, err := policy.Check("violet kettle marching", "")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if got := strings.Join(rules(violations), ","); got != "breached" {
+		t.Fatalf("expected the password to be breached, got %s", got)
+	}
+
+	// Prefixes without a file have no breaches
+	if violations, err := policy.Check("x7#Lq9!vB2mZ", ""); err != nil || len(violations) != 0 {
+		t.Fatalf("expected no violations, got %+v, %v", violations, err)
+	}
+}
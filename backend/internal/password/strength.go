@@ -0,0 +1,268 @@
+package password
+
+import (
+	_ "embed"
+	"math"
+	"strings"
+	"unicode"
+)
+
+//go:embed common.txt
+var commonList string
+
+// commonRanks maps common passwords and words to their popularity rank
+var commonRanks = func() map[string]int {
+	ranks := make(map[string]int)
+	for _, word := range strings.Fields(commonList) {
+		if _, ok := ranks[word]; !ok {
+			ranks[word] = len(ranks) + 1
+		}
+	}
+	return ranks
+}()
+
+// maxWordLength bounds dictionary lookups
+const maxWordLength = 32
+
+// keyboardRows are the runs of adjacent keys people walk along
+var keyboardRows = []string{"1234567890", "qwertyuiop", "asdfghjkl", "zxcvbnm"}
+
+// leet undoes common character substitutions before dictionary lookups
+var leet = strings.NewReplacer("4", "a", "@", "a", "8", "b", "3", "e", "6", "g", "1", "i", "!", "i", "0", "o", "$", "s", "5", "s", "7", "t", "+", "t", "2", "z")
+
+// Strength is an estimate of how hard a password is to guess
+type Strength struct {
+	// Score runs from 0 (guessable in a few tries) to 4 (very hard)
+	Score int
+	// Guesses is the log10 of the number of guesses an attacker who knows
+	// the common patterns needs
+	Guesses float64
+	// Warning names the weakest pattern found, if any
+	Warning string
+}
+
+// Estimate scores a password the way zxcvbn does, more roughly: it splits
+// the password into the cheapest-to-guess patterns it can find (common
+// passwords and words, userInputs such as the email, repeats, sequences,
+// keyboard walks and years) and counts guesses for each, treating the rest
+// as random characters.
+func Estimate(password string, userInputs ...string) Strength {
+	runes := []rune(password)
+	if len(runes) == 0 {
+		return Strength{Warning: "password is empty"}
+	}
+
+	inputs := make(map[string]bool, len(userInputs))
+	for _, input := range userInputs {
+		if len(input) >= 3 {
+			inputs[strings.ToLower(input)] = true
+		}
+	}
+
+	var guesses float64
+	var warning string
+	weakest := math.Inf(1)
+	segments := 0
+
+	for i := 0; i < len(runes); {
+		length, cost, hint := bestMatch(runes[i:], inputs)
+		if length == 0 {
+			// A random character: zxcvbn's brute-force cardinality
+			length, cost = 1, 1
+		} else if cost/float64(length) < weakest {
+			weakest = cost / float64(length)
+			warning = hint
+		}
+		guesses += cost
+		segments++
+		i += length
+	}
+
+	// The attacker must also guess how the patterns are combined
+	guesses += math.Log10(float64(segments))
+
+	return Strength{Score: score(guesses), Guesses: guesses, Warning: warning}
+}
+
+// score buckets log10 guesses with zxcvbn's thresholds
+func score(guesses float64) int {
+	switch {
+	case guesses < 3:
+		return 0
+	case guesses < 6:
+		return 1
+	case guesses < 8:
+		return 2
+	case guesses < 10:
+		return 3
+	default:
+		return 4
+	}
+}
+
+// bestMatch returns the length, log10 guesses and warning of the longest
+// pattern at the start of runes, or a zero length when there is none
+func bestMatch(runes []rune, inputs map[string]bool) (int, float64, string) {
+	type match struct {
+		length int
+		cost   float64
+		hint   string
+	}
+	var best match
+
+	consider := func(length int, cost float64, hint string) {
+		if length > best.length || (length == best.length && cost < best.cost) {
+			best = match{length, cost, hint}
+		}
+	}
+
+	if n, cost, hint := dictionaryMatch(runes, inputs); n > 0 {
+		consider(n, cost, hint)
+	}
+	if n := repeatLength(runes); n >= 3 {
+		consider(n, math.Log10(float64(cardinality(runes[0])*n)), "repeated characters are easy to guess")
+	}
+	if n, base := sequenceLength(runes); n >= 3 {
+		consider(n, math.Log10(float64(base*n)), "sequences like abc or 321 are easy to guess")
+	}
+	if n := keyboardLength(runes); n >= 3 {
+		consider(n, math.Log10(float64(40*n)), "keyboard patterns are easy to guess")
+	}
+	if isYear(runes) {
+		consider(4, math.Log10(120), "years are easy to guess")
+	}
+
+	return best.length, best.cost, best.hint
+}
+
+// dictionaryMatch finds the longest common word or user input at the start
+// of runes, seen through capitalisation and leet substitutions
+func dictionaryMatch(runes []rune, inputs map[string]bool) (int, float64, string) {
+	n := len(runes)
+	if n > maxWordLength {
+		n = maxWordLength
+	}
+	for ; n >= 3; n-- {
+		word := string(runes[:n])
+		lower := strings.ToLower(word)
+		plain := leet.Replace(lower)
+
+		rank := 0
+		hint := "this is a common password or word"
+		if inputs[lower] || inputs[plain] {
+			rank, hint = 1, "passwords built from your email are easy to guess"
+		} else if r, ok := commonRanks[lower]; ok {
+			rank = r
+		} else if r, ok := commonRanks[plain]; ok {
+			rank = r
+		}
+		if rank == 0 {
+			continue
+		}
+
+		cost := math.Log10(float64(rank))
+		if lower != word {
+			// Capitalised, all caps or mixed
+			cost += math.Log10(2)
+		}
+		if plain != lower {
+			cost += math.Log10(2)
+		}
+		return n, cost, hint
+	}
+	return 0, 0, ""
+}
+
+func repeatLength(runes []rune) int {
+	n := 1
+	for n < len(runes) && runes[n] == runes[0] {
+		n++
+	}
+	return n
+}
+
+// sequenceLength measures a run of letters or digits stepping by one, and
+// how many such runs an attacker would try
+func sequenceLength(runes []rune) (int, int) {
+	if len(runes) < 2 {
+		return 0, 0
+	}
+	delta := runes[1] - runes[0]
+	if delta != 1 && delta != -1 {
+		return 0, 0
+	}
+
+	n := 1
+	for n < len(runes) && runes[n]-runes[n-1] == delta && sameClass(runes[n], runes[0]) {
+		n++
+	}
+
+	base := 26
+	switch {
+	case strings.ContainsRune("aAzZ019", runes[0]):
+		// The obvious starting points
+		base = 4
+	case unicode.IsDigit(runes[0]):
+		base = 10
+	}
+	return n, base
+}
+
+// keyboardLength measures a walk along one keyboard row, either way
+func keyboardLength(runes []rune) int {
+	lower := []rune(strings.ToLower(string(runes)))
+	best := 0
+	for _, row := range keyboardRows {
+		for _, walk := range []string{row, reverse(row)} {
+			start := strings.IndexRune(walk, lower[0])
+			if start < 0 {
+				continue
+			}
+			n := 0
+			for n < len(lower) && start+n < len(walk) && rune(walk[start+n]) == lower[n] {
+				n++
+			}
+			if n > best {
+				best = n
+			}
+		}
+	}
+	return best
+}
+
+// isYear reports whether runes start with a year from 1900 to 2039
+func isYear(runes []rune) bool {
+	if len(runes) < 4 {
+		return false
+	}
+	for _, r := range runes[:4] {
+		if !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	century := string(runes[:2])
+	return century == "19" || (century == "20" && runes[2] <= '3')
+}
+
+func cardinality(r rune) int {
+	switch {
+	case unicode.IsDigit(r):
+		return 10
+	case unicode.IsLower(r), unicode.IsUpper(r):
+		return 26
+	default:
+		return 33
+	}
+}
+
+func sameClass(a, b rune) bool {
+	return unicode.IsDigit(a) == unicode.IsDigit(b) && unicode.IsLower(a) == unicode.IsLower(b) && unicode.IsUpper(a) == unicode.IsUpper(b)
+}
+
+func reverse(s string) string {
+	runes := []rune(s)
+	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+		runes[i], runes[j] = runes[j], runes[i]
+	}
+	return string(runes)
+}
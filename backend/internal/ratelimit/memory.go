@@ -0,0 +1,86 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often full buckets are dropped from memory
+const sweepInterval = time.Minute
+
+// MemoryStore keeps buckets in process memory, so each instance limits on
+// its own
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// bucket records when it will be full again rather than how many tokens it
+// holds, so refilling needs no bookkeeping: every token taken pushes fullAt
+// one interval later, and a bucket whose fullAt is a whole period away is
+// empty
+type bucket struct {
+	fullAt time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	if limit.Unlimited() {
+		return Result{Allowed: true}, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{fullAt: now}
+		s.buckets[key] = b
+	}
+	if b.fullAt.Before(now) {
+		b.fullAt = now
+	}
+
+	interval := limit.interval()
+	fullAt := b.fullAt.Add(interval)
+	if used := fullAt.Sub(now); used > limit.Period {
+		// Taking a token would overdraw the bucket
+		return Result{
+			Reset:      b.fullAt.Sub(now),
+			RetryAfter: used - limit.Period,
+		}, nil
+	}
+
+	b.fullAt = fullAt
+	return Result{
+		Allowed:   true,
+		Remaining: int((limit.Period - fullAt.Sub(now)) / interval),
+		Reset:     fullAt.Sub(now),
+	}, nil
+}
+
+// sweep drops buckets that have refilled, which behave like missing ones
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+
+	for key, b := range s.buckets {
+		if !b.fullAt.After(now) {
+			delete(s.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidLimit is returned for limits that are not "requests/period"
+var ErrInvalidLimit = errors.New("rate limit must look like 100/1m")
+
+// Limit is a token bucket holding up to Requests tokens that refills
+// completely over Period. The zero Limit is unlimited.
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// ParseLimit reads a limit such as "100/1m". An empty string or "off" is
+// unlimited.
+func ParseLimit(s string) (Limit, error) {
+	if s == "" || s == "off" {
+		return Limit{}, nil
+	}
+
+	requests, period, ok := strings.Cut(s, "/")
+	if !ok {
+		return Limit{}, ErrInvalidLimit
+	}
+	n, err := strconv.Atoi(requests)
+	if err != nil || n < 1 {
+		return Limit{}, ErrInvalidLimit
+	}
+	d, err := time.ParseDuration(period)
+	if err != nil || d <= 0 {
+		return Limit{}, ErrInvalidLimit
+	}
+
+	return Limit{Requests: n, Period: d}, nil
+}
+
+// Unlimited reports whether the limit lets everything through
+func (l Limit) Unlimited() bool {
+	return l.Requests == 0
+}
+
+// String formats the limit as the RateLimit-Policy header does
+func (l Limit) String() string {
+	return fmt.Sprintf("%d;w=%d", l.Requests, int(l.Period.Seconds()))
+}
+
+// interval is how long one token takes to come back
+func (l Limit) interval() time.Duration {
+	return l.Period / time.Duration(l.Requests)
+}
+
+// Result is the outcome of taking a token
+type Result struct {
+	Allowed bool
+	// Remaining is how many requests the bucket still allows right away
+	Remaining int
+	// Reset is how long until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is how long until the next request is allowed, when this
+	// one was not
+	RetryAfter time.Duration
+}
+
+// Store keeps the buckets. Instances share limits only when they share a
+// store.
+type Store interface {
+	// Take removes a token from the bucket under key, creating a full
+	// bucket for limit if there is none
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
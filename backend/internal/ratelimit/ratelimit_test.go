@@ -0,0 +1,67 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseLimit(t *testing.T) {
+	limit, err := ParseLimit("100/1m")
+	if err != nil || limit.Requests != 100 || limit.Period != time.Minute {
+		t.Fatalf("expected 100 per minute, got %+v, %v", limit, err)
+	}
+	if limit.String() != "100;w=60" {
+		t.Fatalf("expected the policy 100;w=60, got %q", limit.String())
+	}
+
+	for _, s := range []string{"", "off"} {
+		if limit, err := ParseLimit(s); err != nil || !limit.Unlimited() {
+			t.Fatalf("expected %q to be unlimited, got %+v, %v", s, limit, err)
+		}
+	}
+
+	for _, s := range []string{"100", "0/1m", "x/1m", "100/0s", "100/minute"} {
+		if _, err := ParseLimit(s); !errors.Is(err, ErrInvalidLimit) {
+			t.Fatalf("expected %q to be rejected, got %v", s, err)
+		}
+	}
+}
+
+func TestMemoryStore_Take(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	limit := Limit{Requests: 3, Period: 3 * time.Second}
+
+	for want := 2; want >= 0; want-- {
+		result, err := store.Take(context.Background(), "alice", limit)
+		if err != nil || !result.Allowed || result.Remaining != want {
+			t.Fatalf("expected %d remaining, got %+v, %v", want, result, err)
+		}
+	}
+
+	result, _ := store.Take(context.Background(), "alice", limit)
+	if result.Allowed || result.RetryAfter != time.Second || result.Reset != 3*time.Second {
+		t.Fatalf("expected a denial for one second, got %+v", result)
+	}
+
+	// Other keys have their own bucket
+	if result, _ := store.Take(context.Background(), "bob", limit); !result.Allowed {
+		t.Fatalf("expected another key to be allowed")
+	}
+
+	// Tokens come back one interval at a time
+	now = now.Add(time.Second)
+	if result, _ := store.Take(context.Background(), "alice", limit); !result.Allowed || result.Remaining != 0 {
+		t.Fatalf("expected one refilled token, got %+v", result)
+	}
+
+	// Refilled buckets are swept
+	now = now.Add(time.Hour)
+	_, _ = store.Take(context.Background(), "carol", limit)
+	if len(store.buckets) != 1 {
+		t.Fatalf("expected full buckets to be dropped, got %d", len(store.buckets))
+	}
+}
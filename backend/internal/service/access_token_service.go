@@ -0,0 +1,262 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+	"todo-app/backend/internal/auth"
+	"todo-app/backend/internal/cache"
+	"todo-app/backend/internal/model"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidAccessToken  = errors.New("invalid access token")
+	ErrAccessTokenNotFound = errors.New("access token not found")
+	ErrInvalidScope        = errors.New("invalid scope")
+	ErrInvalidExpiry       = errors.New("expiry must be in the future")
+)
+
+type AccessTokenOptions struct {
+	// TouchInterval is the minimum time between last-used writes for one
+	// token
+	TouchInterval time.Duration
+	// CacheSize bounds the number of tokens tracked for throttling
+	CacheSize int
+}
+
+// AccessTokenService manages personal access tokens: long-lived bearer
+// tokens for scripts, limited to scopes chosen by the user
+type AccessTokenService struct {
+	hasura  *HasuraClient
+	roles   *RoleService
+	touched *cache.LRU[uuid.UUID, struct{}]
+	opts    AccessTokenOptions
+}
+
+func NewAccessTokenService(hasura *HasuraClient, roles *RoleService, opts AccessTokenOptions) *AccessTokenService {
+	return &AccessTokenService{
+		hasura:  hasura,
+		roles:   roles,
+		touched: cache.NewLRU[uuid.UUID, struct{}](opts.CacheSize),
+		opts:    opts,
+	}
+}
+
+// CreateToken issues a token for the user. The token is returned only
+// here. It inherits whether the creating session passed a second factor, so
+// it cannot be used to get around an MFA requirement.
+func (s *AccessTokenService) CreateToken(userID uuid.UUID, role string, mfaVerified bool, req model.CreateAccessTokenRequest) (*model.CreatedAccessToken, error) {
+	scopes := make([]string, 0, len(req.Scopes))
+	seen := make(map[string]bool, len(req.Scopes))
+	for _, scope := range req.Scopes {
+		if !auth.ValidScope(scope) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidScope, scope)
+		}
+		if scope == auth.ScopeAdmin {
+			permissions, err := s.roles.Permissions(role)
+			if err != nil {
+				return nil, err
+			}
+			if len(permissions) == 0 {
+				return nil, fmt.Errorf("%w: %q requires a role with administration permissions", ErrInvalidScope, scope)
+			}
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, ErrInvalidExpiry
+	}
+
+	token, prefix, err := auth.GenerateAccessToken()
+	if err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		InsertPersonalAccessTokensOne model.AccessToken `json:"insert_personal_access_tokens_one"`
+	}
+
+	err = s.hasura.execute(`
+        mutation ($object: personal_access_tokens_insert_input!) {
+          insert_personal_access_tokens_one(object: $object) {
+            id
+            name
+            token_prefix
+            scopes
+            expires_at
+            last_used_at
+            created_at
+          }
+        }
+        `, map[string]interface{}{
+		"object": map[string]interface{}{
+			"user_id":      userID,
+			"name":         req.Name,
+			"token_hash":   auth.HashOpaqueToken(token),
+			"token_prefix": prefix,
+			"scopes":       scopes,
+			"mfa_verified": mfaVerified,
+			"expires_at":   req.ExpiresAt,
+		},
+	}, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.CreatedAccessToken{
+		AccessToken: response.InsertPersonalAccessTokensOne,
+		Token:       token,
+	}, nil
+}
+
+// ListTokens retrieves the user's tokens, including expired ones
+func (s *AccessTokenService) ListTokens(userID uuid.UUID) ([]model.AccessToken, error) {
+	var response struct {
+		PersonalAccessTokens []model.AccessToken `json:"personal_access_tokens"`
+	}
+
+	err := s.hasura.execute(`
+        query ($userId: uuid!) {
+          personal_access_tokens(where: {user_id: {_eq: $userId}}, order_by: {created_at: desc}) {
+            id
+            name
+            token_prefix
+            scopes
+            expires_at
+            last_used_at
+            created_at
+          }
+        }
+        `, map[string]interface{}{"userId": userID}, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	return response.PersonalAccessTokens, nil
+}
+
+// DeleteToken revokes one of the user's tokens
+func (s *AccessTokenService) DeleteToken(userID, tokenID uuid.UUID) error {
+	var response struct {
+		DeletePersonalAccessTokens struct {
+			AffectedRows int `json:"affected_rows"`
+		} `json:"delete_personal_access_tokens"`
+	}
+
+	err := s.hasura.execute(`
+        mutation ($id: uuid!, $userId: uuid!) {
+          delete_personal_access_tokens(where: {id: {_eq: $id}, user_id: {_eq: $userId}}) {
+            affected_rows
+          }
+        }
+        `, map[string]interface{}{"id": tokenID, "userId": userID}, &response)
+	if err != nil {
+		return err
+	}
+
+	if response.DeletePersonalAccessTokens.AffectedRows == 0 {
+		return ErrAccessTokenNotFound
+	}
+
+	return nil
+}
+
+// Authenticate resolves a personal access token to the claims of its owner.
+// Tokens are looked up on every request, so deleting one takes effect
+// immediately. Tokens of accounts pending deletion are refused, and those of
+// suspended accounts fail with ErrUserSuspended.
+func (s *AccessTokenService) Authenticate(token string) (*auth.Claims, error) {
+	var response struct {
+		PersonalAccessTokens []struct {
+			ID          uuid.UUID `json:"id"`
+			UserID      uuid.UUID `json:"user_id"`
+			Scopes      []string  `json:"scopes"`
+			MFAVerified bool      `json:"mfa_verified"`
+			User        struct {
+				Email           string     `json:"email"`
+				Role            string     `json:"role"`
+				EmailVerifiedAt *time.Time `json:"email_verified_at"`
+				Status          string     `json:"status"`
+			} `json:"user"`
+		} `json:"personal_access_tokens"`
+	}
+
+	now := time.Now().UTC()
+	err := s.hasura.execute(`
+        query ($tokenHash: String!, $now: timestamptz!) {
+          personal_access_tokens(where: {
+            token_hash: {_eq: $tokenHash},
+            _or: [{expires_at: {_is_null: true}}, {expires_at: {_gt: $now}}],
+            user: {deletion_scheduled_at: {_is_null: true}}
+          }, limit: 1) {
+            id
+            user_id
+            scopes
+            mfa_verified
+            user {
+              email
+              role
+              email_verified_at
+              status
+            }
+          }
+        }
+        `, map[string]interface{}{"tokenHash": auth.HashOpaqueToken(token), "now": now}, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(response.PersonalAccessTokens) == 0 {
+		return nil, ErrInvalidAccessToken
+	}
+
+	record := response.PersonalAccessTokens[0]
+
+	if record.User.Status == model.UserStatusSuspended {
+		return nil, ErrUserSuspended
+	}
+
+	// Last-used times are informational; a failed write must not fail the
+	// request
+	_ = s.touch(record.ID, now)
+
+	claims := &auth.Claims{
+		UserID:        record.UserID,
+		Email:         record.User.Email,
+		EmailVerified: record.User.EmailVerifiedAt != nil,
+		Role:          record.User.Role,
+		MFA:           record.MFAVerified,
+		Scopes:        record.Scopes,
+	}
+	claims.ID = record.ID.String()
+	return claims, nil
+}
+
+// touch records that the token was just used, at most once per
+// TouchInterval
+func (s *AccessTokenService) touch(tokenID uuid.UUID, now time.Time) error {
+	if _, ok := s.touched.Get(tokenID); ok {
+		return nil
+	}
+	s.touched.Set(tokenID, struct{}{}, now.Add(s.opts.TouchInterval))
+
+	var response struct {
+		UpdatePersonalAccessTokensByPk *struct {
+			ID uuid.UUID `json:"id"`
+		} `json:"update_personal_access_tokens_by_pk"`
+	}
+
+	return s.hasura.execute(`
+        mutation ($id: uuid!, $now: timestamptz!) {
+          update_personal_access_tokens_by_pk(pk_columns: {id: $id}, _set: {last_used_at: $now}) {
+            id
+          }
+        }
+        `, map[string]interface{}{"id": tokenID, "now": now}, &response)
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+	"todo-app/backend/internal/auth"
+	"todo-app/backend/internal/model"
+
+	"github.com/google/uuid"
+)
+
+func newTestAccessTokenService(client *HasuraClient) *AccessTokenService {
+	return NewAccessTokenService(client, NewRoleService(client, RoleOptions{CacheSize: 10, CacheTTL: time.Minute}), AccessTokenOptions{TouchInterval: time.Minute, CacheSize: 10})
+}
+
+func TestAccessTokenService_CreateToken(t *testing.T) {
+	userID := uuid.New()
+
+	t.Run("unknown scope", func(t *testing.T) {
+		client, shutdown := newMockHasuraClient(t, nil)
+		defer shutdown()
+
+		_, err := newTestAccessTokenService(client).CreateToken(userID, "user", false, model.CreateAccessTokenRequest{
+			Name:   "CI",
+			Scopes: []string{"todos:delete"},
+		})
+		if !errors.Is(err, ErrInvalidScope) {
+			t.Fatalf("expected ErrInvalidScope, got %v", err)
+		}
+	})
+
+	t.Run("admin scope for a regular user", func(t *testing.T) {
+		client, shutdown := newMockHasuraClient(t, nil)
+		defer shutdown()
+
+		_, err := newTestAccessTokenService(client).CreateToken(userID, "user", false, model.CreateAccessTokenRequest{
+			Name:   "CI",
+			Scopes: []string{auth.ScopeAdmin},
+		})
+		if !errors.Is(err, ErrInvalidScope) {
+			t.Fatalf("expected ErrInvalidScope, got %v", err)
+		}
+	})
+
+	t.Run("expiry in the past", func(t *testing.T) {
+		client, shutdown := newMockHasuraClient(t, nil)
+		defer shutdown()
+
+		past := time.Now().Add(-time.Hour)
+		_, err := newTestAccessTokenService(client).CreateToken(userID, "user", false, model.CreateAccessTokenRequest{
+			Name:      "CI",
+			Scopes:    []string{auth.ScopeTodosRead},
+			ExpiresAt: &past,
+		})
+		if !errors.Is(err, ErrInvalidExpiry) {
+			t.Fatalf("expected ErrInvalidExpiry, got %v", err)
+		}
+	})
+
+	t.Run("stores only the hash", func(t *testing.T) {
+		var stored map[string]interface{}
+		client, shutdown := newMockHasuraClient(t, []mockResponse{
+			{respond: func(variables map[string]interface{}) string {
+				stored = variables["object"].(map[string]interface{})
+				return fmt.Sprintf(`{"data":{"insert_personal_access_tokens_one":{"id":"%s","name":"CI","token_prefix":%q,"scopes":["todos:read"],"expires_at":null,"last_used_at":null,"created_at":"2026-01-01T00:00:00Z"}}}`,
+					uuid.New(), stored["token_prefix"])
+			}},
+		})
+		defer shutdown()
+
+		created, err := newTestAccessTokenService(client).CreateToken(userID, "user", true, model.CreateAccessTokenRequest{
+			Name:   "CI",
+			Scopes: []string{auth.ScopeTodosRead, auth.ScopeTodosRead},
+		})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if !strings.HasPrefix(created.Token, auth.AccessTokenPrefix) {
+			t.Fatalf("expected token to start with %q, got %q", auth.AccessTokenPrefix, created.Token)
+		}
+		if stored["token_hash"] != auth.HashOpaqueToken(created.Token) {
+			t.Fatalf("expected the token hash to be stored")
+		}
+		if !strings.HasPrefix(created.Token, created.TokenPrefix) {
+			t.Fatalf("expected prefix %q to identify token", created.TokenPrefix)
+		}
+		if scopes := stored["scopes"].([]interface{}); len(scopes) != 1 {
+			t.Fatalf("expected duplicate scopes to be dropped, got %v", scopes)
+		}
+		if stored["mfa_verified"] != true {
+			t.Fatalf("expected token to inherit the session's MFA")
+		}
+	})
+}
+
+func TestAccessTokenService_Authenticate(t *testing.T) {
+	userID := uuid.New()
+	tokenID := uuid.New()
+
+	t.Run("unknown or expired token", func(t *testing.T) {
+		client, shutdown := newMockHasuraClient(t, []mockResponse{
+			{body: `{"data":{"personal_access_tokens":[]}}`},
+		})
+		defer shutdown()
+
+		_, err := newTestAccessTokenService(client).Authenticate("tdp_unknown")
+		if !errors.Is(err, ErrInvalidAccessToken) {
+			t.Fatalf("expected ErrInvalidAccessToken, got %v", err)
+		}
+	})
+
+	t.Run("valid token", func(t *testing.T) {
+		record := fmt.Sprintf(`{"data":{"personal_access_tokens":[{"id":"%s","user_id":"%s","scopes":["todos:read","todos:write"],"mfa_verified":false,"user":{"email":"user@example.com","role":"user","email_verified_at":"2026-01-01T00:00:00Z"}}]}}`, tokenID, userID)
+		client, shutdown := newMockHasuraClient(t, []mockResponse{
+			{body: record},
+			{body: fmt.Sprintf(`{"data":{"update_personal_access_tokens_by_pk":{"id":"%s"}}}`, tokenID)},
+			// The second use within the touch interval is not written
+			{body: record},
+		})
+		defer shutdown()
+
+		service := newTestAccessTokenService(client)
+		for i := 0; i < 2; i++ {
+			claims, err := service.Authenticate("tdp_valid")
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if claims.UserID != userID || !claims.EmailVerified || len(claims.Scopes) != 2 {
+				t.Fatalf("unexpected claims %+v", claims)
+			}
+		}
+	})
+}
@@ -0,0 +1,800 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+	"todo-app/backend/internal/auth"
+	"todo-app/backend/internal/mail"
+	"todo-app/backend/internal/model"
+	"todo-app/backend/internal/password"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidAccountToken     = errors.New("invalid or expired token")
+	ErrEmailAlreadyVerified    = errors.New("email already verified")
+	ErrVerificationRateLimited = errors.New("too many verification emails")
+	ErrIncorrectPassword       = errors.New("incorrect password")
+	ErrEmailUnchanged          = errors.New("email unchanged")
+	ErrPasswordPolicy          = errors.New("password does not meet the policy")
+)
+
+// PasswordPolicyError lists the rules a new password breaks. It matches
+// ErrPasswordPolicy.
+type PasswordPolicyError struct {
+	Violations []password.Violation
+}
+
+func (e *PasswordPolicyError) Error() string {
+	return ErrPasswordPolicy.Error()
+}
+
+func (e *PasswordPolicyError) Is(target error) bool {
+	return target == ErrPasswordPolicy
+}
+
+// Purposes of single-use tokens in user_tokens
+const (
+	tokenPurposePasswordReset     = "password_reset"
+	tokenPurposeEmailVerification = "email_verification"
+	tokenPurposeEmailChange       = "email_change"
+	tokenPurposeMFAChallenge      = "mfa_challenge"
+)
+
+// userToken is a consumed single-use token
+type userToken struct {
+	UserID  uuid.UUID `json:"user_id"`
+	Purpose string    `json:"purpose"`
+	// Email is the new address of an email change
+	Email *string `json:"email"`
+}
+
+type AccountOptions struct {
+	// AppURL is the frontend origin that emailed links point to
+	AppURL           string
+	PasswordResetTTL time.Duration
+	// ForgotPasswordDuration is the fixed time a forgot-password request
+	// takes, so the response does not reveal whether the account exists
+	ForgotPasswordDuration time.Duration
+	VerificationTTL        time.Duration
+	// VerificationResendInterval is the minimum time between verification
+	// emails to one user, and VerificationDailyLimit caps them per 24 hours
+	VerificationResendInterval time.Duration
+	VerificationDailyLimit     int
+	// DeletionGracePeriod is how long a deleted account can still be
+	// restored by signing in before it is removed for good
+	DeletionGracePeriod time.Duration
+	// PasswordPolicy applies to every new password: at registration, on
+	// change and on reset
+	PasswordPolicy password.Policy
+	// PasswordHasher hashes new passwords and verifies stored ones. It
+	// defaults to Argon2id with auth.DefaultArgon2Params.
+	PasswordHasher *auth.PasswordHasher
+}
+
+// AccountService handles self-service account management: password recovery
+// and changes, email verification and changes, and account deletion
+type AccountService struct {
+	hasura      *HasuraClient
+	mailer      mail.Mailer
+	revocations *RevocationService
+	opts        AccountOptions
+}
+
+func NewAccountService(hasura *HasuraClient, mailer mail.Mailer, revocations *RevocationService, opts AccountOptions) *AccountService {
+	if opts.PasswordHasher == nil {
+		opts.PasswordHasher = auth.NewPasswordHasher(auth.DefaultArgon2Params)
+	}
+	return &AccountService{
+		hasura:      hasura,
+		mailer:      mailer,
+		revocations: revocations,
+		opts:        opts,
+	}
+}
+
+// ForgotPassword emails a password reset link if the address belongs to an
+// account. It takes the same time and returns the same result either way.
+func (s *AccountService) ForgotPassword(ctx context.Context, email string) error {
+	deadline := time.Now().Add(s.opts.ForgotPasswordDuration)
+	defer func() {
+		time.Sleep(time.Until(deadline))
+	}()
+
+	var response struct {
+		Users []struct {
+			ID uuid.UUID `json:"id"`
+		} `json:"users"`
+	}
+
+	err := s.hasura.execute(`
+        query ($email: String!) {
+          users(where: {email: {_eq: $email}}, limit: 1) {
+            id
+          }
+        }
+        `, map[string]interface{}{"email": email}, &response)
+	if err != nil {
+		return err
+	}
+
+	if len(response.Users) == 0 {
+		return nil
+	}
+
+	token, err := s.issueToken(response.Users[0].ID, tokenPurposePasswordReset, "", s.opts.PasswordResetTTL)
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(ctx, mail.Message{
+		To:      email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Someone asked to reset the password for your account.\n\n"+
+			"Open this link within %s to choose a new password:\n%s\n\n"+
+			"If this wasn't you, you can ignore this email.",
+			s.opts.PasswordResetTTL, s.link("/reset-password", token)),
+	})
+}
+
+// ResetPassword sets a new password using a reset token and signs the user
+// out everywhere
+func (s *AccountService) ResetPassword(token, newPassword string) error {
+	// A rejected password must not use up the link, so it is checked first
+	var email string
+	if s.opts.PasswordPolicy.DisallowEmail {
+		var err error
+		if email, err = s.tokenEmail(token, tokenPurposePasswordReset); err != nil {
+			return err
+		}
+	}
+	if err := s.checkPasswordPolicy(newPassword, email); err != nil {
+		return err
+	}
+
+	consumed, err := s.consumeToken(token, tokenPurposePasswordReset)
+	if err != nil {
+		return err
+	}
+
+	if err := s.setPassword(consumed.UserID, newPassword); err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return ErrInvalidAccountToken
+		}
+		return err
+	}
+
+	return s.revocations.RevokeUser(consumed.UserID)
+}
+
+// ChangePassword replaces the password of a signed-in user and ends their
+// other sessions. The session making the change stays signed in.
+func (s *AccountService) ChangePassword(userID, sessionID uuid.UUID, currentPassword, newPassword string) error {
+	email, err := s.checkPassword(userID, currentPassword)
+	if err != nil {
+		return err
+	}
+
+	if err := s.checkPasswordPolicy(newPassword, email); err != nil {
+		return err
+	}
+
+	if err := s.setPassword(userID, newPassword); err != nil {
+		return err
+	}
+
+	return s.revocations.RevokeOtherSessions(userID, sessionID)
+}
+
+// VerifyEmail confirms the address a verification token was sent to. For an
+// email change this is when the new address replaces the old one.
+func (s *AccountService) VerifyEmail(token string) error {
+	consumed, err := s.consumeToken(token, tokenPurposeEmailVerification, tokenPurposeEmailChange)
+	if err != nil {
+		return err
+	}
+
+	if consumed.Purpose == tokenPurposeEmailChange && consumed.Email != nil {
+		return s.applyEmailChange(consumed.UserID, *consumed.Email)
+	}
+
+	var response struct {
+		UpdateUsersByPk *struct {
+			ID uuid.UUID `json:"id"`
+		} `json:"update_users_by_pk"`
+	}
+
+	err = s.hasura.execute(`
+        mutation ($userId: uuid!, $now: timestamptz!) {
+          update_users_by_pk(pk_columns: {id: $userId}, _set: {email_verified_at: $now}) {
+            id
+          }
+        }
+        `, map[string]interface{}{"userId": consumed.UserID, "now": time.Now().UTC()}, &response)
+	if err != nil {
+		return err
+	}
+
+	if response.UpdateUsersByPk == nil {
+		return ErrInvalidAccountToken
+	}
+
+	return nil
+}
+
+// ResendVerification emails a new verification link, subject to the resend
+// interval and daily limit
+func (s *AccountService) ResendVerification(ctx context.Context, userID uuid.UUID) error {
+	now := time.Now().UTC()
+
+	var response struct {
+		UsersByPk *struct {
+			Email           string     `json:"email"`
+			EmailVerifiedAt *time.Time `json:"email_verified_at"`
+		} `json:"users_by_pk"`
+		Recent struct {
+			Aggregate struct {
+				Count int `json:"count"`
+			} `json:"aggregate"`
+		} `json:"recent"`
+		Today struct {
+			Aggregate struct {
+				Count int `json:"count"`
+			} `json:"aggregate"`
+		} `json:"today"`
+	}
+
+	err := s.hasura.execute(`
+        query ($userId: uuid!, $purpose: String!, $recentSince: timestamptz!, $daySince: timestamptz!) {
+          users_by_pk(id: $userId) {
+            email
+            email_verified_at
+          }
+          recent: user_tokens_aggregate(where: {user_id: {_eq: $userId}, purpose: {_eq: $purpose}, created_at: {_gt: $recentSince}}) {
+            aggregate {
+              count
+            }
+          }
+          today: user_tokens_aggregate(where: {user_id: {_eq: $userId}, purpose: {_eq: $purpose}, created_at: {_gt: $daySince}}) {
+            aggregate {
+              count
+            }
+          }
+        }
+        `, map[string]interface{}{
+		"userId":      userID,
+		"purpose":     tokenPurposeEmailVerification,
+		"recentSince": now.Add(-s.opts.VerificationResendInterval),
+		"daySince":    now.Add(-24 * time.Hour),
+	}, &response)
+	if err != nil {
+		return err
+	}
+
+	user := response.UsersByPk
+	if user == nil {
+		return ErrUserNotFound
+	}
+
+	if user.EmailVerifiedAt != nil {
+		return ErrEmailAlreadyVerified
+	}
+
+	if response.Recent.Aggregate.Count > 0 || response.Today.Aggregate.Count >= s.opts.VerificationDailyLimit {
+		return ErrVerificationRateLimited
+	}
+
+	return s.sendVerification(ctx, userID, user.Email)
+}
+
+// sendVerification emails a link confirming that the user owns the address
+func (s *AccountService) sendVerification(ctx context.Context, userID uuid.UUID, email string) error {
+	token, err := s.issueToken(userID, tokenPurposeEmailVerification, "", s.opts.VerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(ctx, mail.Message{
+		To:      email,
+		Subject: "Confirm your email address",
+		Body: fmt.Sprintf("Confirm your email address by opening this link within %s:\n%s\n\n"+
+			"If you didn't create an account, you can ignore this email.",
+			s.opts.VerificationTTL, s.link("/verify-email", token)),
+	})
+}
+
+// ChangeEmail sends a confirmation link to the new address. The account keeps
+// its current address until the link is opened.
+func (s *AccountService) ChangeEmail(ctx context.Context, userID uuid.UUID, password, newEmail string) error {
+	currentEmail, err := s.checkPassword(userID, password)
+	if err != nil {
+		return err
+	}
+
+	if newEmail == currentEmail {
+		return ErrEmailUnchanged
+	}
+
+	now := time.Now().UTC()
+
+	var response struct {
+		Users []struct {
+			ID uuid.UUID `json:"id"`
+		} `json:"users"`
+		Recent struct {
+			Aggregate struct {
+				Count int `json:"count"`
+			} `json:"aggregate"`
+		} `json:"recent"`
+		Today struct {
+			Aggregate struct {
+				Count int `json:"count"`
+			} `json:"aggregate"`
+		} `json:"today"`
+	}
+
+	err = s.hasura.execute(`
+        query ($email: String!, $userId: uuid!, $purpose: String!, $recentSince: timestamptz!, $daySince: timestamptz!) {
+          users(where: {email: {_eq: $email}}, limit: 1) {
+            id
+          }
+          recent: user_tokens_aggregate(where: {user_id: {_eq: $userId}, purpose: {_eq: $purpose}, created_at: {_gt: $recentSince}}) {
+            aggregate {
+              count
+            }
+          }
+          today: user_tokens_aggregate(where: {user_id: {_eq: $userId}, purpose: {_eq: $purpose}, created_at: {_gt: $daySince}}) {
+            aggregate {
+              count
+            }
+          }
+        }
+        `, map[string]interface{}{
+		"email":       newEmail,
+		"userId":      userID,
+		"purpose":     tokenPurposeEmailChange,
+		"recentSince": now.Add(-s.opts.VerificationResendInterval),
+		"daySince":    now.Add(-24 * time.Hour),
+	}, &response)
+	if err != nil {
+		return err
+	}
+
+	if len(response.Users) > 0 {
+		return ErrUserAlreadyExists
+	}
+
+	if response.Recent.Aggregate.Count > 0 || response.Today.Aggregate.Count >= s.opts.VerificationDailyLimit {
+		return ErrVerificationRateLimited
+	}
+
+	token, err := s.issueToken(userID, tokenPurposeEmailChange, newEmail, s.opts.VerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	err = s.mailer.Send(ctx, mail.Message{
+		To:      newEmail,
+		Subject: "Confirm your new email address",
+		Body: fmt.Sprintf("Confirm that you want to use this address for your account by opening this link within %s:\n%s\n\n"+
+			"If you didn't ask for this, you can ignore this email.",
+			s.opts.VerificationTTL, s.link("/verify-email", token)),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Tell the current address so a hijacked session cannot move the
+	// account away unnoticed
+	return s.mailer.Send(ctx, mail.Message{
+		To:      currentEmail,
+		Subject: "Your email address is being changed",
+		Body: fmt.Sprintf("Someone asked to change the email address of your account to %s.\n\n"+
+			"If this wasn't you, change your password now.", newEmail),
+	})
+}
+
+// applyEmailChange switches the account to a confirmed new address
+func (s *AccountService) applyEmailChange(userID uuid.UUID, email string) error {
+	var existing struct {
+		Users []struct {
+			ID uuid.UUID `json:"id"`
+		} `json:"users"`
+	}
+
+	err := s.hasura.execute(`
+        query ($email: String!) {
+          users(where: {email: {_eq: $email}}, limit: 1) {
+            id
+          }
+        }
+        `, map[string]interface{}{"email": email}, &existing)
+	if err != nil {
+		return err
+	}
+
+	// The address may have been registered since the link was sent
+	if len(existing.Users) > 0 && existing.Users[0].ID != userID {
+		return ErrUserAlreadyExists
+	}
+
+	var response struct {
+		UpdateUsersByPk *struct {
+			ID uuid.UUID `json:"id"`
+		} `json:"update_users_by_pk"`
+		UpdateUserTokens struct {
+			AffectedRows int `json:"affected_rows"`
+		} `json:"update_user_tokens"`
+	}
+
+	// Links to other pending addresses are void once one is confirmed
+	now := time.Now().UTC()
+	err = s.hasura.execute(`
+        mutation ($userId: uuid!, $email: String!, $purpose: String!, $now: timestamptz!) {
+          update_users_by_pk(pk_columns: {id: $userId}, _set: {email: $email, email_verified_at: $now}) {
+            id
+          }
+          update_user_tokens(where: {user_id: {_eq: $userId}, purpose: {_eq: $purpose}, used_at: {_is_null: true}}, _set: {used_at: $now}) {
+            affected_rows
+          }
+        }
+        `, map[string]interface{}{
+		"userId":  userID,
+		"email":   email,
+		"purpose": tokenPurposeEmailChange,
+		"now":     now,
+	}, &response)
+	if err != nil {
+		return err
+	}
+
+	if response.UpdateUsersByPk == nil {
+		return ErrInvalidAccountToken
+	}
+
+	return nil
+}
+
+// DeleteAccount schedules the account for deletion after the grace period
+// and signs the user out everywhere. Signing in again before then cancels it.
+func (s *AccountService) DeleteAccount(ctx context.Context, userID uuid.UUID, password string) (time.Time, error) {
+	email, err := s.checkPassword(userID, password)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	scheduledAt := time.Now().Add(s.opts.DeletionGracePeriod).UTC()
+
+	var response struct {
+		UpdateUsersByPk *struct {
+			ID uuid.UUID `json:"id"`
+		} `json:"update_users_by_pk"`
+	}
+
+	err = s.hasura.execute(`
+        mutation ($userId: uuid!, $scheduledAt: timestamptz!, $status: String!) {
+          update_users_by_pk(pk_columns: {id: $userId}, _set: {deletion_scheduled_at: $scheduledAt, status: $status}) {
+            id
+          }
+        }
+        `, map[string]interface{}{"userId": userID, "scheduledAt": scheduledAt, "status": model.UserStatusPendingDeletion}, &response)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if response.UpdateUsersByPk == nil {
+		return time.Time{}, ErrUserNotFound
+	}
+
+	if err := s.revocations.RevokeUser(userID); err != nil {
+		return time.Time{}, err
+	}
+
+	// The deletion stands even if the notice cannot be sent
+	_ = s.mailer.Send(ctx, mail.Message{
+		To:      email,
+		Subject: "Your account will be deleted",
+		Body: fmt.Sprintf("Your account and all of its data will be deleted on %s.\n\n"+
+			"To keep your account, sign in again before then.",
+			scheduledAt.Format(time.RFC1123)),
+	})
+
+	return scheduledAt, nil
+}
+
+// CancelDeletion keeps an account that was scheduled for deletion. A
+// suspended account stays suspended.
+func (s *AccountService) CancelDeletion(userID uuid.UUID) error {
+	var response struct {
+		UpdateUsers struct {
+			AffectedRows int `json:"affected_rows"`
+		} `json:"update_users"`
+	}
+
+	return s.hasura.execute(`
+        mutation ($userId: uuid!, $pending: String!, $active: String!) {
+          update_users(where: {id: {_eq: $userId}, deletion_scheduled_at: {_is_null: false}, status: {_eq: $pending}}, _set: {deletion_scheduled_at: null, status: $active}) {
+            affected_rows
+          }
+        }
+        `, map[string]interface{}{
+		"userId":  userID,
+		"pending": model.UserStatusPendingDeletion,
+		"active":  model.UserStatusActive,
+	}, &response)
+}
+
+// PurgeDeletedAccounts removes accounts whose grace period has passed
+func (s *AccountService) PurgeDeletedAccounts() (int, error) {
+	var response struct {
+		DeleteUsers struct {
+			AffectedRows int `json:"affected_rows"`
+		} `json:"delete_users"`
+	}
+
+	err := s.hasura.execute(`
+        mutation ($now: timestamptz!) {
+          delete_users(where: {deletion_scheduled_at: {_lte: $now}}) {
+            affected_rows
+          }
+        }
+        `, map[string]interface{}{"now": time.Now().UTC()}, &response)
+	if err != nil {
+		return 0, err
+	}
+
+	return response.DeleteUsers.AffectedRows, nil
+}
+
+// checkPassword confirms the user's password and returns their email
+func (s *AccountService) checkPassword(userID uuid.UUID, password string) (string, error) {
+	var response struct {
+		UsersByPk *struct {
+			Email        string `json:"email"`
+			PasswordHash string `json:"password_hash"`
+		} `json:"users_by_pk"`
+	}
+
+	err := s.hasura.execute(`
+        query ($userId: uuid!) {
+          users_by_pk(id: $userId) {
+            email
+            password_hash
+          }
+        }
+        `, map[string]interface{}{"userId": userID}, &response)
+	if err != nil {
+		return "", err
+	}
+
+	if response.UsersByPk == nil {
+		return "", ErrUserNotFound
+	}
+
+	if !s.verifyPassword(userID, password, response.UsersByPk.PasswordHash) {
+		return "", ErrIncorrectPassword
+	}
+
+	return response.UsersByPk.Email, nil
+}
+
+// checkPasswordPolicy returns a *PasswordPolicyError when a new password for
+// the account with the given email breaks the policy
+func (s *AccountService) checkPasswordPolicy(newPassword, email string) error {
+	violations, err := s.opts.PasswordPolicy.Check(newPassword, email)
+	if err != nil {
+		return err
+	}
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// hashPassword hashes a new password with the current algorithm
+func (s *AccountService) hashPassword(password string) (string, error) {
+	return s.opts.PasswordHasher.Hash(password)
+}
+
+// verifyPassword reports whether password matches the user's stored hash.
+// A match against an outdated hash upgrades it; failing to is not an error,
+// since the next sign-in tries again.
+func (s *AccountService) verifyPassword(userID uuid.UUID, password, hash string) bool {
+	ok, rehash := s.opts.PasswordHasher.Verify(password, hash)
+	if ok && rehash {
+		_ = s.rehashPassword(userID, password, hash)
+	}
+	return ok
+}
+
+// rehashPassword replaces an outdated hash, unless the password changed
+// since it was read
+func (s *AccountService) rehashPassword(userID uuid.UUID, password, oldHash string) error {
+	newHash, err := s.hashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	var response struct {
+		UpdateUsers struct {
+			AffectedRows int `json:"affected_rows"`
+		} `json:"update_users"`
+	}
+
+	return s.hasura.execute(`
+        mutation ($userId: uuid!, $oldHash: String!, $newHash: String!) {
+          update_users(where: {id: {_eq: $userId}, password_hash: {_eq: $oldHash}}, _set: {password_hash: $newHash}) {
+            affected_rows
+          }
+        }
+        `, map[string]interface{}{
+		"userId":  userID,
+		"oldHash": oldHash,
+		"newHash": newHash,
+	}, &response)
+}
+
+// setPassword stores a new password. Outstanding reset links die with the
+// old password.
+func (s *AccountService) setPassword(userID uuid.UUID, password string) error {
+	hashedPassword, err := s.hashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	var response struct {
+		UpdateUsersByPk *struct {
+			ID uuid.UUID `json:"id"`
+		} `json:"update_users_by_pk"`
+		UpdateUserTokens struct {
+			AffectedRows int `json:"affected_rows"`
+		} `json:"update_user_tokens"`
+	}
+
+	err = s.hasura.execute(`
+        mutation ($userId: uuid!, $passwordHash: String!, $purpose: String!, $now: timestamptz!) {
+          update_users_by_pk(pk_columns: {id: $userId}, _set: {password_hash: $passwordHash}) {
+            id
+          }
+          update_user_tokens(where: {user_id: {_eq: $userId}, purpose: {_eq: $purpose}, used_at: {_is_null: true}}, _set: {used_at: $now}) {
+            affected_rows
+          }
+        }
+        `, map[string]interface{}{
+		"userId":       userID,
+		"passwordHash": hashedPassword,
+		"purpose":      tokenPurposePasswordReset,
+		"now":          time.Now().UTC(),
+	}, &response)
+	if err != nil {
+		return err
+	}
+
+	if response.UpdateUsersByPk == nil {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// issueToken stores the hash of a new single-use token and returns the token.
+// email is only set for email changes.
+func (s *AccountService) issueToken(userID uuid.UUID, purpose, email string, ttl time.Duration) (string, error) {
+	token, err := auth.GenerateOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+
+	object := map[string]interface{}{
+		"user_id":    userID,
+		"purpose":    purpose,
+		"token_hash": auth.HashOpaqueToken(token),
+		"expires_at": time.Now().Add(ttl).UTC(),
+	}
+	if email != "" {
+		object["email"] = email
+	}
+
+	var response struct {
+		InsertUserTokensOne struct {
+			ID uuid.UUID `json:"id"`
+		} `json:"insert_user_tokens_one"`
+	}
+
+	err = s.hasura.execute(`
+        mutation ($object: user_tokens_insert_input!) {
+          insert_user_tokens_one(object: $object) {
+            id
+          }
+        }
+        `, map[string]interface{}{"object": object}, &response)
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// tokenEmail returns the email of the account a live token belongs to,
+// without using the token up
+func (s *AccountService) tokenEmail(token, purpose string) (string, error) {
+	var response struct {
+		UserTokens []struct {
+			User struct {
+				Email string `json:"email"`
+			} `json:"user"`
+		} `json:"user_tokens"`
+	}
+
+	err := s.hasura.execute(`
+        query ($tokenHash: String!, $purpose: String!, $now: timestamptz!) {
+          user_tokens(where: {token_hash: {_eq: $tokenHash}, purpose: {_eq: $purpose}, used_at: {_is_null: true}, expires_at: {_gt: $now}}, limit: 1) {
+            user {
+              email
+            }
+          }
+        }
+        `, map[string]interface{}{
+		"tokenHash": auth.HashOpaqueToken(token),
+		"purpose":   purpose,
+		"now":       time.Now().UTC(),
+	}, &response)
+	if err != nil {
+		return "", err
+	}
+
+	if len(response.UserTokens) == 0 {
+		return "", ErrInvalidAccountToken
+	}
+
+	return response.UserTokens[0].User.Email, nil
+}
+
+// consumeToken marks a live token with one of the given purposes as used and
+// returns it. A token can only be consumed once, even by concurrent requests.
+func (s *AccountService) consumeToken(token string, purposes ...string) (*userToken, error) {
+	now := time.Now().UTC()
+
+	var response struct {
+		UpdateUserTokens struct {
+			Returning []userToken `json:"returning"`
+		} `json:"update_user_tokens"`
+	}
+
+	err := s.hasura.execute(`
+        mutation ($tokenHash: String!, $purposes: [String!]!, $now: timestamptz!) {
+          update_user_tokens(
+            where: {token_hash: {_eq: $tokenHash}, purpose: {_in: $purposes}, used_at: {_is_null: true}, expires_at: {_gt: $now}},
+            _set: {used_at: $now}
+          ) {
+            returning {
+              user_id
+              purpose
+              email
+            }
+          }
+        }
+        `, map[string]interface{}{
+		"tokenHash": auth.HashOpaqueToken(token),
+		"purposes":  purposes,
+		"now":       now,
+	}, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(response.UpdateUserTokens.Returning) == 0 {
+		return nil, ErrInvalidAccountToken
+	}
+
+	return &response.UpdateUserTokens.Returning[0], nil
+}
+
+// link builds a frontend URL carrying a token
+func (s *AccountService) link(path, token string) string {
+	return s.opts.AppURL + path + "?token=" + url.QueryEscape(token)
+}
@@ -0,0 +1,353 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+	"todo-app/backend/internal/auth"
+	"todo-app/backend/internal/mail"
+	"todo-app/backend/internal/password"
+
+	"github.com/google/uuid"
+)
+
+// recordingMailer keeps sent messages in memory
+type recordingMailer struct {
+	sent []mail.Message
+}
+
+func (m *recordingMailer) Send(ctx context.Context, msg mail.Message) error {
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+// testPasswordHasher uses the cheapest Argon2id parameters to keep tests
+// fast
+var testPasswordHasher = auth.NewPasswordHasher(auth.Argon2Params{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32})
+
+func newTestAccountService(client *HasuraClient, mailer mail.Mailer) *AccountService {
+	revocations := NewRevocationService(client, RevocationOptions{CacheSize: 10, CacheTTL: time.Minute})
+	return NewAccountService(client, mailer, revocations, AccountOptions{
+		AppURL:                     "http://app.test",
+		PasswordResetTTL:           time.Hour,
+		ForgotPasswordDuration:     20 * time.Millisecond,
+		VerificationTTL:            48 * time.Hour,
+		VerificationResendInterval: time.Minute,
+		VerificationDailyLimit:     5,
+		DeletionGracePeriod:        30 * 24 * time.Hour,
+		PasswordHasher:             testPasswordHasher,
+	})
+}
+
+// passwordState is the users_by_pk response checkPassword reads
+func passwordState(t *testing.T, email, password string) string {
+	t.Helper()
+
+	hash, err := testPasswordHasher.Hash(password)
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+	return fmt.Sprintf(`{"data":{"users_by_pk":{"email":%q,"password_hash":%q}}}`, email, hash)
+}
+
+func TestAccountService_ForgotPassword(t *testing.T) {
+	t.Run("unknown email", func(t *testing.T) {
+		client, shutdown := newMockHasuraClient(t, []mockResponse{
+			{body: `{"data":{"users":[]}}`},
+		})
+		defer shutdown()
+
+		mailer := &recordingMailer{}
+		service := newTestAccountService(client, mailer)
+
+		start := time.Now()
+		if err := service.ForgotPassword(context.Background(), "nobody@example.com"); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+			t.Fatalf("expected the request to be padded, took %v", elapsed)
+		}
+		if len(mailer.sent) != 0 {
+			t.Fatalf("expected no mail, got %d", len(mailer.sent))
+		}
+	})
+
+	t.Run("registered email", func(t *testing.T) {
+		client, shutdown := newMockHasuraClient(t, []mockResponse{
+			{body: fmt.Sprintf(`{"data":{"users":[{"id":"%s"}]}}`, uuid.New())},
+			{body: fmt.Sprintf(`{"data":{"insert_user_tokens_one":{"id":"%s"}}}`, uuid.New())},
+		})
+		defer shutdown()
+
+		mailer := &recordingMailer{}
+		service := newTestAccountService(client, mailer)
+
+		if err := service.ForgotPassword(context.Background(), "alice@example.com"); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if len(mailer.sent) != 1 {
+			t.Fatalf("expected one mail, got %d", len(mailer.sent))
+		}
+		if msg := mailer.sent[0]; msg.To != "alice@example.com" || !strings.Contains(msg.Body, "http://app.test/reset-password?token=") {
+			t.Fatalf("unexpected message: %+v", msg)
+		}
+	})
+}
+
+func TestAccountService_ResetPassword(t *testing.T) {
+	userID := uuid.New()
+
+	t.Run("used or expired token", func(t *testing.T) {
+		client, shutdown := newMockHasuraClient(t, []mockResponse{
+			{body: `{"data":{"update_user_tokens":{"returning":[]}}}`},
+		})
+		defer shutdown()
+
+		service := newTestAccountService(client, &recordingMailer{})
+		err := service.ResetPassword("stale", "new-password")
+		if !errors.Is(err, ErrInvalidAccountToken) {
+			t.Fatalf("expected ErrInvalidAccountToken, got %v", err)
+		}
+	})
+
+	t.Run("valid token", func(t *testing.T) {
+		client, shutdown := newMockHasuraClient(t, []mockResponse{
+			{body: fmt.Sprintf(`{"data":{"update_user_tokens":{"returning":[{"user_id":"%s"}]}}}`, userID)},
+			{body: fmt.Sprintf(`{"data":{"update_users_by_pk":{"id":"%s"},"update_user_tokens":{"affected_rows":0}}}`, userID)},
+			{body: fmt.Sprintf(`{"data":{"update_users_by_pk":{"id":"%s"},"update_refresh_tokens":{"affected_rows":2}}}`, userID)},
+		})
+		defer shutdown()
+
+		service := newTestAccountService(client, &recordingMailer{})
+		if err := service.ResetPassword("fresh", "new-password"); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	})
+}
+
+func TestAccountService_PasswordPolicy(t *testing.T) {
+	newPolicyService := func(client *HasuraClient) *AccountService {
+		service := newTestAccountService(client, &recordingMailer{})
+		service.opts.PasswordPolicy = password.Policy{MinLength: 8, MinScore: 2, DisallowEmail: true}
+		return service
+	}
+
+	t.Run("rejected resets keep the link", func(t *testing.T) {
+		// Only the lookup is made; the token is not consumed
+		client, shutdown := newMockHasuraClient(t, []mockResponse{
+			{body: `{"data":{"user_tokens":[{"user":{"email":"alice@example.com"}}]}}`},
+		})
+		defer shutdown()
+
+		var policy *PasswordPolicyError
+		err := newPolicyService(client).ResetPassword("fresh", "Alice123")
+		if !errors.As(err, &policy) {
+			t.Fatalf("expected a PasswordPolicyError, got %v", err)
+		}
+		if len(policy.Violations) != 2 || policy.Violations[0].Rule != password.RuleContainsEmail {
+			t.Fatalf("expected the email and strength rules, got %+v", policy.Violations)
+		}
+	})
+
+	t.Run("changes are checked after the current password", func(t *testing.T) {
+		client, shutdown := newMockHasuraClient(t, []mockResponse{
+			{body: passwordState(t, "alice@example.com", "old-password")},
+		})
+		defer shutdown()
+
+		err := newPolicyService(client).ChangePassword(uuid.New(), uuid.New(), "old-password", "qwerty")
+		if !errors.Is(err, ErrPasswordPolicy) {
+			t.Fatalf("expected ErrPasswordPolicy, got %v", err)
+		}
+	})
+}
+
+func TestAccountService_ResendVerification(t *testing.T) {
+	userID := uuid.New()
+
+	resendState := func(verifiedAt string, recent, today int) string {
+		return fmt.Sprintf(`{"data":{"users_by_pk":{"email":"alice@example.com","email_verified_at":%s},"recent":{"aggregate":{"count":%d}},"today":{"aggregate":{"count":%d}}}}`, verifiedAt, recent, today)
+	}
+
+	tests := []struct {
+		name    string
+		body    string
+		wantErr error
+	}{
+		{"already verified", resendState(`"2026-01-01T00:00:00Z"`, 0, 0), ErrEmailAlreadyVerified},
+		{"within resend interval", resendState("null", 1, 1), ErrVerificationRateLimited},
+		{"daily limit reached", resendState("null", 0, 5), ErrVerificationRateLimited},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, shutdown := newMockHasuraClient(t, []mockResponse{{body: tt.body}})
+			defer shutdown()
+
+			mailer := &recordingMailer{}
+			service := newTestAccountService(client, mailer)
+
+			err := service.ResendVerification(context.Background(), userID)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+			if len(mailer.sent) != 0 {
+				t.Fatalf("expected no mail, got %d", len(mailer.sent))
+			}
+		})
+	}
+
+	t.Run("sends a new link", func(t *testing.T) {
+		client, shutdown := newMockHasuraClient(t, []mockResponse{
+			{body: resendState("null", 0, 2)},
+			{body: fmt.Sprintf(`{"data":{"insert_user_tokens_one":{"id":"%s"}}}`, uuid.New())},
+		})
+		defer shutdown()
+
+		mailer := &recordingMailer{}
+		service := newTestAccountService(client, mailer)
+
+		if err := service.ResendVerification(context.Background(), userID); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(mailer.sent) != 1 || !strings.Contains(mailer.sent[0].Body, "http://app.test/verify-email?token=") {
+			t.Fatalf("unexpected mail: %+v", mailer.sent)
+		}
+	})
+}
+
+func TestAccountService_ChangePassword(t *testing.T) {
+	userID := uuid.New()
+	sessionID := uuid.New()
+
+	t.Run("incorrect current password", func(t *testing.T) {
+		client, shutdown := newMockHasuraClient(t, []mockResponse{
+			{body: passwordState(t, "alice@example.com", "old-password")},
+		})
+		defer shutdown()
+
+		service := newTestAccountService(client, &recordingMailer{})
+		err := service.ChangePassword(userID, sessionID, "wrong-password", "new-password")
+		if !errors.Is(err, ErrIncorrectPassword) {
+			t.Fatalf("expected ErrIncorrectPassword, got %v", err)
+		}
+	})
+
+	t.Run("ends other sessions", func(t *testing.T) {
+		otherSessionID := uuid.New()
+		client, shutdown := newMockHasuraClient(t, []mockResponse{
+			{body: passwordState(t, "alice@example.com", "old-password")},
+			{body: fmt.Sprintf(`{"data":{"update_users_by_pk":{"id":"%s"},"update_user_tokens":{"affected_rows":0}}}`, userID)},
+			{body: fmt.Sprintf(`{"data":{"update_sessions":{"returning":[{"id":"%s"}]},"update_refresh_tokens":{"affected_rows":1}}}`, otherSessionID)},
+		})
+		defer shutdown()
+
+		service := newTestAccountService(client, &recordingMailer{})
+		if err := service.ChangePassword(userID, sessionID, "old-password", "new-password"); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		// The ended session is answered from the cache without another request
+		revoked, err := service.revocations.IsRevoked(&auth.Claims{UserID: userID, SessionID: otherSessionID})
+		if err != nil || !revoked {
+			t.Fatalf("expected the other session to be revoked, got %v, %v", revoked, err)
+		}
+	})
+}
+
+func TestAccountService_ChangeEmail(t *testing.T) {
+	userID := uuid.New()
+
+	changeState := func(taken bool) string {
+		users := "[]"
+		if taken {
+			users = fmt.Sprintf(`[{"id":"%s"}]`, uuid.New())
+		}
+		return fmt.Sprintf(`{"data":{"users":%s,"recent":{"aggregate":{"count":0}},"today":{"aggregate":{"count":0}}}}`, users)
+	}
+
+	t.Run("address in use", func(t *testing.T) {
+		client, shutdown := newMockHasuraClient(t, []mockResponse{
+			{body: passwordState(t, "alice@example.com", "password")},
+			{body: changeState(true)},
+		})
+		defer shutdown()
+
+		service := newTestAccountService(client, &recordingMailer{})
+		err := service.ChangeEmail(context.Background(), userID, "password", "bob@example.com")
+		if !errors.Is(err, ErrUserAlreadyExists) {
+			t.Fatalf("expected ErrUserAlreadyExists, got %v", err)
+		}
+	})
+
+	t.Run("sends confirmation and notice", func(t *testing.T) {
+		client, shutdown := newMockHasuraClient(t, []mockResponse{
+			{body: passwordState(t, "alice@example.com", "password")},
+			{body: changeState(false)},
+			{body: fmt.Sprintf(`{"data":{"insert_user_tokens_one":{"id":"%s"}}}`, uuid.New())},
+		})
+		defer shutdown()
+
+		mailer := &recordingMailer{}
+		service := newTestAccountService(client, mailer)
+
+		if err := service.ChangeEmail(context.Background(), userID, "password", "alice@new.example.com"); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if len(mailer.sent) != 2 {
+			t.Fatalf("expected two mails, got %d", len(mailer.sent))
+		}
+		if msg := mailer.sent[0]; msg.To != "alice@new.example.com" || !strings.Contains(msg.Body, "http://app.test/verify-email?token=") {
+			t.Fatalf("unexpected confirmation: %+v", msg)
+		}
+		if msg := mailer.sent[1]; msg.To != "alice@example.com" || strings.Contains(msg.Body, "token=") {
+			t.Fatalf("unexpected notice: %+v", msg)
+		}
+	})
+
+	t.Run("confirmed link switches the address", func(t *testing.T) {
+		client, shutdown := newMockHasuraClient(t, []mockResponse{
+			{body: fmt.Sprintf(`{"data":{"update_user_tokens":{"returning":[{"user_id":"%s","purpose":"email_change","email":"alice@new.example.com"}]}}}`, userID)},
+			{body: `{"data":{"users":[]}}`},
+			{body: fmt.Sprintf(`{"data":{"update_users_by_pk":{"id":"%s"},"update_user_tokens":{"affected_rows":0}}}`, userID)},
+		})
+		defer shutdown()
+
+		service := newTestAccountService(client, &recordingMailer{})
+		if err := service.VerifyEmail("fresh"); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	})
+}
+
+func TestAccountService_DeleteAccount(t *testing.T) {
+	userID := uuid.New()
+
+	client, shutdown := newMockHasuraClient(t, []mockResponse{
+		{body: passwordState(t, "alice@example.com", "password")},
+		{body: fmt.Sprintf(`{"data":{"update_users_by_pk":{"id":"%s"}}}`, userID)},
+		{body: fmt.Sprintf(`{"data":{"update_users_by_pk":{"id":"%s"},"update_refresh_tokens":{"affected_rows":1}}}`, userID)},
+	})
+	defer shutdown()
+
+	mailer := &recordingMailer{}
+	service := newTestAccountService(client, mailer)
+
+	scheduledAt, err := service.DeleteAccount(context.Background(), userID, "password")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if until := time.Until(scheduledAt); until < 29*24*time.Hour {
+		t.Fatalf("expected deletion after the grace period, got %v", scheduledAt)
+	}
+	if len(mailer.sent) != 1 || mailer.sent[0].To != "alice@example.com" {
+		t.Fatalf("unexpected mail: %+v", mailer.sent)
+	}
+}
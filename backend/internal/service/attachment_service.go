@@ -0,0 +1,415 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"todo-app/backend/internal/model"
+	"todo-app/backend/internal/storage"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrAttachmentNotFound   = errors.New("attachment not found")
+	ErrAttachmentTooLarge   = errors.New("attachment exceeds the size limit")
+	ErrUnsupportedMediaType = errors.New("unsupported attachment type")
+	ErrInvalidDownloadLink  = errors.New("invalid or expired download link")
+)
+
+// allowedAttachmentTypes lists the sniffed MIME types accepted for upload
+var allowedAttachmentTypes = map[string]bool{
+	"application/pdf": true,
+	"image/png":       true,
+	"image/jpeg":      true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"text/plain":      true,
+}
+
+type AttachmentOptions struct {
+	MaxBytes  int64
+	URLSecret string
+	URLTTL    time.Duration
+}
+
+type AttachmentService struct {
+	hasura *HasuraClient
+	todos  *TodoService
+	blobs  storage.BlobStore
+	opts   AttachmentOptions
+}
+
+func NewAttachmentService(hasura *HasuraClient, todos *TodoService, blobs storage.BlobStore, opts AttachmentOptions) *AttachmentService {
+	return &AttachmentService{
+		hasura: hasura,
+		todos:  todos,
+		blobs:  blobs,
+		opts:   opts,
+	}
+}
+
+// MaxBytes returns the largest accepted attachment size
+func (s *AttachmentService) MaxBytes() int64 {
+	return s.opts.MaxBytes
+}
+
+// ListAttachments retrieves the attachments on a todo the user can view
+func (s *AttachmentService) ListAttachments(userID, todoID uuid.UUID) ([]model.Attachment, error) {
+	if err := s.requirePermission(userID, todoID, model.PermissionViewer); err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		Attachments []model.Attachment `json:"attachments"`
+	}
+
+	err := s.hasura.execute(`
+        query ($todoId: uuid!) {
+          attachments(where: {todo_id: {_eq: $todoId}}, order_by: {created_at: asc}) {
+            id
+            todo_id
+            uploader_id
+            filename
+            content_type
+            size_bytes
+            created_at
+          }
+        }
+        `, map[string]interface{}{"todoId": todoID}, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range response.Attachments {
+		response.Attachments[i].DownloadURL = s.signedURL(response.Attachments[i].ID)
+	}
+
+	return response.Attachments, nil
+}
+
+// GetAttachment retrieves a single attachment with a fresh download link
+func (s *AttachmentService) GetAttachment(userID, todoID, attachmentID uuid.UUID) (*model.Attachment, error) {
+	if err := s.requirePermission(userID, todoID, model.PermissionViewer); err != nil {
+		return nil, err
+	}
+
+	attachment, err := s.getAttachment(attachmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	if attachment.TodoID == nil || *attachment.TodoID != todoID {
+		return nil, ErrAttachmentNotFound
+	}
+
+	attachment.DownloadURL = s.signedURL(attachment.ID)
+	return attachment, nil
+}
+
+// Upload streams r into the blob store and records it as an attachment.
+// The content type is sniffed from the data rather than trusted from the client.
+func (s *AttachmentService) Upload(ctx context.Context, userID, todoID uuid.UUID, filename string, r io.Reader) (*model.Attachment, error) {
+	if err := s.requirePermission(userID, todoID, model.PermissionEditor); err != nil {
+		return nil, err
+	}
+
+	// Spool to disk so the size is known before anything reaches the store
+	spool, err := os.CreateTemp("", "attachment-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(spool.Name())
+	defer spool.Close()
+
+	size, err := io.Copy(spool, io.LimitReader(r, s.opts.MaxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if size > s.opts.MaxBytes {
+		return nil, ErrAttachmentTooLarge
+	}
+
+	head := make([]byte, 512)
+	n, err := spool.ReadAt(head, 0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	contentType := strings.TrimSpace(strings.Split(http.DetectContentType(head[:n]), ";")[0])
+	if !allowedAttachmentTypes[contentType] {
+		return nil, ErrUnsupportedMediaType
+	}
+
+	if _, err := spool.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	attachmentID := uuid.New()
+	key := fmt.Sprintf("todos/%s/%s", todoID, attachmentID)
+	if err := s.blobs.Put(ctx, key, spool, size, contentType); err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		InsertAttachmentsOne model.Attachment `json:"insert_attachments_one"`
+	}
+
+	err = s.hasura.execute(`
+        mutation ($id: uuid!, $todoId: uuid!, $uploaderId: uuid!, $filename: String!, $contentType: String!, $size: bigint!, $key: String!) {
+          insert_attachments_one(object: {id: $id, todo_id: $todoId, uploader_id: $uploaderId, filename: $filename, content_type: $contentType, size_bytes: $size, storage_key: $key}) {
+            id
+            todo_id
+            uploader_id
+            filename
+            content_type
+            size_bytes
+            created_at
+          }
+        }
+        `, map[string]interface{}{
+		"id":          attachmentID,
+		"todoId":      todoID,
+		"uploaderId":  userID,
+		"filename":    cleanFilename(filename),
+		"contentType": contentType,
+		"size":        size,
+		"key":         key,
+	}, &response)
+	if err != nil {
+		// PurgeOrphans won't find a blob without a row, so say where it is
+		if deleteErr := s.blobs.Delete(ctx, key); deleteErr != nil {
+			log.Printf("Failed to delete attachment blob %s after its row was not inserted: %v", key, deleteErr)
+		}
+		return nil, err
+	}
+
+	attachment := response.InsertAttachmentsOne
+	attachment.DownloadURL = s.signedURL(attachment.ID)
+	return &attachment, nil
+}
+
+// DeleteAttachment removes an attachment and its blob. The attachment is
+// detached from its todo first, so a blob that fails to delete is left for
+// PurgeOrphans rather than losing track of it.
+func (s *AttachmentService) DeleteAttachment(ctx context.Context, userID, todoID, attachmentID uuid.UUID) error {
+	if err := s.requirePermission(userID, todoID, model.PermissionEditor); err != nil {
+		return err
+	}
+
+	var response struct {
+		UpdateAttachments struct {
+			Returning []orphanAttachment `json:"returning"`
+		} `json:"update_attachments"`
+	}
+
+	err := s.hasura.execute(`
+        mutation ($id: uuid!, $todoId: uuid!) {
+          update_attachments(where: {id: {_eq: $id}, todo_id: {_eq: $todoId}}, _set: {todo_id: null}) {
+            returning {
+              id
+              storage_key
+            }
+          }
+        }
+        `, map[string]interface{}{"id": attachmentID, "todoId": todoID}, &response)
+	if err != nil {
+		return err
+	}
+
+	if len(response.UpdateAttachments.Returning) == 0 {
+		return ErrAttachmentNotFound
+	}
+
+	_, err = s.purge(ctx, response.UpdateAttachments.Returning)
+	return err
+}
+
+// Open verifies a signed download link and opens the attachment's blob
+func (s *AttachmentService) Open(ctx context.Context, attachmentID uuid.UUID, expires, signature string) (*model.Attachment, io.ReadCloser, error) {
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > expiresAt {
+		return nil, nil, ErrInvalidDownloadLink
+	}
+
+	expected := s.sign(attachmentID, expiresAt)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return nil, nil, ErrInvalidDownloadLink
+	}
+
+	attachment, err := s.getAttachment(attachmentID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if attachment.TodoID == nil {
+		return nil, nil, ErrAttachmentNotFound
+	}
+
+	blob, err := s.blobs.Get(ctx, attachment.StorageKey)
+	if errors.Is(err, storage.ErrBlobNotFound) {
+		return nil, nil, ErrAttachmentNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return attachment, blob, nil
+}
+
+// orphanBatchSize is how many orphaned attachments PurgeOrphans reads at a
+// time
+const orphanBatchSize = 100
+
+type orphanAttachment struct {
+	ID         uuid.UUID `json:"id"`
+	StorageKey string    `json:"storage_key"`
+}
+
+// PurgeOrphans removes blobs whose todo has been deleted and then the
+// attachment rows themselves. Attachments whose blob fails to delete are
+// skipped until the next run. It returns the number of attachments purged.
+func (s *AttachmentService) PurgeOrphans(ctx context.Context) (int, error) {
+	total := 0
+	after := uuid.Nil
+	for {
+		var response struct {
+			Attachments []orphanAttachment `json:"attachments"`
+		}
+
+		err := s.hasura.execute(`
+            query ($after: uuid!, $limit: Int!) {
+              attachments(where: {todo_id: {_is_null: true}, id: {_gt: $after}}, order_by: {id: asc}, limit: $limit) {
+                id
+                storage_key
+              }
+            }
+            `, map[string]interface{}{"after": after, "limit": orphanBatchSize}, &response)
+		if err != nil {
+			return total, err
+		}
+
+		purged, err := s.purge(ctx, response.Attachments)
+		total += purged
+		if err != nil {
+			return total, err
+		}
+
+		if len(response.Attachments) < orphanBatchSize {
+			return total, nil
+		}
+		after = response.Attachments[len(response.Attachments)-1].ID
+	}
+}
+
+// purge deletes the blobs of detached attachments and then the rows of
+// those whose blob is gone
+func (s *AttachmentService) purge(ctx context.Context, attachments []orphanAttachment) (int, error) {
+	var purged []uuid.UUID
+	for _, attachment := range attachments {
+		if err := s.blobs.Delete(ctx, attachment.StorageKey); err != nil {
+			log.Printf("Failed to delete attachment blob %s: %v", attachment.StorageKey, err)
+			continue
+		}
+		purged = append(purged, attachment.ID)
+	}
+
+	if len(purged) == 0 {
+		return 0, nil
+	}
+
+	err := s.hasura.execute(`
+        mutation ($ids: [uuid!]!) {
+          delete_attachments(where: {id: {_in: $ids}}) {
+            affected_rows
+          }
+        }
+        `, map[string]interface{}{"ids": purged}, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(purged), nil
+}
+
+func (s *AttachmentService) requirePermission(userID, todoID uuid.UUID, required string) error {
+	permission, err := s.todos.Permission(userID, todoID)
+	if err != nil {
+		return err
+	}
+
+	if !hasPermission(permission, required) {
+		return ErrTodoNotFound
+	}
+
+	return nil
+}
+
+func (s *AttachmentService) getAttachment(attachmentID uuid.UUID) (*model.Attachment, error) {
+	var response struct {
+		AttachmentsByPk *struct {
+			model.Attachment
+			StorageKey string `json:"storage_key"`
+		} `json:"attachments_by_pk"`
+	}
+
+	err := s.hasura.execute(`
+        query ($id: uuid!) {
+          attachments_by_pk(id: $id) {
+            id
+            todo_id
+            uploader_id
+            filename
+            content_type
+            size_bytes
+            storage_key
+            created_at
+          }
+        }
+        `, map[string]interface{}{"id": attachmentID}, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.AttachmentsByPk == nil {
+		return nil, ErrAttachmentNotFound
+	}
+
+	attachment := response.AttachmentsByPk.Attachment
+	attachment.StorageKey = response.AttachmentsByPk.StorageKey
+	return &attachment, nil
+}
+
+func (s *AttachmentService) signedURL(attachmentID uuid.UUID) string {
+	expires := time.Now().Add(s.opts.URLTTL).Unix()
+	return fmt.Sprintf("/api/attachments/%s/download?expires=%d&signature=%s", attachmentID, expires, s.sign(attachmentID, expires))
+}
+
+func (s *AttachmentService) sign(attachmentID uuid.UUID, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(s.opts.URLSecret))
+	fmt.Fprintf(mac, "%s:%d", attachmentID, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// cleanFilename keeps only the base name of a client-supplied filename
+func cleanFilename(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	if name == "." || name == "/" || name == "" {
+		return "attachment"
+	}
+	if len(name) > 255 {
+		name = name[:255]
+	}
+	return name
+}
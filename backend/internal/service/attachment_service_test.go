@@ -0,0 +1,154 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"todo-app/backend/internal/storage"
+)
+
+func newTestAttachmentService(t *testing.T, client *HasuraClient) *AttachmentService {
+	t.Helper()
+
+	blobs, err := storage.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalStore returned error: %v", err)
+	}
+
+	return NewAttachmentService(client, NewTodoService(client), blobs, AttachmentOptions{
+		MaxBytes:  1024,
+		URLSecret: "test-secret",
+		URLTTL:    time.Minute,
+	})
+}
+
+func TestAttachmentService_Upload(t *testing.T) {
+	userID := uuid.New()
+	todoID := uuid.New()
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC).Format(time.RFC3339)
+	png := append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 32)...)
+
+	t.Run("stores sniffed image", func(t *testing.T) {
+		client, shutdown := newMockHasuraClient(t, []mockResponse{
+			{body: fmt.Sprintf(`{"data":{"todos_by_pk":{"user_id":"%s"}}}`, userID)},
+			{
+				body: fmt.Sprintf(`{"data":{"insert_attachments_one":{"id":"%s","todo_id":"%s","uploader_id":"%s","filename":"shot.png","content_type":"image/png","size_bytes":%d,"created_at":"%s"}}}`, uuid.New(), todoID, userID, len(png), now),
+			},
+		})
+		defer shutdown()
+
+		service := newTestAttachmentService(t, client)
+		attachment, err := service.Upload(context.Background(), userID, todoID, "../../shot.png", bytes.NewReader(png))
+		if err != nil {
+			t.Fatalf("Upload returned error: %v", err)
+		}
+
+		if attachment.ContentType != "image/png" || attachment.DownloadURL == "" {
+			t.Fatalf("unexpected attachment: %+v", attachment)
+		}
+	})
+
+	t.Run("rejects unsupported type", func(t *testing.T) {
+		client, shutdown := newMockHasuraClient(t, []mockResponse{
+			{body: fmt.Sprintf(`{"data":{"todos_by_pk":{"user_id":"%s"}}}`, userID)},
+		})
+		defer shutdown()
+
+		service := newTestAttachmentService(t, client)
+		_, err := service.Upload(context.Background(), userID, todoID, "page.html", bytes.NewReader([]byte("<html><body>x</body></html>")))
+		if !errors.Is(err, ErrUnsupportedMediaType) {
+			t.Fatalf("expected ErrUnsupportedMediaType, got %v", err)
+		}
+	})
+
+	t.Run("rejects oversized file", func(t *testing.T) {
+		client, shutdown := newMockHasuraClient(t, []mockResponse{
+			{body: fmt.Sprintf(`{"data":{"todos_by_pk":{"user_id":"%s"}}}`, userID)},
+		})
+		defer shutdown()
+
+		service := newTestAttachmentService(t, client)
+		_, err := service.Upload(context.Background(), userID, todoID, "big.png", bytes.NewReader(make([]byte, 2048)))
+		if !errors.Is(err, ErrAttachmentTooLarge) {
+			t.Fatalf("expected ErrAttachmentTooLarge, got %v", err)
+		}
+	})
+}
+
+func TestAttachmentService_Open(t *testing.T) {
+	attachmentID := uuid.New()
+	service := newTestAttachmentService(t, nil)
+
+	t.Run("tampered signature", func(t *testing.T) {
+		expires := strconv.FormatInt(time.Now().Add(time.Minute).Unix(), 10)
+		_, _, err := service.Open(context.Background(), attachmentID, expires, "deadbeef")
+		if !errors.Is(err, ErrInvalidDownloadLink) {
+			t.Fatalf("expected ErrInvalidDownloadLink, got %v", err)
+		}
+	})
+
+	t.Run("expired link", func(t *testing.T) {
+		expiresAt := time.Now().Add(-time.Minute).Unix()
+		_, _, err := service.Open(context.Background(), attachmentID, strconv.FormatInt(expiresAt, 10), service.sign(attachmentID, expiresAt))
+		if !errors.Is(err, ErrInvalidDownloadLink) {
+			t.Fatalf("expected ErrInvalidDownloadLink, got %v", err)
+		}
+	})
+}
+
+func TestAttachmentService_PurgeOrphans(t *testing.T) {
+	// A full page whose first blob cannot be deleted, then a short page
+	rows := make([]string, orphanBatchSize)
+	ids := make([]uuid.UUID, orphanBatchSize)
+	for i := range rows {
+		ids[i] = uuid.New()
+		key := "orphan-" + strconv.Itoa(i)
+		if i == 0 {
+			key = "../outside"
+		}
+		rows[i] = fmt.Sprintf(`{"id":"%s","storage_key":%q}`, ids[i], key)
+	}
+	lastID := uuid.New()
+
+	var after interface{}
+	var deleted [][]interface{}
+	deleteRows := func(vars map[string]interface{}) string {
+		deleted = append(deleted, vars["ids"].([]interface{}))
+		return `{"data":{"delete_attachments":{"affected_rows":1}}}`
+	}
+	client, shutdown := newMockHasuraClient(t, []mockResponse{
+		{body: `{"data":{"attachments":[` + strings.Join(rows, ",") + `]}}`},
+		{respond: deleteRows},
+		{respond: func(vars map[string]interface{}) string {
+			after = vars["after"]
+			return fmt.Sprintf(`{"data":{"attachments":[{"id":"%s","storage_key":"orphan-last"}]}}`, lastID)
+		}},
+		{respond: deleteRows},
+	})
+	defer shutdown()
+
+	purged, err := newTestAttachmentService(t, client).PurgeOrphans(context.Background())
+	if err != nil {
+		t.Fatalf("PurgeOrphans returned error: %v", err)
+	}
+
+	if purged != orphanBatchSize {
+		t.Fatalf("expected %d attachments purged, got %d", orphanBatchSize, purged)
+	}
+	if after != ids[orphanBatchSize-1].String() {
+		t.Fatalf("expected the next page after the last row, got %v", after)
+	}
+	for _, id := range deleted[0] {
+		if id == ids[0].String() {
+			t.Fatalf("expected the attachment whose blob failed to delete to be kept")
+		}
+	}
+}
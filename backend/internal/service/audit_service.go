@@ -0,0 +1,166 @@
+package service
+
+import (
+	"time"
+	"todo-app/backend/internal/auth"
+	"todo-app/backend/internal/model"
+
+	"github.com/google/uuid"
+)
+
+// defaultAuditLimit and maxAuditLimit bound audit log listings
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+// AuditService keeps the audit log: what administrators did to users, and
+// everything done while impersonating one
+type AuditService struct {
+	hasura *HasuraClient
+}
+
+func NewAuditService(hasura *HasuraClient) *AuditService {
+	return &AuditService{hasura: hasura}
+}
+
+// Record appends an entry to the audit log
+func (s *AuditService) Record(entry model.AuditLog) error {
+	details := entry.Details
+	if details == nil {
+		details = map[string]interface{}{}
+	}
+
+	var response struct {
+		InsertAuditLogsOne struct {
+			ID uuid.UUID `json:"id"`
+		} `json:"insert_audit_logs_one"`
+	}
+
+	return s.hasura.execute(`
+        mutation ($object: audit_logs_insert_input!) {
+          insert_audit_logs_one(object: $object) {
+            id
+          }
+        }
+        `, map[string]interface{}{
+		"object": map[string]interface{}{
+			"action":      entry.Action,
+			"actor_id":    entry.ActorID,
+			"actor_email": entry.ActorEmail,
+			"user_id":     entry.UserID,
+			"method":      entry.Method,
+			"path":        entry.Path,
+			"ip_address":  entry.IPAddress,
+			"user_agent":  entry.UserAgent,
+			"details":     details,
+		},
+	}, &response)
+}
+
+// RecordImpersonatedRequest logs a request made with an impersonation token
+func (s *AuditService) RecordImpersonatedRequest(claims *auth.Claims, method, path string, client model.ClientInfo) error {
+	actorID := claims.Act.Subject
+	return s.Record(model.AuditLog{
+		Action:     model.AuditActionImpersonatedRequest,
+		ActorID:    &actorID,
+		ActorEmail: claims.Act.Email,
+		UserID:     claims.UserID,
+		Method:     method,
+		Path:       path,
+		IPAddress:  client.IPAddress,
+		UserAgent:  client.UserAgent,
+	})
+}
+
+// List returns the newest audit log entries matching the filter
+func (s *AuditService) List(filter model.AuditFilter) ([]model.AuditLog, error) {
+	where := map[string]interface{}{}
+	if filter.UserID != nil {
+		where["user_id"] = map[string]interface{}{"_eq": *filter.UserID}
+	}
+	if filter.ActorID != nil {
+		where["actor_id"] = map[string]interface{}{"_eq": *filter.ActorID}
+	}
+	if filter.Action != "" {
+		where["action"] = map[string]interface{}{"_eq": filter.Action}
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultAuditLimit
+	}
+	if limit > maxAuditLimit {
+		limit = maxAuditLimit
+	}
+
+	var response struct {
+		AuditLogs []model.AuditLog `json:"audit_logs"`
+	}
+
+	err := s.hasura.execute(`
+        query ($where: audit_logs_bool_exp!, $limit: Int!) {
+          audit_logs(where: $where, order_by: {created_at: desc}, limit: $limit) {
+            id
+            action
+            actor_id
+            actor_email
+            user_id
+            method
+            path
+            ip_address
+            user_agent
+            details
+            created_at
+          }
+        }
+        `, map[string]interface{}{"where": where, "limit": limit}, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	return response.AuditLogs, nil
+}
+
+// Impersonations lists the times an administrator signed in as the user,
+// newest first
+func (s *AuditService) Impersonations(userID uuid.UUID) ([]model.Impersonation, error) {
+	var response struct {
+		AuditLogs []struct {
+			ActorEmail string `json:"actor_email"`
+			Details    struct {
+				Reason    string    `json:"reason"`
+				ExpiresAt time.Time `json:"expires_at"`
+			} `json:"details"`
+			CreatedAt time.Time `json:"created_at"`
+		} `json:"audit_logs"`
+	}
+
+	err := s.hasura.execute(`
+        query ($userId: uuid!, $action: String!, $limit: Int!) {
+          audit_logs(where: {user_id: {_eq: $userId}, action: {_eq: $action}}, order_by: {created_at: desc}, limit: $limit) {
+            actor_email
+            details
+            created_at
+          }
+        }
+        `, map[string]interface{}{
+		"userId": userID,
+		"action": model.AuditActionImpersonationStart,
+		"limit":  defaultAuditLimit,
+	}, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	impersonations := make([]model.Impersonation, 0, len(response.AuditLogs))
+	for _, entry := range response.AuditLogs {
+		impersonations = append(impersonations, model.Impersonation{
+			ActorEmail: entry.ActorEmail,
+			Reason:     entry.Details.Reason,
+			StartedAt:  entry.CreatedAt,
+			ExpiresAt:  entry.Details.ExpiresAt,
+		})
+	}
+	return impersonations, nil
+}
@@ -1,34 +1,68 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"time"
 	"todo-app/backend/internal/auth"
 	"todo-app/backend/internal/model"
+	"todo-app/backend/internal/webauthn"
 
 	"github.com/google/uuid"
 )
 
 var (
-	ErrUserAlreadyExists  = errors.New("user already exists")
-	ErrInvalidCredentials = errors.New("invalid email or password")
-	ErrUserNotFound       = errors.New("user not found")
+	ErrUserAlreadyExists   = errors.New("user already exists")
+	ErrInvalidCredentials  = errors.New("invalid email or password")
+	ErrUserNotFound        = errors.New("user not found")
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reused")
+	ErrUserSuspended       = errors.New("account suspended")
 )
 
+type AuthOptions struct {
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+	// MFARequiredRoles are the roles that may only use content after a
+	// second factor; their tokens carry no Hasura claims until then
+	MFARequiredRoles []string
+}
+
 type AuthService struct {
-	hasura    *HasuraClient
-	jwtSecret string
+	hasura      *HasuraClient
+	keys        *auth.Keyring
+	revocations *RevocationService
+	sessions    *SessionService
+	accounts    *AccountService
+	mfa         *MFAService
+	webauthn    *WebAuthnService
+	oidc        *OIDCService
+	lockouts    *LockoutService
+	opts        AuthOptions
 }
 
-func NewAuthService(hasura *HasuraClient, jwtSecret string) *AuthService {
+func NewAuthService(hasura *HasuraClient, keys *auth.Keyring, revocations *RevocationService, sessions *SessionService, accounts *AccountService, mfa *MFAService, webauthn *WebAuthnService, oidc *OIDCService, lockouts *LockoutService, opts AuthOptions) *AuthService {
 	return &AuthService{
-		hasura:    hasura,
-		jwtSecret: jwtSecret,
+		hasura:      hasura,
+		keys:        keys,
+		revocations: revocations,
+		sessions:    sessions,
+		accounts:    accounts,
+		mfa:         mfa,
+		webauthn:    webauthn,
+		oidc:        oidc,
+		lockouts:    lockouts,
+		opts:        opts,
 	}
 }
 
-// Register creates a new user account
-func (s *AuthService) Register(email, password string) (*model.LoginResponse, error) {
+// Register creates a new user account and emails a verification link. The
+// password must meet the account service's policy.
+func (s *AuthService) Register(ctx context.Context, email, password string, client model.ClientInfo) (*model.LoginResponse, error) {
+	if err := s.accounts.checkPasswordPolicy(password, email); err != nil {
+		return nil, err
+	}
+
 	// Check if user already exists
 	var existsResp struct {
 		Users []struct {
@@ -51,7 +85,7 @@ func (s *AuthService) Register(email, password string) (*model.LoginResponse, er
 	}
 
 	// Hash password
-	hashedPassword, err := auth.HashPassword(password)
+	hashedPassword, err := s.accounts.hashPassword(password)
 	if err != nil {
 		return nil, err
 	}
@@ -66,6 +100,8 @@ func (s *AuthService) Register(email, password string) (*model.LoginResponse, er
             id
             email
             role
+            email_verified_at
+            status
             created_at
             updated_at
           }
@@ -75,29 +111,34 @@ func (s *AuthService) Register(email, password string) (*model.LoginResponse, er
 		return nil, err
 	}
 
-	// Generate token
-	token, err := auth.GenerateToken(userResp.InsertUsersOne.ID, userResp.InsertUsersOne.Email, userResp.InsertUsersOne.Role, s.jwtSecret)
-	if err != nil {
-		return nil, err
-	}
+	// A lost verification email is recoverable through a resend, so it
+	// must not fail the registration
+	_ = s.accounts.sendVerification(ctx, userResp.InsertUsersOne.ID, userResp.InsertUsersOne.Email)
 
-	return &model.LoginResponse{
-		Token: token,
-		User:  userResp.InsertUsersOne,
-	}, nil
+	return s.startSession(userResp.InsertUsersOne, client, false)
 }
 
-// Login authenticates a user and returns a token
-func (s *AuthService) Login(email, password string) (*model.LoginResponse, error) {
+// Login authenticates a user and returns a token. Users with a second factor
+// get an MFA challenge to complete with VerifyMFA instead of tokens. Too many
+// failures lock the account or the client address out with a
+// *LockedOutError.
+func (s *AuthService) Login(email, password string, client model.ClientInfo) (*model.LoginResponse, *model.MFAChallenge, error) {
+	if err := s.lockouts.Check(email, client.IPAddress); err != nil {
+		return nil, nil, err
+	}
+
 	// Get user
 	var response struct {
 		Users []struct {
-			ID           uuid.UUID `json:"id"`
-			Email        string    `json:"email"`
-			PasswordHash string    `json:"password_hash"`
-			Role         string    `json:"role"`
-			CreatedAt    time.Time `json:"created_at"`
-			UpdatedAt    time.Time `json:"updated_at"`
+			ID                  uuid.UUID  `json:"id"`
+			Email               string     `json:"email"`
+			PasswordHash        string     `json:"password_hash"`
+			Role                string     `json:"role"`
+			EmailVerifiedAt     *time.Time `json:"email_verified_at"`
+			Status              string     `json:"status"`
+			DeletionScheduledAt *time.Time `json:"deletion_scheduled_at"`
+			CreatedAt           time.Time  `json:"created_at"`
+			UpdatedAt           time.Time  `json:"updated_at"`
 		} `json:"users"`
 	}
 
@@ -108,43 +149,352 @@ func (s *AuthService) Login(email, password string) (*model.LoginResponse, error
             email
             password_hash
             role
+            email_verified_at
+            status
+            deletion_scheduled_at
             created_at
             updated_at
           }
         }
         `, map[string]interface{}{"email": email}, &response)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	if len(response.Users) == 0 {
-		return nil, ErrInvalidCredentials
+		return nil, nil, s.loginFailed(email, client)
 	}
 
 	userRecord := response.Users[0]
 
 	// Check password
-	if !auth.CheckPassword(password, userRecord.PasswordHash) {
-		return nil, ErrInvalidCredentials
+	if !s.accounts.verifyPassword(userRecord.ID, password, userRecord.PasswordHash) {
+		return nil, nil, s.loginFailed(email, client)
+	}
+
+	// Only the right password learns that the account is suspended
+	if userRecord.Status == model.UserStatusSuspended {
+		return nil, nil, ErrUserSuspended
+	}
+
+	mfaEnabled, err := s.mfa.IsEnabled(userRecord.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The account's failures are kept until the second factor is passed too,
+	// so wrong codes keep counting across challenges
+	if mfaEnabled {
+		challenge, err := s.mfa.Challenge(userRecord.ID)
+		return nil, challenge, err
+	}
+
+	if err := s.lockouts.RecordSuccess(email); err != nil {
+		return nil, nil, err
+	}
+
+	// Signing in during the grace period keeps the account
+	if userRecord.DeletionScheduledAt != nil {
+		if err := s.accounts.CancelDeletion(userRecord.ID); err != nil {
+			return nil, nil, err
+		}
 	}
 
-	// Generate token
 	user := model.User{
-		ID:        userRecord.ID,
-		Email:     userRecord.Email,
-		Role:      userRecord.Role,
-		CreatedAt: userRecord.CreatedAt,
-		UpdatedAt: userRecord.UpdatedAt,
+		ID:              userRecord.ID,
+		Email:           userRecord.Email,
+		Role:            userRecord.Role,
+		Status:          model.UserStatusActive,
+		EmailVerifiedAt: userRecord.EmailVerifiedAt,
+		CreatedAt:       userRecord.CreatedAt,
+		UpdatedAt:       userRecord.UpdatedAt,
 	}
 
-	token, err := auth.GenerateToken(user.ID, user.Email, user.Role, s.jwtSecret)
+	loginResponse, err := s.startSession(user, client, false)
+	return loginResponse, nil, err
+}
+
+// loginFailed counts a failed login and returns ErrInvalidCredentials
+func (s *AuthService) loginFailed(email string, client model.ClientInfo) error {
+	if err := s.lockouts.RecordFailure(email, client.IPAddress); err != nil {
+		return err
+	}
+	return ErrInvalidCredentials
+}
+
+// VerifyMFA completes a login with the second factor. Wrong codes count as
+// failed logins of the account and the client address, and lock them out
+// like wrong passwords do.
+func (s *AuthService) VerifyMFA(mfaToken, code string, client model.ClientInfo) (*model.LoginResponse, error) {
+	email, err := s.mfa.challengeEmail(mfaToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.lockouts.Check(email, client.IPAddress); err != nil {
+		return nil, err
+	}
+
+	userID, err := s.mfa.Verify(mfaToken, code)
+	if err != nil {
+		if errors.Is(err, ErrInvalidMFACode) {
+			if err := s.lockouts.RecordFailure(email, client.IPAddress); err != nil {
+				return nil, err
+			}
+		}
+		return nil, err
+	}
+
+	if err := s.lockouts.RecordSuccess(email); err != nil {
+		return nil, err
+	}
+
+	return s.completeLogin(userID, client, true)
+}
+
+// LoginWithPasskey signs in with a passkey assertion. Passkeys require user
+// verification, so the session counts as having passed MFA.
+func (s *AuthService) LoginWithPasskey(credential *webauthn.AssertionCredential, client model.ClientInfo) (*model.LoginResponse, error) {
+	userID, err := s.webauthn.FinishLogin(credential)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.completeLogin(userID, client, true)
+}
+
+// LoginWithOIDC completes a login at an identity provider. Users with
+// two-factor authentication get an MFA challenge unless the provider reports
+// a multi-factor login of its own.
+func (s *AuthService) LoginWithOIDC(ctx context.Context, code, state string, client model.ClientInfo) (*model.LoginResponse, *model.MFAChallenge, error) {
+	userID, mfaVerified, err := s.oidc.FinishLogin(ctx, code, state)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !mfaVerified {
+		mfaEnabled, err := s.mfa.IsEnabled(userID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if mfaEnabled {
+			challenge, err := s.mfa.Challenge(userID)
+			return nil, challenge, err
+		}
+	}
+
+	loginResponse, err := s.completeLogin(userID, client, mfaVerified)
+	return loginResponse, nil, err
+}
+
+// completeLogin starts a session for a user who signed in without the
+// password form
+func (s *AuthService) completeLogin(userID uuid.UUID, client model.ClientInfo, mfaVerified bool) (*model.LoginResponse, error) {
+	user, err := s.GetProfile(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.Status == model.UserStatusSuspended {
+		return nil, ErrUserSuspended
+	}
+
+	// Signing in during the grace period keeps the account
+	if err := s.accounts.CancelDeletion(userID); err != nil {
+		return nil, err
+	}
+	user.Status = model.UserStatusActive
+
+	return s.startSession(*user, client, mfaVerified)
+}
+
+// Refresh exchanges a refresh token for a new access token and a rotated
+// refresh token. Presenting a token that was already rotated or revoked
+// revokes every token in its family, since it means the token was copied.
+func (s *AuthService) Refresh(refreshToken string) (*model.LoginResponse, error) {
+	var response struct {
+		RefreshTokens []struct {
+			ID        uuid.UUID  `json:"id"`
+			FamilyID  uuid.UUID  `json:"family_id"`
+			ExpiresAt time.Time  `json:"expires_at"`
+			RevokedAt *time.Time `json:"revoked_at"`
+			User      model.User `json:"user"`
+			Session   struct {
+				MFAVerifiedAt *time.Time `json:"mfa_verified_at"`
+			} `json:"session"`
+		} `json:"refresh_tokens"`
+	}
+
+	err := s.hasura.execute(`
+        query ($tokenHash: String!) {
+          refresh_tokens(where: {token_hash: {_eq: $tokenHash}}, limit: 1) {
+            id
+            family_id
+            expires_at
+            revoked_at
+            user {
+              id
+              email
+              role
+              email_verified_at
+              status
+              created_at
+              updated_at
+            }
+            session {
+              mfa_verified_at
+            }
+          }
+        }
+        `, map[string]interface{}{"tokenHash": auth.HashOpaqueToken(refreshToken)}, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(response.RefreshTokens) == 0 {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	record := response.RefreshTokens[0]
+
+	if record.RevokedAt != nil {
+		if err := s.revocations.RevokeSession(record.FamilyID); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+
+	if time.Now().After(record.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	// Suspension revokes refresh tokens; this covers a suspension racing
+	// the rotation
+	if record.User.Status == model.UserStatusSuspended {
+		return nil, ErrUserSuspended
+	}
+
+	// Only one caller can win the rotation; a concurrent loser is treated
+	// like any other reuse.
+	var rotateResp struct {
+		UpdateRefreshTokens struct {
+			AffectedRows int `json:"affected_rows"`
+		} `json:"update_refresh_tokens"`
+	}
+
+	err = s.hasura.execute(`
+        mutation ($id: uuid!, $now: timestamptz!) {
+          update_refresh_tokens(where: {id: {_eq: $id}, revoked_at: {_is_null: true}}, _set: {revoked_at: $now}) {
+            affected_rows
+          }
+        }
+        `, map[string]interface{}{"id": record.ID, "now": time.Now().UTC()}, &rotateResp)
+	if err != nil {
+		return nil, err
+	}
+
+	if rotateResp.UpdateRefreshTokens.AffectedRows == 0 {
+		if err := s.revocations.RevokeSession(record.FamilyID); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+
+	return s.issueTokens(record.User, record.FamilyID, record.Session.MFAVerifiedAt != nil)
+}
+
+// Logout revokes the access token and the refresh tokens of its session
+func (s *AuthService) Logout(claims *auth.Claims) error {
+	if err := s.revocations.RevokeSession(claims.SessionID); err != nil {
+		return err
+	}
+	return s.revocations.RevokeToken(claims)
+}
+
+// LogoutAll revokes every access and refresh token issued to the user
+func (s *AuthService) LogoutAll(userID uuid.UUID) error {
+	return s.revocations.RevokeUser(userID)
+}
+
+// startSession records a new session and issues its first tokens
+func (s *AuthService) startSession(user model.User, client model.ClientInfo, mfaVerified bool) (*model.LoginResponse, error) {
+	sessionID, err := s.sessions.StartSession(user.ID, client, mfaVerified)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.issueTokens(user, sessionID, mfaVerified)
+}
+
+// mfaRequired reports whether the role must pass a second factor to use
+// content
+func (s *AuthService) mfaRequired(role string) bool {
+	for _, required := range s.opts.MFARequiredRoles {
+		if role == required {
+			return true
+		}
+	}
+	return false
+}
+
+// issueTokens mints an access token and a refresh token for a session. The
+// session id doubles as the refresh token family id.
+func (s *AuthService) issueTokens(user model.User, familyID uuid.UUID, mfaVerified bool) (*model.LoginResponse, error) {
+	claims := auth.Claims{
+		UserID:        user.ID,
+		SessionID:     familyID,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerifiedAt != nil,
+		Role:          user.Role,
+		MFA:           mfaVerified,
+	}
+
+	// Hasura cannot see the MFA requirement the backend enforces, so a
+	// token that does not meet it is not let through there at all
+	if mfaVerified || !s.mfaRequired(user.Role) {
+		claims.Hasura = auth.NewHasuraClaims(user.ID)
+	}
+
+	token, expiresAt, err := auth.GenerateToken(claims, s.keys, s.opts.AccessTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := auth.GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	refreshExpiresAt := time.Now().Add(s.opts.RefreshTokenTTL)
+
+	var response struct {
+		InsertRefreshTokensOne struct {
+			ID uuid.UUID `json:"id"`
+		} `json:"insert_refresh_tokens_one"`
+	}
+
+	err = s.hasura.execute(`
+        mutation ($userId: uuid!, $familyId: uuid!, $tokenHash: String!, $expiresAt: timestamptz!) {
+          insert_refresh_tokens_one(object: {user_id: $userId, family_id: $familyId, token_hash: $tokenHash, expires_at: $expiresAt}) {
+            id
+          }
+        }
+        `, map[string]interface{}{
+		"userId":    user.ID,
+		"familyId":  familyID,
+		"tokenHash": auth.HashOpaqueToken(refreshToken),
+		"expiresAt": refreshExpiresAt,
+	}, &response)
 	if err != nil {
 		return nil, err
 	}
 
 	return &model.LoginResponse{
-		Token: token,
-		User:  user,
+		Token:            token,
+		ExpiresAt:        expiresAt,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: refreshExpiresAt,
+		User:             user,
 	}, nil
 }
 
@@ -160,6 +510,8 @@ func (s *AuthService) GetProfile(userID uuid.UUID) (*model.User, error) {
             id
             email
             role
+            email_verified_at
+            status
             created_at
             updated_at
           }
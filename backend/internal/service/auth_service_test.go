@@ -0,0 +1,290 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+	"todo-app/backend/internal/auth"
+	"todo-app/backend/internal/model"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func newTestAuthService(t *testing.T, client *HasuraClient, opts AuthOptions) *AuthService {
+	t.Helper()
+	revocations := NewRevocationService(client, RevocationOptions{CacheSize: 10, CacheTTL: time.Minute})
+	sessions := NewSessionService(client, revocations, SessionOptions{TouchInterval: time.Minute, CacheSize: 10})
+	accounts := NewAccountService(client, &recordingMailer{}, revocations, AccountOptions{PasswordHasher: testPasswordHasher})
+	mfa := NewMFAService(client, accounts, MFAOptions{Issuer: "Todo App", ChallengeTTL: 5 * time.Minute, MaxAttempts: 5})
+	webauthn := NewWebAuthnService(client, accounts, testWebAuthnConfig(), WebAuthnOptions{ChallengeTTL: 5 * time.Minute})
+	oidc := NewOIDCService(client, nil, NewAuditService(client), OIDCOptions{StateTTL: 10 * time.Minute, PasswordHasher: testPasswordHasher})
+	key, err := auth.GenerateSigningKey(auth.AlgorithmEdDSA, time.Now().Add(-time.Minute))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	lockouts := NewLockoutService(NewMemoryLockoutStore(), testLockoutOptions)
+	return NewAuthService(client, auth.NewKeyring(key), revocations, sessions, accounts, mfa, webauthn, oidc, lockouts, opts)
+}
+
+func TestAuthService_Refresh(t *testing.T) {
+	userID := uuid.New()
+	familyID := uuid.New()
+	now := time.Now().UTC()
+	opts := AuthOptions{AccessTokenTTL: 15 * time.Minute, RefreshTokenTTL: time.Hour}
+
+	refreshRecord := func(expiresAt time.Time, revokedAt string) string {
+		return fmt.Sprintf(`{"data":{"refresh_tokens":[{"id":"%s","family_id":"%s","expires_at":"%s","revoked_at":%s,"user":{"id":"%s","email":"alice@example.com","role":"user","created_at":"%s","updated_at":"%s"}}]}}`,
+			uuid.New(), familyID, expiresAt.Format(time.RFC3339), revokedAt, userID, now.Format(time.RFC3339), now.Format(time.RFC3339))
+	}
+
+	t.Run("rotates the token", func(t *testing.T) {
+		client, shutdown := newMockHasuraClient(t, []mockResponse{
+			{body: refreshRecord(now.Add(time.Hour), "null")},
+			{body: `{"data":{"update_refresh_tokens":{"affected_rows":1}}}`},
+			{body: fmt.Sprintf(`{"data":{"insert_refresh_tokens_one":{"id":"%s"}}}`, uuid.New())},
+		})
+		defer shutdown()
+
+		service := newTestAuthService(t, client, opts)
+		response, err := service.Refresh("old-token")
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if response.RefreshToken == "" || response.RefreshToken == "old-token" {
+			t.Fatalf("expected a new refresh token, got %q", response.RefreshToken)
+		}
+		if response.User.ID != userID {
+			t.Fatalf("expected user %s, got %s", userID, response.User.ID)
+		}
+		if response.ExpiresAt.After(time.Now().Add(opts.AccessTokenTTL)) {
+			t.Fatalf("access token outlives its ttl: %v", response.ExpiresAt)
+		}
+	})
+
+	t.Run("reuse revokes the family", func(t *testing.T) {
+		client, shutdown := newMockHasuraClient(t, []mockResponse{
+			{body: refreshRecord(now.Add(time.Hour), fmt.Sprintf(`"%s"`, now.Format(time.RFC3339)))},
+			{body: `{"data":{"update_refresh_tokens":{"affected_rows":2}}}`},
+		})
+		defer shutdown()
+
+		service := newTestAuthService(t, client, opts)
+		_, err := service.Refresh("rotated-token")
+		if !errors.Is(err, ErrRefreshTokenReused) {
+			t.Fatalf("expected ErrRefreshTokenReused, got %v", err)
+		}
+	})
+
+	t.Run("lost rotation race counts as reuse", func(t *testing.T) {
+		client, shutdown := newMockHasuraClient(t, []mockResponse{
+			{body: refreshRecord(now.Add(time.Hour), "null")},
+			{body: `{"data":{"update_refresh_tokens":{"affected_rows":0}}}`},
+			{body: `{"data":{"update_refresh_tokens":{"affected_rows":1}}}`},
+		})
+		defer shutdown()
+
+		service := newTestAuthService(t, client, opts)
+		_, err := service.Refresh("raced-token")
+		if !errors.Is(err, ErrRefreshTokenReused) {
+			t.Fatalf("expected ErrRefreshTokenReused, got %v", err)
+		}
+	})
+
+	t.Run("expired token", func(t *testing.T) {
+		client, shutdown := newMockHasuraClient(t, []mockResponse{
+			{body: refreshRecord(now.Add(-time.Minute), "null")},
+		})
+		defer shutdown()
+
+		service := newTestAuthService(t, client, opts)
+		_, err := service.Refresh("expired-token")
+		if !errors.Is(err, ErrInvalidRefreshToken) {
+			t.Fatalf("expected ErrInvalidRefreshToken, got %v", err)
+		}
+	})
+
+	t.Run("unknown token", func(t *testing.T) {
+		client, shutdown := newMockHasuraClient(t, []mockResponse{
+			{body: `{"data":{"refresh_tokens":[]}}`},
+		})
+		defer shutdown()
+
+		service := newTestAuthService(t, client, opts)
+		_, err := service.Refresh("unknown-token")
+		if !errors.Is(err, ErrInvalidRefreshToken) {
+			t.Fatalf("expected ErrInvalidRefreshToken, got %v", err)
+		}
+	})
+}
+
+func TestAuthService_HasuraClaims(t *testing.T) {
+	userID := uuid.New()
+	now := time.Now().UTC().Format(time.RFC3339)
+
+	refresh := func(t *testing.T, role string, opts AuthOptions) *auth.Claims {
+		t.Helper()
+		client, shutdown := newMockHasuraClient(t, []mockResponse{
+			{body: fmt.Sprintf(`{"data":{"refresh_tokens":[{"id":"%s","family_id":"%s","expires_at":"%s","revoked_at":null,"user":{"id":"%s","email":"alice@example.com","role":"%s","created_at":"%s","updated_at":"%s"}}]}}`,
+				uuid.New(), uuid.New(), time.Now().Add(time.Hour).Format(time.RFC3339), userID, role, now, now)},
+			{body: `{"data":{"update_refresh_tokens":{"affected_rows":1}}}`},
+			{body: fmt.Sprintf(`{"data":{"insert_refresh_tokens_one":{"id":"%s"}}}`, uuid.New())},
+		})
+		defer shutdown()
+
+		service := newTestAuthService(t, client, opts)
+		response, err := service.Refresh("refresh-token")
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		claims, err := auth.ValidateToken(response.Token, service.keys)
+		if err != nil {
+			t.Fatalf("expected a valid token, got %v", err)
+		}
+		return claims
+	}
+
+	opts := AuthOptions{AccessTokenTTL: 15 * time.Minute, RefreshTokenTTL: time.Hour}
+
+	t.Run("user", func(t *testing.T) {
+		claims := refresh(t, "user", opts)
+		if claims.Hasura == nil || claims.Hasura.DefaultRole != auth.HasuraRoleUser || claims.Hasura.UserID != userID.String() || len(claims.Hasura.AllowedRoles) != 1 {
+			t.Fatalf("unexpected Hasura claims: %+v", claims.Hasura)
+		}
+	})
+
+	t.Run("admin is only a user to Hasura", func(t *testing.T) {
+		claims := refresh(t, "admin", opts)
+		if claims.Hasura == nil || claims.Hasura.DefaultRole != auth.HasuraRoleUser || len(claims.Hasura.AllowedRoles) != 1 {
+			t.Fatalf("unexpected Hasura claims: %+v", claims.Hasura)
+		}
+	})
+
+	t.Run("withheld until MFA", func(t *testing.T) {
+		opts := opts
+		opts.MFARequiredRoles = []string{"admin"}
+		if claims := refresh(t, "admin", opts); claims.Hasura != nil {
+			t.Fatalf("expected no Hasura claims without a second factor, got %+v", claims.Hasura)
+		}
+	})
+}
+
+func TestAuthService_Login(t *testing.T) {
+	userID := uuid.New()
+	opts := AuthOptions{AccessTokenTTL: 15 * time.Minute, RefreshTokenTTL: time.Hour}
+
+	hash, err := testPasswordHasher.Hash("password")
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+	userRecord := fmt.Sprintf(`{"data":{"users":[{"id":"%s","email":"alice@example.com","password_hash":%q,"role":"user","email_verified_at":null,"deletion_scheduled_at":null,"created_at":"2026-01-01T00:00:00Z","updated_at":"2026-01-01T00:00:00Z"}]}}`, userID, hash)
+
+	t.Run("second factor enabled", func(t *testing.T) {
+		client, shutdown := newMockHasuraClient(t, []mockResponse{
+			{body: userRecord},
+			{body: `{"data":{"user_mfa_by_pk":{"confirmed_at":"2026-01-01T00:00:00Z"}}}`},
+			{body: fmt.Sprintf(`{"data":{"insert_user_tokens_one":{"id":"%s"}}}`, uuid.New())},
+		})
+		defer shutdown()
+
+		response, challenge, err := newTestAuthService(t, client, opts).Login("alice@example.com", "password", model.ClientInfo{})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if response != nil || challenge == nil || challenge.MFAToken == "" {
+			t.Fatalf("expected only a challenge, got %+v, %+v", response, challenge)
+		}
+	})
+
+	t.Run("no second factor", func(t *testing.T) {
+		client, shutdown := newMockHasuraClient(t, []mockResponse{
+			{body: userRecord},
+			{body: `{"data":{"user_mfa_by_pk":null}}`},
+			{body: fmt.Sprintf(`{"data":{"insert_sessions_one":{"id":"%s"}}}`, uuid.New())},
+			{body: fmt.Sprintf(`{"data":{"insert_refresh_tokens_one":{"id":"%s"}}}`, uuid.New())},
+		})
+		defer shutdown()
+
+		response, challenge, err := newTestAuthService(t, client, opts).Login("alice@example.com", "password", model.ClientInfo{})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if response == nil || challenge != nil {
+			t.Fatalf("expected tokens, got %+v, %+v", response, challenge)
+		}
+	})
+	t.Run("upgrades an outdated hash", func(t *testing.T) {
+		legacyHash, err := auth.BcryptHasher{Cost: bcrypt.MinCost}.Hash("password")
+		if err != nil {
+			t.Fatalf("failed to hash password: %v", err)
+		}
+		legacyRecord := strings.Replace(userRecord, hash, legacyHash, 1)
+
+		var newHash string
+		client, shutdown := newMockHasuraClient(t, []mockResponse{
+			{body: legacyRecord},
+			{respond: func(vars map[string]interface{}) string {
+				if vars["oldHash"] != legacyHash {
+					t.Fatalf("expected the rehash to be conditional on the old hash, got %v", vars["oldHash"])
+				}
+				newHash, _ = vars["newHash"].(string)
+				return `{"data":{"update_users":{"affected_rows":1}}}`
+			}},
+			{body: `{"data":{"user_mfa_by_pk":null}}`},
+			{body: fmt.Sprintf(`{"data":{"insert_sessions_one":{"id":"%s"}}}`, uuid.New())},
+			{body: fmt.Sprintf(`{"data":{"insert_refresh_tokens_one":{"id":"%s"}}}`, uuid.New())},
+		})
+		defer shutdown()
+
+		if _, _, err := newTestAuthService(t, client, opts).Login("alice@example.com", "password", model.ClientInfo{}); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if ok, rehash := testPasswordHasher.Verify("password", newHash); !ok || rehash {
+			t.Fatalf("expected a current Argon2id hash, got %q", newHash)
+		}
+	})
+}
+
+func TestAuthService_VerifyMFA(t *testing.T) {
+	userID := uuid.New()
+	opts := AuthOptions{AccessTokenTTL: 15 * time.Minute, RefreshTokenTTL: time.Hour}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		t.Fatalf("failed to generate secret: %v", err)
+	}
+
+	challenge := mockResponse{body: `{"data":{"user_tokens":[{"user":{"email":"alice@example.com"}}]}}`}
+	wrongCode := []mockResponse{
+		challenge,
+		{body: fmt.Sprintf(`{"data":{"update_user_tokens":{"returning":[{"user_id":"%s"}]}}}`, userID)},
+		{body: fmt.Sprintf(`{"data":{"user_mfa_by_pk":{"secret":"%s","confirmed_at":"2026-01-01T00:00:00Z"}}}`, secret)},
+		{body: `{"data":{"update_mfa_recovery_codes":{"affected_rows":0}}}`},
+	}
+
+	// Wrong codes lock the account across challenges, as wrong passwords do
+	var responses []mockResponse
+	for i := 0; i < testLockoutOptions.AccountThreshold; i++ {
+		responses = append(responses, wrongCode...)
+	}
+	responses = append(responses, challenge)
+
+	client, shutdown := newMockHasuraClient(t, responses)
+	defer shutdown()
+
+	service := newTestAuthService(t, client, opts)
+	for i := 0; i < testLockoutOptions.AccountThreshold; i++ {
+		token := fmt.Sprintf("challenge-%d", i)
+		if _, err := service.VerifyMFA(token, "000000", model.ClientInfo{IPAddress: "192.0.2.1"}); !errors.Is(err, ErrInvalidMFACode) {
+			t.Fatalf("attempt %d: expected ErrInvalidMFACode, got %v", i, err)
+		}
+	}
+
+	var locked *LockedOutError
+	if _, err := service.VerifyMFA("challenge-next", "000000", model.ClientInfo{IPAddress: "192.0.2.1"}); !errors.As(err, &locked) {
+		t.Fatalf("expected a lockout, got %v", err)
+	}
+}
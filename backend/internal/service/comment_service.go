@@ -0,0 +1,341 @@
+package service
+
+import (
+	"errors"
+	"time"
+	"todo-app/backend/internal/markdown"
+	"todo-app/backend/internal/model"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrCommentNotFound  = errors.New("comment not found")
+	ErrCommentForbidden = errors.New("not allowed to modify this comment")
+	ErrEmptyComment     = errors.New("comment body is empty")
+)
+
+type CommentService struct {
+	hasura *HasuraClient
+	todos  *TodoService
+}
+
+func NewCommentService(hasura *HasuraClient, todos *TodoService) *CommentService {
+	return &CommentService{
+		hasura: hasura,
+		todos:  todos,
+	}
+}
+
+// ListComments retrieves the comments on a todo the user can view
+func (s *CommentService) ListComments(userID, todoID uuid.UUID) ([]model.Comment, error) {
+	if _, err := s.requirePermission(userID, todoID, model.PermissionViewer); err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		Comments []model.Comment `json:"comments"`
+	}
+
+	err := s.hasura.execute(`
+        query ($todoId: uuid!) {
+          comments(where: {todo_id: {_eq: $todoId}}, order_by: {created_at: asc}) {
+            id
+            todo_id
+            author_id
+            body
+            mentions {
+              user_id
+            }
+            edited_at
+            created_at
+            updated_at
+          }
+        }
+        `, map[string]interface{}{"todoId": todoID}, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	return response.Comments, nil
+}
+
+// CreateComment adds a comment to a todo and records its mentions
+func (s *CommentService) CreateComment(userID, todoID uuid.UUID, body string) (*model.Comment, error) {
+	if _, err := s.requirePermission(userID, todoID, model.PermissionViewer); err != nil {
+		return nil, err
+	}
+
+	body = markdown.Sanitize(body)
+	if body == "" {
+		return nil, ErrEmptyComment
+	}
+
+	mentions, err := s.resolveMentions(todoID, body)
+	if err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		InsertCommentsOne model.Comment `json:"insert_comments_one"`
+	}
+
+	err = s.hasura.execute(`
+        mutation ($todoId: uuid!, $authorId: uuid!, $body: String!, $mentions: [comment_mentions_insert_input!]!) {
+          insert_comments_one(object: {todo_id: $todoId, author_id: $authorId, body: $body, mentions: {data: $mentions}}) {
+            id
+            todo_id
+            author_id
+            body
+            mentions {
+              user_id
+            }
+            edited_at
+            created_at
+            updated_at
+          }
+        }
+        `, map[string]interface{}{
+		"todoId":   todoID,
+		"authorId": userID,
+		"body":     body,
+		"mentions": mentionObjects(mentions, nil),
+	}, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	return &response.InsertCommentsOne, nil
+}
+
+// UpdateComment edits a comment written by the user and re-parses its mentions
+func (s *CommentService) UpdateComment(userID, todoID, commentID uuid.UUID, body string) (*model.Comment, error) {
+	if _, err := s.requirePermission(userID, todoID, model.PermissionViewer); err != nil {
+		return nil, err
+	}
+
+	comment, err := s.getComment(todoID, commentID)
+	if err != nil {
+		return nil, err
+	}
+
+	if comment.AuthorID != userID {
+		return nil, ErrCommentForbidden
+	}
+
+	body = markdown.Sanitize(body)
+	if body == "" {
+		return nil, ErrEmptyComment
+	}
+
+	mentions, err := s.resolveMentions(todoID, body)
+	if err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		UpdateCommentsByPk *model.Comment `json:"update_comments_by_pk"`
+	}
+
+	// Mentions are replaced wholesale; Hasura runs the fields in one transaction
+	err = s.hasura.execute(`
+        mutation ($id: uuid!, $body: String!, $editedAt: timestamptz!, $mentions: [comment_mentions_insert_input!]!) {
+          delete_comment_mentions(where: {comment_id: {_eq: $id}}) {
+            affected_rows
+          }
+          insert_comment_mentions(objects: $mentions) {
+            affected_rows
+          }
+          update_comments_by_pk(pk_columns: {id: $id}, _set: {body: $body, edited_at: $editedAt, updated_at: $editedAt}) {
+            id
+            todo_id
+            author_id
+            body
+            mentions {
+              user_id
+            }
+            edited_at
+            created_at
+            updated_at
+          }
+        }
+        `, map[string]interface{}{
+		"id":       commentID,
+		"body":     body,
+		"editedAt": time.Now().UTC(),
+		"mentions": mentionObjects(mentions, &commentID),
+	}, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.UpdateCommentsByPk == nil {
+		return nil, ErrCommentNotFound
+	}
+
+	return response.UpdateCommentsByPk, nil
+}
+
+// DeleteComment removes a comment. Authors can delete their own comments and
+// todo owners can delete any comment on their todo.
+func (s *CommentService) DeleteComment(userID, todoID, commentID uuid.UUID) error {
+	permission, err := s.requirePermission(userID, todoID, model.PermissionViewer)
+	if err != nil {
+		return err
+	}
+
+	comment, err := s.getComment(todoID, commentID)
+	if err != nil {
+		return err
+	}
+
+	if comment.AuthorID != userID && permission != model.PermissionOwner {
+		return ErrCommentForbidden
+	}
+
+	var response struct {
+		DeleteCommentsByPk *struct {
+			ID uuid.UUID `json:"id"`
+		} `json:"delete_comments_by_pk"`
+	}
+
+	err = s.hasura.execute(`
+        mutation ($id: uuid!) {
+          delete_comments_by_pk(id: $id) {
+            id
+          }
+        }
+        `, map[string]interface{}{"id": commentID}, &response)
+	if err != nil {
+		return err
+	}
+
+	if response.DeleteCommentsByPk == nil {
+		return ErrCommentNotFound
+	}
+
+	return nil
+}
+
+func (s *CommentService) requirePermission(userID, todoID uuid.UUID, required string) (string, error) {
+	permission, err := s.todos.Permission(userID, todoID)
+	if err != nil {
+		return "", err
+	}
+
+	if !hasPermission(permission, required) {
+		return "", ErrTodoNotFound
+	}
+
+	return permission, nil
+}
+
+func (s *CommentService) getComment(todoID, commentID uuid.UUID) (*model.Comment, error) {
+	var response struct {
+		Comments []model.Comment `json:"comments"`
+	}
+
+	err := s.hasura.execute(`
+        query ($id: uuid!, $todoId: uuid!) {
+          comments(where: {id: {_eq: $id}, todo_id: {_eq: $todoId}}, limit: 1) {
+            id
+            todo_id
+            author_id
+          }
+        }
+        `, map[string]interface{}{"id": commentID, "todoId": todoID}, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(response.Comments) == 0 {
+		return nil, ErrCommentNotFound
+	}
+
+	return &response.Comments[0], nil
+}
+
+// resolveMentions maps the @email mentions in a body to the registered users
+// who can view the todo. Addresses must match as registered. Unknown
+// addresses, and users without access, are ignored.
+func (s *CommentService) resolveMentions(todoID uuid.UUID, body string) ([]uuid.UUID, error) {
+	emails := markdown.Mentions(body)
+	if len(emails) == 0 {
+		return nil, nil
+	}
+
+	var response struct {
+		Users []struct {
+			ID uuid.UUID `json:"id"`
+		} `json:"users"`
+		TodosByPk *struct {
+			UserID uuid.UUID `json:"user_id"`
+			Shares []struct {
+				UserID uuid.UUID `json:"user_id"`
+			} `json:"shares"`
+			Workspace *struct {
+				Members []struct {
+					UserID uuid.UUID `json:"user_id"`
+				} `json:"members"`
+			} `json:"workspace"`
+		} `json:"todos_by_pk"`
+	}
+
+	// Every share and workspace role can at least view the todo
+	err := s.hasura.execute(`
+        query ($emails: [String!]!, $todoId: uuid!) {
+          users(where: {email: {_in: $emails}}) {
+            id
+          }
+          todos_by_pk(id: $todoId) {
+            user_id
+            shares(where: {user: {email: {_in: $emails}}}) {
+              user_id
+            }
+            workspace {
+              members(where: {user: {email: {_in: $emails}}}) {
+                user_id
+              }
+            }
+          }
+        }
+        `, map[string]interface{}{"emails": emails, "todoId": todoID}, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.TodosByPk == nil {
+		return nil, nil
+	}
+
+	viewers := map[uuid.UUID]bool{response.TodosByPk.UserID: true}
+	for _, share := range response.TodosByPk.Shares {
+		viewers[share.UserID] = true
+	}
+	if response.TodosByPk.Workspace != nil {
+		for _, member := range response.TodosByPk.Workspace.Members {
+			viewers[member.UserID] = true
+		}
+	}
+
+	ids := make([]uuid.UUID, 0, len(response.Users))
+	for _, user := range response.Users {
+		if viewers[user.ID] {
+			ids = append(ids, user.ID)
+		}
+	}
+
+	return ids, nil
+}
+
+func mentionObjects(userIDs []uuid.UUID, commentID *uuid.UUID) []map[string]interface{} {
+	objects := make([]map[string]interface{}, 0, len(userIDs))
+	for _, id := range userIDs {
+		object := map[string]interface{}{"user_id": id}
+		if commentID != nil {
+			object["comment_id"] = *commentID
+		}
+		objects = append(objects, object)
+	}
+	return objects
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestCommentService_CreateComment(t *testing.T) {
+	userID := uuid.New()
+	todoID := uuid.New()
+	commentID := uuid.New()
+	mentionedID := uuid.New()
+	now := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC).Format(time.RFC3339)
+
+	t.Run("with mention", func(t *testing.T) {
+		client, shutdown := newMockHasuraClient(t, []mockResponse{
+			{body: fmt.Sprintf(`{"data":{"todos_by_pk":{"user_id":"%s"}}}`, userID)},
+			{body: fmt.Sprintf(`{"data":{"users":[{"id":"%s"}],"todos_by_pk":{"user_id":"%s","shares":[{"user_id":"%s"}],"workspace":null}}}`, mentionedID, userID, mentionedID)},
+			{
+				body: fmt.Sprintf(`{"data":{"insert_comments_one":{"id":"%s","todo_id":"%s","author_id":"%s","body":"ping @bob@example.com","mentions":[{"user_id":"%s"}],"edited_at":null,"created_at":"%s","updated_at":"%s"}}}`, commentID, todoID, userID, mentionedID, now, now),
+			},
+		})
+		defer shutdown()
+
+		service := NewCommentService(client, NewTodoService(client))
+		comment, err := service.CreateComment(userID, todoID, "ping @bob@example.com <script>x</script>")
+		if err != nil {
+			t.Fatalf("CreateComment returned error: %v", err)
+		}
+
+		if comment.ID != commentID || len(comment.Mentions) != 1 || comment.Mentions[0].UserID != mentionedID {
+			t.Fatalf("unexpected comment: %+v", comment)
+		}
+	})
+
+	t.Run("mentions as written, of viewers only", func(t *testing.T) {
+		outsiderID := uuid.New()
+		var emails []interface{}
+		var mentions []interface{}
+		client, shutdown := newMockHasuraClient(t, []mockResponse{
+			{body: fmt.Sprintf(`{"data":{"todos_by_pk":{"user_id":"%s"}}}`, userID)},
+			{respond: func(vars map[string]interface{}) string {
+				emails = vars["emails"].([]interface{})
+				return fmt.Sprintf(`{"data":{"users":[{"id":"%s"},{"id":"%s"}],"todos_by_pk":{"user_id":"%s","shares":[],"workspace":{"members":[{"user_id":"%s"}]}}}}`, mentionedID, outsiderID, userID, mentionedID)
+			}},
+			{respond: func(vars map[string]interface{}) string {
+				mentions = vars["mentions"].([]interface{})
+				return fmt.Sprintf(`{"data":{"insert_comments_one":{"id":"%s","todo_id":"%s","author_id":"%s","body":"ping","mentions":[{"user_id":"%s"}],"edited_at":null,"created_at":"%s","updated_at":"%s"}}}`, commentID, todoID, userID, mentionedID, now, now)
+			}},
+		})
+		defer shutdown()
+
+		service := NewCommentService(client, NewTodoService(client))
+		if _, err := service.CreateComment(userID, todoID, "ping @Bob@Example.com and @eve@example.com"); err != nil {
+			t.Fatalf("CreateComment returned error: %v", err)
+		}
+
+		if len(emails) != 2 || emails[0] != "Bob@Example.com" {
+			t.Fatalf("expected addresses as written, got %v", emails)
+		}
+		if len(mentions) != 1 || mentions[0].(map[string]interface{})["user_id"] != mentionedID.String() {
+			t.Fatalf("expected only the workspace member to be mentioned, got %v", mentions)
+		}
+	})
+
+	t.Run("todo of another user", func(t *testing.T) {
+		client, shutdown := newMockHasuraClient(t, []mockResponse{
+			{body: fmt.Sprintf(`{"data":{"todos_by_pk":{"user_id":"%s"}}}`, uuid.New())},
+		})
+		defer shutdown()
+
+		service := NewCommentService(client, NewTodoService(client))
+		_, err := service.CreateComment(userID, todoID, "hello")
+		if !errors.Is(err, ErrTodoNotFound) {
+			t.Fatalf("expected ErrTodoNotFound, got %v", err)
+		}
+	})
+
+	t.Run("empty after sanitizing", func(t *testing.T) {
+		client, shutdown := newMockHasuraClient(t, []mockResponse{
+			{body: fmt.Sprintf(`{"data":{"todos_by_pk":{"user_id":"%s"}}}`, userID)},
+		})
+		defer shutdown()
+
+		service := NewCommentService(client, NewTodoService(client))
+		_, err := service.CreateComment(userID, todoID, "<b></b>")
+		if !errors.Is(err, ErrEmptyComment) {
+			t.Fatalf("expected ErrEmptyComment, got %v", err)
+		}
+	})
+}
+
+func TestCommentService_UpdateComment(t *testing.T) {
+	userID := uuid.New()
+	todoID := uuid.New()
+	commentID := uuid.New()
+
+	t.Run("not the author", func(t *testing.T) {
+		client, shutdown := newMockHasuraClient(t, []mockResponse{
+			{body: fmt.Sprintf(`{"data":{"todos_by_pk":{"user_id":"%s"}}}`, userID)},
+			{body: fmt.Sprintf(`{"data":{"comments":[{"id":"%s","todo_id":"%s","author_id":"%s"}]}}`, commentID, todoID, uuid.New())},
+		})
+		defer shutdown()
+
+		service := NewCommentService(client, NewTodoService(client))
+		_, err := service.UpdateComment(userID, todoID, commentID, "edited")
+		if !errors.Is(err, ErrCommentForbidden) {
+			t.Fatalf("expected ErrCommentForbidden, got %v", err)
+		}
+	})
+}
@@ -5,12 +5,19 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"todo-app/backend/internal/auth"
 )
 
+// HasuraClient sends GraphQL requests to Hasura with the admin secret. With
+// token forwarding on, ForCaller gives a client that sends the caller's
+// access token instead, so Hasura enforces its own permissions as well.
 type HasuraClient struct {
-	endpoint    string
-	adminSecret string
-	httpClient  *http.Client
+	endpoint      string
+	adminSecret   string
+	httpClient    *http.Client
+	forwardTokens bool
+	// callerToken replaces the admin secret when set
+	callerToken string
 }
 
 type graphQLRequest struct {
@@ -35,6 +42,26 @@ func NewHasuraClient(endpoint, adminSecret string) *HasuraClient {
 	}
 }
 
+// EnableTokenForwarding makes ForCaller forward callers' tokens. Tokens must
+// carry Hasura claims and Hasura must trust the backend's JWKS.
+func (c *HasuraClient) EnableTokenForwarding() {
+	c.forwardTokens = true
+}
+
+// ForCaller returns a client that acts as the caller, whose access token is
+// given, in Hasura's "user" role. Requests then only see rows the metadata
+// permits that user, whatever the query asks for. Without forwarding, or
+// without a token (personal access tokens cannot be forwarded), it returns
+// the admin client.
+func (c *HasuraClient) ForCaller(token string) *HasuraClient {
+	if !c.forwardTokens || token == "" {
+		return c
+	}
+	caller := *c
+	caller.callerToken = token
+	return &caller
+}
+
 func (c *HasuraClient) execute(query string, variables map[string]interface{}, out interface{}) error {
 	payload, err := json.Marshal(graphQLRequest{Query: query, Variables: variables})
 	if err != nil {
@@ -47,7 +74,10 @@ func (c *HasuraClient) execute(query string, variables map[string]interface{}, o
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	if c.adminSecret != "" {
+	if c.callerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.callerToken)
+		req.Header.Set("x-hasura-role", auth.HasuraRoleUser)
+	} else if c.adminSecret != "" {
 		req.Header.Set("x-hasura-admin-secret", c.adminSecret)
 	}
 
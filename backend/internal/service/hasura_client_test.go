@@ -0,0 +1,55 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHasuraClient_ForCaller(t *testing.T) {
+	var headers http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header.Clone()
+		_, _ = w.Write([]byte(`{"data":{}}`))
+	}))
+	defer server.Close()
+
+	client := NewHasuraClient(server.URL, "admin-secret")
+
+	tests := []struct {
+		name       string
+		forward    bool
+		token      string
+		wantSecret string
+		wantAuth   string
+		wantRole   string
+	}{
+		{name: "forwarding off", token: "caller-token", wantSecret: "admin-secret"},
+		{name: "forwarding on", forward: true, token: "caller-token", wantAuth: "Bearer caller-token", wantRole: "user"},
+		{name: "no caller token", forward: true, wantSecret: "admin-secret"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client.forwardTokens = tt.forward
+			if err := client.ForCaller(tt.token).execute(`query { __typename }`, nil, nil); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			if got := headers.Get("x-hasura-admin-secret"); got != tt.wantSecret {
+				t.Fatalf("expected admin secret %q, got %q", tt.wantSecret, got)
+			}
+			if got := headers.Get("Authorization"); got != tt.wantAuth {
+				t.Fatalf("expected authorization %q, got %q", tt.wantAuth, got)
+			}
+			if got := headers.Get("x-hasura-role"); got != tt.wantRole {
+				t.Fatalf("expected role %q, got %q", tt.wantRole, got)
+			}
+		})
+	}
+
+	// The shared client keeps using the admin secret
+	if client.callerToken != "" {
+		t.Fatalf("expected ForCaller to leave the client unchanged")
+	}
+}
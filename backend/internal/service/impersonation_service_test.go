@@ -0,0 +1,107 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+	"todo-app/backend/internal/auth"
+	"todo-app/backend/internal/model"
+
+	"github.com/google/uuid"
+)
+
+func newTestImpersonationService(t *testing.T, client *HasuraClient) (*ImpersonationService, *auth.Keyring) {
+	t.Helper()
+	key, err := auth.GenerateSigningKey(auth.AlgorithmEdDSA, time.Now().Add(-time.Minute))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	keys := auth.NewKeyring(key)
+
+	revocations := NewRevocationService(client, RevocationOptions{CacheSize: 10, CacheTTL: time.Minute})
+	sessions := NewSessionService(client, revocations, SessionOptions{TouchInterval: time.Minute, CacheSize: 10})
+	roles := newTestRoleService(client)
+	audit := NewAuditService(client)
+	users := NewUserService(client, revocations, sessions, nil, roles, audit)
+	return NewImpersonationService(keys, users, roles, audit, ImpersonationOptions{TokenTTL: 10 * time.Minute}), keys
+}
+
+func TestImpersonationService_Impersonate(t *testing.T) {
+	actor := &auth.Claims{UserID: uuid.New(), SessionID: uuid.New(), Email: "admin@example.com", Role: auth.RoleAdmin, MFA: true}
+	userID := uuid.New()
+	client := model.ClientInfo{UserAgent: "test", IPAddress: "192.0.2.1"}
+
+	userRecord := func(role string) mockResponse {
+		return mockResponse{body: fmt.Sprintf(`{"data":{"users_by_pk":{"id":"%s","email":"alice@example.com","role":%q,"email_verified_at":null,"created_at":"2026-01-01T00:00:00Z","updated_at":"2026-01-01T00:00:00Z"}}}`, userID, role)}
+	}
+
+	t.Run("issues a token acting as the user", func(t *testing.T) {
+		var recorded map[string]interface{}
+		hasura, shutdown := newMockHasuraClient(t, []mockResponse{
+			userRecord(auth.RoleUser),
+			{respond: func(vars map[string]interface{}) string {
+				recorded = vars["object"].(map[string]interface{})
+				return fmt.Sprintf(`{"data":{"insert_audit_logs_one":{"id":"%s"}}}`, uuid.New())
+			}},
+		})
+		defer shutdown()
+
+		service, keys := newTestImpersonationService(t, hasura)
+		response, err := service.Impersonate(actor, userID, "ticket 42", client)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		claims, err := auth.ValidateToken(response.Token, keys)
+		if err != nil {
+			t.Fatalf("expected a valid token, got %v", err)
+		}
+		if claims.UserID != userID || claims.SessionID != actor.SessionID || !claims.Impersonated() || claims.Act.Subject != actor.UserID {
+			t.Fatalf("unexpected claims %+v, act %+v", claims, claims.Act)
+		}
+		if claims.Hasura == nil || claims.Hasura.DefaultRole != auth.HasuraRoleUser || len(claims.Hasura.AllowedRoles) != 1 {
+			t.Fatalf("expected Hasura to see only a user, got %+v", claims.Hasura)
+		}
+		if time.Until(response.ExpiresAt) > 10*time.Minute {
+			t.Fatalf("expected a short-lived token, expires at %v", response.ExpiresAt)
+		}
+
+		details := recorded["details"].(map[string]interface{})
+		if recorded["action"] != model.AuditActionImpersonationStart || recorded["user_id"] != userID.String() || details["reason"] != "ticket 42" {
+			t.Fatalf("unexpected audit entry %+v", recorded)
+		}
+	})
+
+	t.Run("user holds permissions the actor lacks", func(t *testing.T) {
+		hasura, shutdown := newMockHasuraClient(t, []mockResponse{
+			userRecord(auth.RoleAdmin),
+			roleResponse(`["users.read","users.impersonate"]`),
+		})
+		defer shutdown()
+
+		support := *actor
+		support.Role = "support"
+
+		service, _ := newTestImpersonationService(t, hasura)
+		if _, err := service.Impersonate(&support, userID, "ticket 42", client); !errors.Is(err, ErrImpersonationForbidden) {
+			t.Fatalf("expected ErrImpersonationForbidden, got %v", err)
+		}
+	})
+
+	t.Run("no self or nested impersonation", func(t *testing.T) {
+		hasura, shutdown := newMockHasuraClient(t, nil)
+		defer shutdown()
+
+		service, _ := newTestImpersonationService(t, hasura)
+		if _, err := service.Impersonate(actor, actor.UserID, "ticket 42", client); !errors.Is(err, ErrCannotImpersonateSelf) {
+			t.Fatalf("expected ErrCannotImpersonateSelf, got %v", err)
+		}
+
+		impersonating := *actor
+		impersonating.Act = &auth.Actor{Subject: uuid.New(), Email: "other@example.com"}
+		if _, err := service.Impersonate(&impersonating, userID, "ticket 42", client); !errors.Is(err, ErrImpersonationForbidden) {
+			t.Fatalf("expected ErrImpersonationForbidden, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,181 @@
+package service
+
+import (
+	"fmt"
+	"time"
+	"todo-app/backend/internal/auth"
+)
+
+type KeyringOptions struct {
+	// Algorithm is used for new keys; existing keys keep theirs
+	Algorithm string
+	// RotationInterval is how long a key signs before it is replaced
+	RotationInterval time.Duration
+	// ActivationDelay is how long a new key is published before it signs,
+	// so that other instances and verifiers caching the JWKS learn it first
+	ActivationDelay time.Duration
+	// AccessTokenTTL is how long a replaced key must keep verifying tokens
+	// it signed
+	AccessTokenTTL time.Duration
+}
+
+// KeyringService keeps the access token keyring in sync with the
+// signing_keys table, which every instance shares, and rotates keys on
+// schedule. Keys are kept in Postgres so that instances sign with the same
+// keys and restarts do not invalidate tokens.
+type KeyringService struct {
+	hasura *HasuraClient
+	keys   *auth.Keyring
+	opts   KeyringOptions
+}
+
+func NewKeyringService(hasura *HasuraClient, opts KeyringOptions) *KeyringService {
+	return &KeyringService{
+		hasura: hasura,
+		keys:   auth.NewKeyring(),
+		opts:   opts,
+	}
+}
+
+// Keyring returns the keyring tokens are signed and verified with. Its keys
+// change as Sync runs.
+func (s *KeyringService) Keyring() *auth.Keyring {
+	return s.keys
+}
+
+type signingKeyRow struct {
+	Kid         string     `json:"kid"`
+	Algorithm   string     `json:"algorithm"`
+	PrivateKey  string     `json:"private_key"`
+	ActivatesAt time.Time  `json:"activates_at"`
+	RetiresAt   *time.Time `json:"retires_at"`
+}
+
+// Sync loads the shared keys, rotates them when the newest is due for
+// replacement, and swaps them into the keyring. It reports whether it
+// rotated. Call it at startup and periodically after; instances that race to
+// rotate each add a key, which is harmless.
+func (s *KeyringService) Sync() (bool, error) {
+	now := time.Now().UTC()
+
+	var response struct {
+		SigningKeys []signingKeyRow `json:"signing_keys"`
+	}
+
+	err := s.hasura.execute(`
+        query ($now: timestamptz!) {
+          signing_keys(
+            where: {_or: [{retires_at: {_is_null: true}}, {retires_at: {_gt: $now}}]}
+            order_by: {activates_at: asc}
+          ) {
+            kid
+            algorithm
+            private_key
+            activates_at
+            retires_at
+          }
+        }
+        `, map[string]interface{}{"now": now}, &response)
+	if err != nil {
+		return false, err
+	}
+
+	keys := make([]auth.SigningKey, 0, len(response.SigningKeys)+1)
+	for _, row := range response.SigningKeys {
+		private, err := auth.ParsePrivateKey(row.Algorithm, row.PrivateKey)
+		if err != nil {
+			return false, fmt.Errorf("signing key %s: %w", row.Kid, err)
+		}
+		keys = append(keys, auth.SigningKey{
+			ID:          row.Kid,
+			Algorithm:   row.Algorithm,
+			PrivateKey:  private,
+			ActivatesAt: row.ActivatesAt,
+			RetiresAt:   row.RetiresAt,
+		})
+	}
+
+	rotated := false
+	if s.rotationDue(keys, now) {
+		keys, err = s.rotate(keys, now)
+		if err != nil {
+			return false, err
+		}
+		rotated = true
+	}
+
+	s.keys.Replace(keys)
+	return rotated, nil
+}
+
+// rotationDue reports whether no key will sign in future or the newest has
+// signed for a full rotation interval
+func (s *KeyringService) rotationDue(keys []auth.SigningKey, now time.Time) bool {
+	var newest *auth.SigningKey
+	for i := range keys {
+		if keys[i].RetiresAt == nil {
+			newest = &keys[i]
+		}
+	}
+	return newest == nil || !now.Before(newest.ActivatesAt.Add(s.opts.RotationInterval))
+}
+
+// rotate adds a key that starts signing after the activation delay and
+// schedules the current keys to retire once the tokens they signed have
+// expired. Retired keys are deleted at the same time.
+func (s *KeyringService) rotate(keys []auth.SigningKey, now time.Time) ([]auth.SigningKey, error) {
+	// With nothing to sign with, there is nobody to warn in advance
+	activatesAt := now.Add(s.opts.ActivationDelay)
+	if len(keys) == 0 {
+		activatesAt = now
+	}
+
+	key, err := auth.GenerateSigningKey(s.opts.Algorithm, activatesAt)
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := auth.MarshalPrivateKey(key.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	retiresAt := activatesAt.Add(s.opts.AccessTokenTTL)
+
+	var response struct {
+		InsertSigningKeysOne struct {
+			Kid string `json:"kid"`
+		} `json:"insert_signing_keys_one"`
+	}
+
+	err = s.hasura.execute(`
+        mutation ($now: timestamptz!, $retiresAt: timestamptz!, $object: signing_keys_insert_input!) {
+          delete_signing_keys(where: {retires_at: {_lte: $now}}) {
+            affected_rows
+          }
+          update_signing_keys(where: {retires_at: {_is_null: true}}, _set: {retires_at: $retiresAt}) {
+            affected_rows
+          }
+          insert_signing_keys_one(object: $object) {
+            kid
+          }
+        }
+        `, map[string]interface{}{
+		"now":       now,
+		"retiresAt": retiresAt,
+		"object": map[string]interface{}{
+			"kid":          key.ID,
+			"algorithm":    key.Algorithm,
+			"private_key":  encoded,
+			"activates_at": activatesAt,
+		},
+	}, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range keys {
+		if keys[i].RetiresAt == nil {
+			keys[i].RetiresAt = &retiresAt
+		}
+	}
+	return append(keys, key), nil
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+	"todo-app/backend/internal/auth"
+
+	"github.com/google/uuid"
+)
+
+func newTestKeyringService(client *HasuraClient) *KeyringService {
+	return NewKeyringService(client, KeyringOptions{
+		Algorithm:        auth.AlgorithmEdDSA,
+		RotationInterval: 30 * 24 * time.Hour,
+		ActivationDelay:  10 * time.Minute,
+		AccessTokenTTL:   15 * time.Minute,
+	})
+}
+
+// signingKeyRowJSON encodes a stored key as the signing_keys query returns it
+func signingKeyRowJSON(t *testing.T, activatesAt time.Time) (auth.SigningKey, string) {
+	t.Helper()
+
+	key, err := auth.GenerateSigningKey(auth.AlgorithmEdDSA, activatesAt)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	encoded, err := auth.MarshalPrivateKey(key.PrivateKey)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	row, _ := json.Marshal(signingKeyRow{
+		Kid:         key.ID,
+		Algorithm:   key.Algorithm,
+		PrivateKey:  encoded,
+		ActivatesAt: activatesAt,
+	})
+	return key, string(row)
+}
+
+func TestKeyringService_Sync(t *testing.T) {
+	t.Run("creates the first key active at once", func(t *testing.T) {
+		var inserted map[string]interface{}
+		client, shutdown := newMockHasuraClient(t, []mockResponse{
+			{body: `{"data":{"signing_keys":[]}}`},
+			{respond: func(variables map[string]interface{}) string {
+				inserted = variables["object"].(map[string]interface{})
+				return fmt.Sprintf(`{"data":{"insert_signing_keys_one":{"kid":"%s"}}}`, inserted["kid"])
+			}},
+		})
+		defer shutdown()
+
+		service := newTestKeyringService(client)
+		rotated, err := service.Sync()
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if !rotated {
+			t.Fatalf("expected a key to be created")
+		}
+
+		if _, err := auth.ParsePrivateKey(auth.AlgorithmEdDSA, inserted["private_key"].(string)); err != nil {
+			t.Fatalf("expected a stored PKCS #8 key, got %v", err)
+		}
+
+		token, _, err := auth.GenerateToken(auth.Claims{UserID: uuid.New()}, service.Keyring(), time.Minute)
+		if err != nil {
+			t.Fatalf("expected the new key to sign, got %v", err)
+		}
+		if _, err := auth.ValidateToken(token, service.Keyring()); err != nil {
+			t.Fatalf("expected the token to verify, got %v", err)
+		}
+	})
+
+	t.Run("keeps a current key", func(t *testing.T) {
+		key, row := signingKeyRowJSON(t, time.Now().Add(-24*time.Hour))
+		client, shutdown := newMockHasuraClient(t, []mockResponse{
+			{body: fmt.Sprintf(`{"data":{"signing_keys":[%s]}}`, row)},
+		})
+		defer shutdown()
+
+		service := newTestKeyringService(client)
+		rotated, err := service.Sync()
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if rotated {
+			t.Fatalf("expected no rotation")
+		}
+
+		keys := service.Keyring().Keys()
+		if len(keys) != 1 || keys[0].ID != key.ID {
+			t.Fatalf("expected the stored key, got %+v", keys)
+		}
+	})
+
+	t.Run("rotates an old key", func(t *testing.T) {
+		old, row := signingKeyRowJSON(t, time.Now().Add(-31*24*time.Hour))
+		var retiresAt time.Time
+		client, shutdown := newMockHasuraClient(t, []mockResponse{
+			{body: fmt.Sprintf(`{"data":{"signing_keys":[%s]}}`, row)},
+			{respond: func(variables map[string]interface{}) string {
+				retiresAt, _ = time.Parse(time.RFC3339Nano, variables["retiresAt"].(string))
+				object := variables["object"].(map[string]interface{})
+				return fmt.Sprintf(`{"data":{"delete_signing_keys":{"affected_rows":0},"update_signing_keys":{"affected_rows":1},"insert_signing_keys_one":{"kid":"%s"}}}`, object["kid"])
+			}},
+		})
+		defer shutdown()
+
+		service := newTestKeyringService(client)
+		rotated, err := service.Sync()
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if !rotated {
+			t.Fatalf("expected a rotation")
+		}
+
+		// The new key activates after the delay, and the old one retires once
+		// its last tokens have expired
+		keys := service.Keyring().Keys()
+		if len(keys) != 2 {
+			t.Fatalf("expected two keys, got %d", len(keys))
+		}
+		next := keys[1]
+		if wait := time.Until(next.ActivatesAt); wait < 9*time.Minute || wait > 10*time.Minute {
+			t.Fatalf("expected activation in about ten minutes, got %s", wait)
+		}
+		if !retiresAt.Equal(next.ActivatesAt.Add(15*time.Minute)) || keys[0].RetiresAt == nil || !keys[0].RetiresAt.Equal(retiresAt) {
+			t.Fatalf("expected the old key to retire at %s, got %v", retiresAt, keys[0].RetiresAt)
+		}
+
+		// Until then the old key keeps signing
+		token, _, err := auth.GenerateToken(auth.Claims{UserID: uuid.New()}, service.Keyring(), time.Minute)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if _, err := auth.ValidateToken(token, auth.NewKeyring(old)); err != nil {
+			t.Fatalf("expected the old key to sign, got %v", err)
+		}
+	})
+}
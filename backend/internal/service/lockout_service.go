@@ -0,0 +1,170 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"time"
+	"todo-app/backend/internal/model"
+)
+
+var (
+	ErrLockedOut           = errors.New("too many failed logins")
+	ErrLockoutNotFound     = errors.New("lockout not found")
+	ErrInvalidLockoutScope = errors.New("invalid lockout scope")
+)
+
+// LockedOutError is returned while logins are refused. It matches
+// ErrLockedOut.
+type LockedOutError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockedOutError) Error() string {
+	return ErrLockedOut.Error()
+}
+
+func (e *LockedOutError) Is(target error) bool {
+	return target == ErrLockedOut
+}
+
+type LockoutOptions struct {
+	// AccountThreshold is how many failed logins an account allows before
+	// it is locked
+	AccountThreshold int
+	// IPThreshold is how many failed logins a client address allows,
+	// across accounts, before it is locked
+	IPThreshold int
+	// BaseDuration is the first lockout; every further failure doubles it
+	BaseDuration time.Duration
+	// MaxDuration caps the lockout
+	MaxDuration time.Duration
+	// FailureWindow is how long failures are remembered after the last one.
+	// It must exceed MaxDuration for the backoff to keep growing.
+	FailureWindow time.Duration
+}
+
+// LockoutService slows down password guessing. Failed logins are counted per
+// account and per client address; once either passes its threshold, logins
+// from it are refused for a period that doubles with each further failure.
+// Accounts are keyed by the email tried, so unknown emails lock the same way
+// and reveal nothing.
+type LockoutService struct {
+	store LockoutStore
+	opts  LockoutOptions
+}
+
+func NewLockoutService(store LockoutStore, opts LockoutOptions) *LockoutService {
+	return &LockoutService{store: store, opts: opts}
+}
+
+// Check returns a *LockedOutError when the account or the address is locked
+func (s *LockoutService) Check(email, ip string) error {
+	now := time.Now()
+
+	var retryAfter time.Duration
+	for _, key := range s.keys(email, ip) {
+		record, err := s.store.Get(key.scope, key.subject)
+		if err != nil {
+			return err
+		}
+		if record != nil && record.LockedUntil != nil {
+			if wait := record.LockedUntil.Sub(now); wait > retryAfter {
+				retryAfter = wait
+			}
+		}
+	}
+
+	if retryAfter > 0 {
+		return &LockedOutError{RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+// RecordFailure counts a failed login and locks whatever passed its
+// threshold
+func (s *LockoutService) RecordFailure(email, ip string) error {
+	now := time.Now()
+
+	for _, key := range s.keys(email, ip) {
+		record, err := s.store.RecordFailure(key.scope, key.subject, now, now.Add(-s.opts.FailureWindow))
+		if err != nil {
+			return err
+		}
+
+		threshold := s.opts.AccountThreshold
+		if key.scope == model.LockoutScopeIP {
+			threshold = s.opts.IPThreshold
+		}
+		if record.Failures < threshold {
+			continue
+		}
+
+		if err := s.store.Lock(key.scope, key.subject, now.Add(s.duration(record.Failures-threshold))); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RecordSuccess forgets the failures of an account once its password has
+// been given. The address keeps its count, so one valid account cannot be
+// used to keep guessing others.
+func (s *LockoutService) RecordSuccess(email string) error {
+	_, err := s.store.Clear(model.LockoutScopeAccount, normalizeEmail(email))
+	return err
+}
+
+// Locked returns the accounts and addresses locked at the moment
+func (s *LockoutService) Locked() ([]model.Lockout, error) {
+	return s.store.Locked(time.Now())
+}
+
+// Clear lifts a lockout and forgets its failures
+func (s *LockoutService) Clear(scope, subject string) error {
+	switch scope {
+	case model.LockoutScopeAccount:
+		subject = normalizeEmail(subject)
+	case model.LockoutScopeIP:
+	default:
+		return ErrInvalidLockoutScope
+	}
+
+	cleared, err := s.store.Clear(scope, subject)
+	if err != nil {
+		return err
+	}
+	if !cleared {
+		return ErrLockoutNotFound
+	}
+	return nil
+}
+
+// PurgeExpired removes counts whose failures have been forgotten
+func (s *LockoutService) PurgeExpired() (int, error) {
+	return s.store.Purge(time.Now().Add(-s.opts.FailureWindow))
+}
+
+// duration is the lockout after excess failures beyond the threshold
+func (s *LockoutService) duration(excess int) time.Duration {
+	d := s.opts.BaseDuration
+	for i := 0; i < excess && d < s.opts.MaxDuration; i++ {
+		d *= 2
+	}
+	if d > s.opts.MaxDuration {
+		d = s.opts.MaxDuration
+	}
+	return d
+}
+
+func (s *LockoutService) keys(email, ip string) []lockoutKey {
+	keys := []lockoutKey{{model.LockoutScopeAccount, normalizeEmail(email)}}
+	if ip != "" {
+		keys = append(keys, lockoutKey{model.LockoutScopeIP, ip})
+	}
+	return keys
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
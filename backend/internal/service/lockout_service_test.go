@@ -0,0 +1,156 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+	"todo-app/backend/internal/model"
+)
+
+var testLockoutOptions = LockoutOptions{
+	AccountThreshold: 3,
+	IPThreshold:      5,
+	BaseDuration:     time.Minute,
+	MaxDuration:      time.Hour,
+	FailureWindow:    24 * time.Hour,
+}
+
+func TestLockoutService(t *testing.T) {
+	t.Run("locks an account with growing backoff", func(t *testing.T) {
+		service := NewLockoutService(NewMemoryLockoutStore(), testLockoutOptions)
+
+		for i := 0; i < 2; i++ {
+			if err := service.RecordFailure("alice@example.com", ""); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+		}
+		if err := service.Check("alice@example.com", ""); err != nil {
+			t.Fatalf("expected no lockout below the threshold, got %v", err)
+		}
+
+		var previous time.Duration
+		for i := 0; i < 3; i++ {
+			if err := service.RecordFailure("alice@example.com", ""); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			var locked *LockedOutError
+			if err := service.Check("Alice@Example.com", ""); !errors.As(err, &locked) {
+				t.Fatalf("expected a lockout, got %v", err)
+			}
+			if locked.RetryAfter <= previous || locked.RetryAfter > time.Duration(1<<i)*time.Minute {
+				t.Fatalf("expected the lockout to double, got %s after %s", locked.RetryAfter, previous)
+			}
+			previous = locked.RetryAfter
+		}
+
+		if err := service.Check("bob@example.com", ""); err != nil {
+			t.Fatalf("expected other accounts to be unaffected, got %v", err)
+		}
+	})
+
+	t.Run("caps the lockout", func(t *testing.T) {
+		service := NewLockoutService(NewMemoryLockoutStore(), testLockoutOptions)
+		if d := service.duration(100); d != time.Hour {
+			t.Fatalf("expected the maximum, got %s", d)
+		}
+	})
+
+	t.Run("locks an address across accounts", func(t *testing.T) {
+		service := NewLockoutService(NewMemoryLockoutStore(), testLockoutOptions)
+
+		for i := 0; i < 5; i++ {
+			if err := service.RecordFailure(fmt.Sprintf("user%d@example.com", i), "192.0.2.1"); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+		}
+
+		if err := service.Check("carol@example.com", "192.0.2.1"); !errors.Is(err, ErrLockedOut) {
+			t.Fatalf("expected the address to be locked, got %v", err)
+		}
+		if err := service.Check("carol@example.com", "192.0.2.2"); err != nil {
+			t.Fatalf("expected other addresses to be unaffected, got %v", err)
+		}
+	})
+
+	t.Run("success and clearing reset the count", func(t *testing.T) {
+		service := NewLockoutService(NewMemoryLockoutStore(), testLockoutOptions)
+
+		for i := 0; i < 2; i++ {
+			_ = service.RecordFailure("alice@example.com", "192.0.2.1")
+		}
+		if err := service.RecordSuccess("alice@example.com"); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		_ = service.RecordFailure("alice@example.com", "192.0.2.1")
+		if err := service.Check("alice@example.com", ""); err != nil {
+			t.Fatalf("expected the count to start over, got %v", err)
+		}
+
+		for i := 0; i < 2; i++ {
+			_ = service.RecordFailure("alice@example.com", "192.0.2.1")
+		}
+		locked, err := service.Locked()
+		if err != nil || len(locked) != 2 {
+			t.Fatalf("expected the account and the address to be locked, got %+v, %v", locked, err)
+		}
+
+		if err := service.Clear(model.LockoutScopeAccount, "ALICE@example.com"); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if err := service.Clear(model.LockoutScopeAccount, "alice@example.com"); !errors.Is(err, ErrLockoutNotFound) {
+			t.Fatalf("expected ErrLockoutNotFound, got %v", err)
+		}
+		if err := service.Clear("user", "alice@example.com"); !errors.Is(err, ErrInvalidLockoutScope) {
+			t.Fatalf("expected ErrInvalidLockoutScope, got %v", err)
+		}
+	})
+}
+
+func TestPostgresLockoutStore_RecordFailure(t *testing.T) {
+	var resetBefore string
+	client, shutdown := newMockHasuraClient(t, []mockResponse{
+		{respond: func(variables map[string]interface{}) string {
+			resetBefore = variables["resetBefore"].(string)
+			return `{"data":{"insert_login_lockouts_one":null,"update_login_lockouts":{"affected_rows":0},"update_login_lockouts_by_pk":{"scope":"account","subject":"alice@example.com","failures":4,"last_failed_at":"2026-01-01T00:00:00Z","locked_until":null}}}`
+		}},
+		{body: `{"data":{"update_login_lockouts_by_pk":{"scope":"account"}}}`},
+	})
+	defer shutdown()
+
+	service := NewLockoutService(NewPostgresLockoutStore(client), testLockoutOptions)
+	if err := service.RecordFailure("alice@example.com", ""); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	cutoff, err := time.Parse(time.RFC3339Nano, resetBefore)
+	if err != nil {
+		t.Fatalf("expected a timestamp, got %q", resetBefore)
+	}
+	if since := time.Since(cutoff); since < 23*time.Hour || since > 25*time.Hour {
+		t.Fatalf("expected failures older than the window to reset, got %s", since)
+	}
+}
+
+func TestAuthService_LoginLockout(t *testing.T) {
+	client, shutdown := newMockHasuraClient(t, []mockResponse{
+		{body: `{"data":{"users":[]}}`},
+		{body: `{"data":{"users":[]}}`},
+		{body: `{"data":{"users":[]}}`},
+	})
+	defer shutdown()
+
+	service := newTestAuthService(t, client, AuthOptions{})
+	info := model.ClientInfo{IPAddress: "192.0.2.1"}
+	for i := 0; i < 3; i++ {
+		if _, _, err := service.Login("nobody@example.com", "guess", info); !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("expected ErrInvalidCredentials, got %v", err)
+		}
+	}
+
+	// Locked logins never reach Postgres
+	var locked *LockedOutError
+	if _, _, err := service.Login("nobody@example.com", "guess", info); !errors.As(err, &locked) || locked.RetryAfter <= 0 {
+		t.Fatalf("expected a lockout, got %v", err)
+	}
+}
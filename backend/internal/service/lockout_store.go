@@ -0,0 +1,280 @@
+package service
+
+import (
+	"sort"
+	"sync"
+	"time"
+	"todo-app/backend/internal/model"
+)
+
+// LockoutStore keeps failed login counts. Records are identified by a scope
+// and a subject, such as an email or a client address.
+type LockoutStore interface {
+	// Get returns the record, or nil when there is none
+	Get(scope, subject string) (*model.Lockout, error)
+	// RecordFailure counts a failed login at now and returns the updated
+	// record. A count whose last failure is before resetBefore starts over.
+	RecordFailure(scope, subject string, now, resetBefore time.Time) (*model.Lockout, error)
+	// Lock refuses logins for the record until the given time
+	Lock(scope, subject string, until time.Time) error
+	// Clear forgets the record, reporting whether there was one
+	Clear(scope, subject string) (bool, error)
+	// Locked returns the records locked at now
+	Locked(now time.Time) ([]model.Lockout, error)
+	// Purge removes records whose last failure is before the given time
+	Purge(before time.Time) (int, error)
+}
+
+type lockoutKey struct {
+	scope, subject string
+}
+
+// MemoryLockoutStore keeps counts in process memory. Each instance counts on
+// its own, so it suits a single instance.
+type MemoryLockoutStore struct {
+	mu      sync.Mutex
+	records map[lockoutKey]*model.Lockout
+}
+
+func NewMemoryLockoutStore() *MemoryLockoutStore {
+	return &MemoryLockoutStore{records: make(map[lockoutKey]*model.Lockout)}
+}
+
+func (s *MemoryLockoutStore) Get(scope, subject string) (*model.Lockout, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.records[lockoutKey{scope, subject}]
+	if !ok {
+		return nil, nil
+	}
+	copied := *record
+	return &copied, nil
+}
+
+func (s *MemoryLockoutStore) RecordFailure(scope, subject string, now, resetBefore time.Time) (*model.Lockout, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := lockoutKey{scope, subject}
+	record, ok := s.records[key]
+	if !ok || record.LastFailedAt.Before(resetBefore) {
+		record = &model.Lockout{Scope: scope, Subject: subject}
+		s.records[key] = record
+	}
+	record.Failures++
+	record.LastFailedAt = now
+
+	copied := *record
+	return &copied, nil
+}
+
+func (s *MemoryLockoutStore) Lock(scope, subject string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if record, ok := s.records[lockoutKey{scope, subject}]; ok {
+		record.LockedUntil = &until
+	}
+	return nil
+}
+
+func (s *MemoryLockoutStore) Clear(scope, subject string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := lockoutKey{scope, subject}
+	_, ok := s.records[key]
+	delete(s.records, key)
+	return ok, nil
+}
+
+func (s *MemoryLockoutStore) Locked(now time.Time) ([]model.Lockout, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	locked := []model.Lockout{}
+	for _, record := range s.records {
+		if record.LockedUntil != nil && record.LockedUntil.After(now) {
+			locked = append(locked, *record)
+		}
+	}
+	sort.Slice(locked, func(i, j int) bool {
+		return locked[i].LockedUntil.After(*locked[j].LockedUntil)
+	})
+	return locked, nil
+}
+
+func (s *MemoryLockoutStore) Purge(before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	purged := 0
+	for key, record := range s.records {
+		if record.LastFailedAt.Before(before) {
+			delete(s.records, key)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// PostgresLockoutStore keeps counts in Postgres, so that every instance
+// shares them
+type PostgresLockoutStore struct {
+	hasura *HasuraClient
+}
+
+func NewPostgresLockoutStore(hasura *HasuraClient) *PostgresLockoutStore {
+	return &PostgresLockoutStore{hasura: hasura}
+}
+
+func (s *PostgresLockoutStore) Get(scope, subject string) (*model.Lockout, error) {
+	var response struct {
+		LoginLockoutsByPk *model.Lockout `json:"login_lockouts_by_pk"`
+	}
+
+	err := s.hasura.execute(`
+        query ($scope: String!, $subject: String!) {
+          login_lockouts_by_pk(scope: $scope, subject: $subject) {
+            scope
+            subject
+            failures
+            last_failed_at
+            locked_until
+          }
+        }
+        `, map[string]interface{}{"scope": scope, "subject": subject}, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	return response.LoginLockoutsByPk, nil
+}
+
+// RecordFailure creates, resets and increments the count in one
+// transaction, so concurrent failures are all counted
+func (s *PostgresLockoutStore) RecordFailure(scope, subject string, now, resetBefore time.Time) (*model.Lockout, error) {
+	var response struct {
+		UpdateLoginLockoutsByPk *model.Lockout `json:"update_login_lockouts_by_pk"`
+	}
+
+	err := s.hasura.execute(`
+        mutation ($scope: String!, $subject: String!, $now: timestamptz!, $resetBefore: timestamptz!) {
+          insert_login_lockouts_one(
+            object: {scope: $scope, subject: $subject, failures: 0, last_failed_at: $now},
+            on_conflict: {constraint: login_lockouts_pkey, update_columns: []}
+          ) {
+            scope
+          }
+          update_login_lockouts(
+            where: {scope: {_eq: $scope}, subject: {_eq: $subject}, last_failed_at: {_lt: $resetBefore}},
+            _set: {failures: 0, locked_until: null}
+          ) {
+            affected_rows
+          }
+          update_login_lockouts_by_pk(
+            pk_columns: {scope: $scope, subject: $subject},
+            _inc: {failures: 1},
+            _set: {last_failed_at: $now}
+          ) {
+            scope
+            subject
+            failures
+            last_failed_at
+            locked_until
+          }
+        }
+        `, map[string]interface{}{
+		"scope":       scope,
+		"subject":     subject,
+		"now":         now.UTC(),
+		"resetBefore": resetBefore.UTC(),
+	}, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	return response.UpdateLoginLockoutsByPk, nil
+}
+
+func (s *PostgresLockoutStore) Lock(scope, subject string, until time.Time) error {
+	var response struct {
+		UpdateLoginLockoutsByPk *struct {
+			Scope string `json:"scope"`
+		} `json:"update_login_lockouts_by_pk"`
+	}
+
+	return s.hasura.execute(`
+        mutation ($scope: String!, $subject: String!, $until: timestamptz!) {
+          update_login_lockouts_by_pk(pk_columns: {scope: $scope, subject: $subject}, _set: {locked_until: $until}) {
+            scope
+          }
+        }
+        `, map[string]interface{}{"scope": scope, "subject": subject, "until": until.UTC()}, &response)
+}
+
+func (s *PostgresLockoutStore) Clear(scope, subject string) (bool, error) {
+	var response struct {
+		DeleteLoginLockoutsByPk *struct {
+			Scope string `json:"scope"`
+		} `json:"delete_login_lockouts_by_pk"`
+	}
+
+	err := s.hasura.execute(`
+        mutation ($scope: String!, $subject: String!) {
+          delete_login_lockouts_by_pk(scope: $scope, subject: $subject) {
+            scope
+          }
+        }
+        `, map[string]interface{}{"scope": scope, "subject": subject}, &response)
+	if err != nil {
+		return false, err
+	}
+
+	return response.DeleteLoginLockoutsByPk != nil, nil
+}
+
+func (s *PostgresLockoutStore) Locked(now time.Time) ([]model.Lockout, error) {
+	var response struct {
+		LoginLockouts []model.Lockout `json:"login_lockouts"`
+	}
+
+	err := s.hasura.execute(`
+        query ($now: timestamptz!) {
+          login_lockouts(where: {locked_until: {_gt: $now}}, order_by: {locked_until: desc}) {
+            scope
+            subject
+            failures
+            last_failed_at
+            locked_until
+          }
+        }
+        `, map[string]interface{}{"now": now.UTC()}, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	return response.LoginLockouts, nil
+}
+
+func (s *PostgresLockoutStore) Purge(before time.Time) (int, error) {
+	var response struct {
+		DeleteLoginLockouts struct {
+			AffectedRows int `json:"affected_rows"`
+		} `json:"delete_login_lockouts"`
+	}
+
+	err := s.hasura.execute(`
+        mutation ($before: timestamptz!) {
+          delete_login_lockouts(where: {last_failed_at: {_lt: $before}}) {
+            affected_rows
+          }
+        }
+        `, map[string]interface{}{"before": before.UTC()}, &response)
+	if err != nil {
+		return 0, err
+	}
+
+	return response.DeleteLoginLockouts.AffectedRows, nil
+}
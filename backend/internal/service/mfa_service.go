@@ -0,0 +1,525 @@
+package service
+
+import (
+	"errors"
+	"time"
+	"todo-app/backend/internal/auth"
+	"todo-app/backend/internal/model"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrMFAAlreadyEnabled = errors.New("two-factor authentication already enabled")
+	ErrMFANotEnrolled    = errors.New("two-factor authentication not enabled")
+	ErrInvalidMFACode    = errors.New("invalid two-factor code")
+	ErrInvalidMFAToken   = errors.New("invalid or expired mfa token")
+)
+
+const (
+	// totpSkew is how many 30 second steps either side of now a code may
+	// come from, to allow for clock drift
+	totpSkew = 1
+	// recoveryCodeCount is the number of recovery codes issued at a time
+	recoveryCodeCount = 10
+)
+
+type MFAOptions struct {
+	// Issuer names the account in authenticator apps
+	Issuer string
+	// ChallengeTTL is how long the second login step may take
+	ChallengeTTL time.Duration
+	// MaxAttempts is the number of wrong codes a challenge survives
+	MaxAttempts int
+}
+
+// MFAService manages TOTP enrollment and recovery codes, and verifies the
+// second step of a login
+type MFAService struct {
+	hasura   *HasuraClient
+	accounts *AccountService
+	opts     MFAOptions
+}
+
+func NewMFAService(hasura *HasuraClient, accounts *AccountService, opts MFAOptions) *MFAService {
+	return &MFAService{
+		hasura:   hasura,
+		accounts: accounts,
+		opts:     opts,
+	}
+}
+
+// Status reports whether the user has a confirmed second factor
+func (s *MFAService) Status(userID uuid.UUID) (*model.MFAStatus, error) {
+	var response struct {
+		UserMfaByPk *struct {
+			ConfirmedAt *time.Time `json:"confirmed_at"`
+		} `json:"user_mfa_by_pk"`
+		MfaRecoveryCodesAggregate struct {
+			Aggregate struct {
+				Count int `json:"count"`
+			} `json:"aggregate"`
+		} `json:"mfa_recovery_codes_aggregate"`
+	}
+
+	err := s.hasura.execute(`
+        query ($userId: uuid!) {
+          user_mfa_by_pk(user_id: $userId) {
+            confirmed_at
+          }
+          mfa_recovery_codes_aggregate(where: {user_id: {_eq: $userId}, used_at: {_is_null: true}}) {
+            aggregate {
+              count
+            }
+          }
+        }
+        `, map[string]interface{}{"userId": userID}, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &model.MFAStatus{}
+	if response.UserMfaByPk != nil && response.UserMfaByPk.ConfirmedAt != nil {
+		status.Enabled = true
+		status.ConfirmedAt = response.UserMfaByPk.ConfirmedAt
+		status.RecoveryCodesRemaining = response.MfaRecoveryCodesAggregate.Aggregate.Count
+	}
+
+	return status, nil
+}
+
+// IsEnabled reports whether logins of the user need a second factor
+func (s *MFAService) IsEnabled(userID uuid.UUID) (bool, error) {
+	var response struct {
+		UserMfaByPk *struct {
+			ConfirmedAt *time.Time `json:"confirmed_at"`
+		} `json:"user_mfa_by_pk"`
+	}
+
+	err := s.hasura.execute(`
+        query ($userId: uuid!) {
+          user_mfa_by_pk(user_id: $userId) {
+            confirmed_at
+          }
+        }
+        `, map[string]interface{}{"userId": userID}, &response)
+	if err != nil {
+		return false, err
+	}
+
+	return response.UserMfaByPk != nil && response.UserMfaByPk.ConfirmedAt != nil, nil
+}
+
+// BeginEnrollment creates a new TOTP secret. It has no effect on logins until
+// a code generated from it is confirmed.
+func (s *MFAService) BeginEnrollment(userID uuid.UUID, password string) (*model.TOTPEnrollment, error) {
+	email, err := s.accounts.checkPassword(userID, password)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		InsertUserMfaOne *struct {
+			UserID uuid.UUID `json:"user_id"`
+		} `json:"insert_user_mfa_one"`
+	}
+
+	// A pending secret is replaced; a confirmed one is left alone
+	err = s.hasura.execute(`
+        mutation ($userId: uuid!, $secret: String!) {
+          insert_user_mfa_one(
+            object: {user_id: $userId, secret: $secret},
+            on_conflict: {constraint: user_mfa_pkey, update_columns: [secret], where: {confirmed_at: {_is_null: true}}}
+          ) {
+            user_id
+          }
+        }
+        `, map[string]interface{}{"userId": userID, "secret": secret}, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.InsertUserMfaOne == nil {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	return &model.TOTPEnrollment{
+		Secret:     secret,
+		OtpauthURI: auth.TOTPURI(s.opts.Issuer, email, secret),
+	}, nil
+}
+
+// ConfirmEnrollment turns on the pending secret once the user proves their
+// authenticator produces matching codes, and returns fresh recovery codes.
+// The session that confirmed counts as having passed the second factor.
+func (s *MFAService) ConfirmEnrollment(userID, sessionID uuid.UUID, code string) ([]string, error) {
+	var response struct {
+		UserMfaByPk *struct {
+			Secret      string     `json:"secret"`
+			ConfirmedAt *time.Time `json:"confirmed_at"`
+		} `json:"user_mfa_by_pk"`
+	}
+
+	err := s.hasura.execute(`
+        query ($userId: uuid!) {
+          user_mfa_by_pk(user_id: $userId) {
+            secret
+            confirmed_at
+          }
+        }
+        `, map[string]interface{}{"userId": userID}, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	enrollment := response.UserMfaByPk
+	if enrollment == nil {
+		return nil, ErrMFANotEnrolled
+	}
+	if enrollment.ConfirmedAt != nil {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	step, ok := auth.ValidateTOTP(enrollment.Secret, code, time.Now(), totpSkew)
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	codes, objects, err := s.newRecoveryCodes(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var confirmResp struct {
+		UpdateUserMfa struct {
+			AffectedRows int `json:"affected_rows"`
+		} `json:"update_user_mfa"`
+	}
+
+	err = s.hasura.execute(`
+        mutation ($userId: uuid!, $sessionId: uuid!, $step: bigint!, $now: timestamptz!, $codes: [mfa_recovery_codes_insert_input!]!) {
+          update_user_mfa(where: {user_id: {_eq: $userId}, confirmed_at: {_is_null: true}}, _set: {confirmed_at: $now, last_used_step: $step}) {
+            affected_rows
+          }
+          update_sessions(where: {id: {_eq: $sessionId}, user_id: {_eq: $userId}}, _set: {mfa_verified_at: $now}) {
+            affected_rows
+          }
+          delete_mfa_recovery_codes(where: {user_id: {_eq: $userId}}) {
+            affected_rows
+          }
+          insert_mfa_recovery_codes(objects: $codes) {
+            affected_rows
+          }
+        }
+        `, map[string]interface{}{
+		"userId":    userID,
+		"sessionId": sessionID,
+		"step":      step,
+		"now":       time.Now().UTC(),
+		"codes":     objects,
+	}, &confirmResp)
+	if err != nil {
+		return nil, err
+	}
+
+	if confirmResp.UpdateUserMfa.AffectedRows == 0 {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	return codes, nil
+}
+
+// RegenerateRecoveryCodes replaces all of the user's recovery codes
+func (s *MFAService) RegenerateRecoveryCodes(userID uuid.UUID, password string) ([]string, error) {
+	if _, err := s.accounts.checkPassword(userID, password); err != nil {
+		return nil, err
+	}
+
+	enabled, err := s.IsEnabled(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !enabled {
+		return nil, ErrMFANotEnrolled
+	}
+
+	codes, objects, err := s.newRecoveryCodes(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		DeleteMfaRecoveryCodes struct {
+			AffectedRows int `json:"affected_rows"`
+		} `json:"delete_mfa_recovery_codes"`
+		InsertMfaRecoveryCodes struct {
+			AffectedRows int `json:"affected_rows"`
+		} `json:"insert_mfa_recovery_codes"`
+	}
+
+	err = s.hasura.execute(`
+        mutation ($userId: uuid!, $codes: [mfa_recovery_codes_insert_input!]!) {
+          delete_mfa_recovery_codes(where: {user_id: {_eq: $userId}}) {
+            affected_rows
+          }
+          insert_mfa_recovery_codes(objects: $codes) {
+            affected_rows
+          }
+        }
+        `, map[string]interface{}{"userId": userID, "codes": objects}, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// Disable turns off the user's second factor
+func (s *MFAService) Disable(userID uuid.UUID, password string) error {
+	if _, err := s.accounts.checkPassword(userID, password); err != nil {
+		return err
+	}
+	return s.Reset(userID)
+}
+
+// Reset removes the user's second factor without their password, for users
+// who lost their authenticator and recovery codes (admin function)
+func (s *MFAService) Reset(userID uuid.UUID) error {
+	var response struct {
+		DeleteUserMfaByPk *struct {
+			UserID uuid.UUID `json:"user_id"`
+		} `json:"delete_user_mfa_by_pk"`
+		DeleteMfaRecoveryCodes struct {
+			AffectedRows int `json:"affected_rows"`
+		} `json:"delete_mfa_recovery_codes"`
+	}
+
+	err := s.hasura.execute(`
+        mutation ($userId: uuid!) {
+          delete_user_mfa_by_pk(user_id: $userId) {
+            user_id
+          }
+          delete_mfa_recovery_codes(where: {user_id: {_eq: $userId}}) {
+            affected_rows
+          }
+        }
+        `, map[string]interface{}{"userId": userID}, &response)
+	if err != nil {
+		return err
+	}
+
+	if response.DeleteUserMfaByPk == nil {
+		return ErrMFANotEnrolled
+	}
+
+	return nil
+}
+
+// Challenge issues the token that carries a login from the password step to
+// the second factor
+func (s *MFAService) Challenge(userID uuid.UUID) (*model.MFAChallenge, error) {
+	expiresAt := time.Now().Add(s.opts.ChallengeTTL)
+
+	token, err := s.accounts.issueToken(userID, tokenPurposeMFAChallenge, "", s.opts.ChallengeTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.MFAChallenge{
+		MFARequired: true,
+		MFAToken:    token,
+		ExpiresAt:   expiresAt,
+	}, nil
+}
+
+// challengeEmail returns the email of the account an open login challenge
+// was issued to, so its lockout can be checked before a code is tried
+func (s *MFAService) challengeEmail(mfaToken string) (string, error) {
+	var response struct {
+		UserTokens []struct {
+			User struct {
+				Email string `json:"email"`
+			} `json:"user"`
+		} `json:"user_tokens"`
+	}
+
+	err := s.hasura.execute(`
+        query ($tokenHash: String!, $purpose: String!, $now: timestamptz!, $maxAttempts: Int!) {
+          user_tokens(where: {token_hash: {_eq: $tokenHash}, purpose: {_eq: $purpose}, used_at: {_is_null: true}, expires_at: {_gt: $now}, attempts: {_lt: $maxAttempts}}, limit: 1) {
+            user {
+              email
+            }
+          }
+        }
+        `, map[string]interface{}{
+		"tokenHash":   auth.HashOpaqueToken(mfaToken),
+		"purpose":     tokenPurposeMFAChallenge,
+		"now":         time.Now().UTC(),
+		"maxAttempts": s.opts.MaxAttempts,
+	}, &response)
+	if err != nil {
+		return "", err
+	}
+
+	if len(response.UserTokens) == 0 {
+		return "", ErrInvalidMFAToken
+	}
+
+	return response.UserTokens[0].User.Email, nil
+}
+
+// Verify checks a TOTP or recovery code against a login challenge and
+// returns the user the challenge was issued to. Every try uses up one of the
+// challenge's MaxAttempts, taken before the code is checked so concurrent
+// tries cannot exceed it; a correct code spends the challenge.
+func (s *MFAService) Verify(mfaToken, code string) (uuid.UUID, error) {
+	var response struct {
+		UpdateUserTokens struct {
+			Returning []struct {
+				UserID uuid.UUID `json:"user_id"`
+			} `json:"returning"`
+		} `json:"update_user_tokens"`
+	}
+
+	err := s.hasura.execute(`
+        mutation ($tokenHash: String!, $purpose: String!, $now: timestamptz!, $maxAttempts: Int!) {
+          update_user_tokens(where: {token_hash: {_eq: $tokenHash}, purpose: {_eq: $purpose}, used_at: {_is_null: true}, expires_at: {_gt: $now}, attempts: {_lt: $maxAttempts}}, _inc: {attempts: 1}) {
+            returning {
+              user_id
+            }
+          }
+        }
+        `, map[string]interface{}{
+		"tokenHash":   auth.HashOpaqueToken(mfaToken),
+		"purpose":     tokenPurposeMFAChallenge,
+		"now":         time.Now().UTC(),
+		"maxAttempts": s.opts.MaxAttempts,
+	}, &response)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	if len(response.UpdateUserTokens.Returning) == 0 {
+		return uuid.Nil, ErrInvalidMFAToken
+	}
+
+	userID := response.UpdateUserTokens.Returning[0].UserID
+
+	ok, err := s.checkCode(userID, code)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if !ok {
+		return uuid.Nil, ErrInvalidMFACode
+	}
+
+	if _, err := s.accounts.consumeToken(mfaToken, tokenPurposeMFAChallenge); err != nil {
+		if errors.Is(err, ErrInvalidAccountToken) {
+			return uuid.Nil, ErrInvalidMFAToken
+		}
+		return uuid.Nil, err
+	}
+
+	return userID, nil
+}
+
+// checkCode accepts a TOTP code that has not been used before or an unused
+// recovery code, and uses it up
+func (s *MFAService) checkCode(userID uuid.UUID, code string) (bool, error) {
+	var enrollResp struct {
+		UserMfaByPk *struct {
+			Secret      string     `json:"secret"`
+			ConfirmedAt *time.Time `json:"confirmed_at"`
+		} `json:"user_mfa_by_pk"`
+	}
+
+	err := s.hasura.execute(`
+        query ($userId: uuid!) {
+          user_mfa_by_pk(user_id: $userId) {
+            secret
+            confirmed_at
+          }
+        }
+        `, map[string]interface{}{"userId": userID}, &enrollResp)
+	if err != nil {
+		return false, err
+	}
+
+	// MFA was reset after the challenge was issued
+	enrollment := enrollResp.UserMfaByPk
+	if enrollment == nil || enrollment.ConfirmedAt == nil {
+		return false, ErrInvalidMFAToken
+	}
+
+	if step, ok := auth.ValidateTOTP(enrollment.Secret, code, time.Now(), totpSkew); ok {
+		var response struct {
+			UpdateUserMfa struct {
+				AffectedRows int `json:"affected_rows"`
+			} `json:"update_user_mfa"`
+		}
+
+		// Each time step is accepted once, so an observed code cannot be
+		// replayed
+		err := s.hasura.execute(`
+        mutation ($userId: uuid!, $step: bigint!) {
+          update_user_mfa(where: {user_id: {_eq: $userId}, last_used_step: {_lt: $step}}, _set: {last_used_step: $step}) {
+            affected_rows
+          }
+        }
+        `, map[string]interface{}{"userId": userID, "step": step}, &response)
+		if err != nil {
+			return false, err
+		}
+
+		return response.UpdateUserMfa.AffectedRows > 0, nil
+	}
+
+	var response struct {
+		UpdateMfaRecoveryCodes struct {
+			AffectedRows int `json:"affected_rows"`
+		} `json:"update_mfa_recovery_codes"`
+	}
+
+	err = s.hasura.execute(`
+        mutation ($userId: uuid!, $codeHash: String!, $now: timestamptz!) {
+          update_mfa_recovery_codes(where: {user_id: {_eq: $userId}, code_hash: {_eq: $codeHash}, used_at: {_is_null: true}}, _set: {used_at: $now}) {
+            affected_rows
+          }
+        }
+        `, map[string]interface{}{
+		"userId":   userID,
+		"codeHash": auth.HashOpaqueToken(auth.NormalizeRecoveryCode(code)),
+		"now":      time.Now().UTC(),
+	}, &response)
+	if err != nil {
+		return false, err
+	}
+
+	return response.UpdateMfaRecoveryCodes.AffectedRows > 0, nil
+}
+
+// newRecoveryCodes generates a set of recovery codes and the rows storing
+// their hashes
+func (s *MFAService) newRecoveryCodes(userID uuid.UUID) ([]string, []map[string]interface{}, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	objects := make([]map[string]interface{}, 0, recoveryCodeCount)
+
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := auth.GenerateRecoveryCode()
+		if err != nil {
+			return nil, nil, err
+		}
+		codes = append(codes, code)
+		objects = append(objects, map[string]interface{}{
+			"user_id":   userID,
+			"code_hash": auth.HashOpaqueToken(auth.NormalizeRecoveryCode(code)),
+		})
+	}
+
+	return codes, objects, nil
+}
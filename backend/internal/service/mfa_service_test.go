@@ -0,0 +1,142 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+	"todo-app/backend/internal/auth"
+
+	"github.com/google/uuid"
+)
+
+func newTestMFAService(client *HasuraClient) *MFAService {
+	return NewMFAService(client, newTestAccountService(client, &recordingMailer{}), MFAOptions{
+		Issuer:       "Todo App",
+		ChallengeTTL: 5 * time.Minute,
+		MaxAttempts:  5,
+	})
+}
+
+func TestMFAService_Verify(t *testing.T) {
+	userID := uuid.New()
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		t.Fatalf("failed to generate secret: %v", err)
+	}
+	code, err := auth.TOTPCode(secret, time.Now())
+	if err != nil {
+		t.Fatalf("failed to generate code: %v", err)
+	}
+
+	challenge := fmt.Sprintf(`{"data":{"update_user_tokens":{"returning":[{"user_id":"%s"}]}}}`, userID)
+	enrollment := fmt.Sprintf(`{"data":{"user_mfa_by_pk":{"secret":"%s","confirmed_at":"2026-01-01T00:00:00Z"}}}`, secret)
+
+	t.Run("expired or exhausted challenge", func(t *testing.T) {
+		client, shutdown := newMockHasuraClient(t, []mockResponse{
+			{body: `{"data":{"update_user_tokens":{"returning":[]}}}`},
+		})
+		defer shutdown()
+
+		_, err := newTestMFAService(client).Verify("stale", code)
+		if !errors.Is(err, ErrInvalidMFAToken) {
+			t.Fatalf("expected ErrInvalidMFAToken, got %v", err)
+		}
+	})
+
+	t.Run("valid code", func(t *testing.T) {
+		client, shutdown := newMockHasuraClient(t, []mockResponse{
+			{body: challenge},
+			{body: enrollment},
+			{body: `{"data":{"update_user_mfa":{"affected_rows":1}}}`},
+			{body: fmt.Sprintf(`{"data":{"update_user_tokens":{"returning":[{"user_id":"%s","purpose":"mfa_challenge","email":null}]}}}`, userID)},
+		})
+		defer shutdown()
+
+		got, err := newTestMFAService(client).Verify("fresh", code)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if got != userID {
+			t.Fatalf("expected user %s, got %s", userID, got)
+		}
+	})
+
+	t.Run("replayed code is rejected", func(t *testing.T) {
+		var maxAttempts interface{}
+		client, shutdown := newMockHasuraClient(t, []mockResponse{
+			{respond: func(vars map[string]interface{}) string {
+				maxAttempts = vars["maxAttempts"]
+				return challenge
+			}},
+			{body: enrollment},
+			{body: `{"data":{"update_user_mfa":{"affected_rows":0}}}`},
+		})
+		defer shutdown()
+
+		_, err := newTestMFAService(client).Verify("fresh", code)
+		if !errors.Is(err, ErrInvalidMFACode) {
+			t.Fatalf("expected ErrInvalidMFACode, got %v", err)
+		}
+		// The attempt was taken only while attempts remained
+		if maxAttempts != float64(5) {
+			t.Fatalf("expected the attempt to be capped, got %v", maxAttempts)
+		}
+	})
+
+	t.Run("recovery code", func(t *testing.T) {
+		client, shutdown := newMockHasuraClient(t, []mockResponse{
+			{body: challenge},
+			{body: enrollment},
+			{body: `{"data":{"update_mfa_recovery_codes":{"affected_rows":1}}}`},
+			{body: fmt.Sprintf(`{"data":{"update_user_tokens":{"returning":[{"user_id":"%s","purpose":"mfa_challenge","email":null}]}}}`, userID)},
+		})
+		defer shutdown()
+
+		if _, err := newTestMFAService(client).Verify("fresh", "ABCD-EFGH-IJKL-MNOP"); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	})
+}
+
+func TestMFAService_ConfirmEnrollment(t *testing.T) {
+	userID := uuid.New()
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		t.Fatalf("failed to generate secret: %v", err)
+	}
+	pending := fmt.Sprintf(`{"data":{"user_mfa_by_pk":{"secret":"%s","confirmed_at":null}}}`, secret)
+
+	t.Run("wrong code", func(t *testing.T) {
+		client, shutdown := newMockHasuraClient(t, []mockResponse{{body: pending}})
+		defer shutdown()
+
+		_, err := newTestMFAService(client).ConfirmEnrollment(userID, uuid.New(), "000000")
+		if !errors.Is(err, ErrInvalidMFACode) {
+			t.Fatalf("expected ErrInvalidMFACode, got %v", err)
+		}
+	})
+
+	t.Run("issues recovery codes", func(t *testing.T) {
+		code, err := auth.TOTPCode(secret, time.Now())
+		if err != nil {
+			t.Fatalf("failed to generate code: %v", err)
+		}
+
+		client, shutdown := newMockHasuraClient(t, []mockResponse{
+			{body: pending},
+			{body: `{"data":{"update_user_mfa":{"affected_rows":1},"update_sessions":{"affected_rows":1},"delete_mfa_recovery_codes":{"affected_rows":0},"insert_mfa_recovery_codes":{"affected_rows":10}}}`},
+		})
+		defer shutdown()
+
+		codes, err := newTestMFAService(client).ConfirmEnrollment(userID, uuid.New(), code)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(codes) != recoveryCodeCount {
+			t.Fatalf("expected %d recovery codes, got %d", recoveryCodeCount, len(codes))
+		}
+	})
+}
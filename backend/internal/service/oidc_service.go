@@ -0,0 +1,537 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"todo-app/backend/internal/auth"
+	"todo-app/backend/internal/model"
+	"todo-app/backend/internal/oidc"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrUnknownOIDCProvider = errors.New("unknown identity provider")
+	ErrInvalidOIDCState    = errors.New("invalid or expired login state")
+	ErrOIDCVerification    = errors.New("identity provider login failed")
+	ErrOIDCEmailRequired   = errors.New("identity provider did not confirm an email address")
+	ErrOIDCAccountExists   = errors.New("an account with this email already exists; sign in and link the identity provider")
+	ErrOIDCIdentityInUse   = errors.New("identity provider account is linked to another user")
+)
+
+// defaultOIDCRole is given to provisioned users no role mapping matches
+const defaultOIDCRole = "user"
+
+// RoleMapping gives users whose role claim contains Value the app role Role
+type RoleMapping struct {
+	Value string
+	Role  string
+}
+
+// OIDCProvider is an identity provider users can sign in with
+type OIDCProvider struct {
+	// Name identifies the provider in URLs and in linked identities
+	Name     string
+	Provider *oidc.Provider
+	// Trusted providers may link an unknown account to the user with the
+	// same verified email. Accounts at other providers are linked only by a
+	// signed-in user.
+	Trusted bool
+	// RoleClaim is the ID token claim holding the user's groups or roles.
+	// When set, the user's role follows the provider on every login;
+	// otherwise roles are managed in the app.
+	RoleClaim string
+	// RoleMappings are tried in order; the first match wins
+	RoleMappings []RoleMapping
+}
+
+type OIDCOptions struct {
+	// StateTTL is how long the user has to sign in at the provider
+	StateTTL time.Duration
+	// PasswordHasher hashes the random passwords of provisioned users. It
+	// defaults to Argon2id with auth.DefaultArgon2Params.
+	PasswordHasher *auth.PasswordHasher
+}
+
+// OIDCService signs users in through OpenID Connect providers, linking
+// provider accounts to users and creating users on first login
+type OIDCService struct {
+	hasura    *HasuraClient
+	audit     *AuditService
+	providers map[string]*OIDCProvider
+	names     []string
+	opts      OIDCOptions
+}
+
+func NewOIDCService(hasura *HasuraClient, providers []OIDCProvider, audit *AuditService, opts OIDCOptions) *OIDCService {
+	if opts.PasswordHasher == nil {
+		opts.PasswordHasher = auth.NewPasswordHasher(auth.DefaultArgon2Params)
+	}
+	s := &OIDCService{
+		hasura:    hasura,
+		audit:     audit,
+		providers: make(map[string]*OIDCProvider, len(providers)),
+		opts:      opts,
+	}
+	for i := range providers {
+		s.providers[providers[i].Name] = &providers[i]
+		s.names = append(s.names, providers[i].Name)
+	}
+	return s
+}
+
+// Providers lists the configured provider names
+func (s *OIDCService) Providers() []string {
+	return s.names
+}
+
+// BeginLogin returns the provider URL to send the user to
+func (s *OIDCService) BeginLogin(ctx context.Context, providerName string) (string, error) {
+	return s.begin(ctx, providerName, nil)
+}
+
+// BeginLink returns the provider URL to send a signed-in user to, so the
+// provider account they sign in with is linked to them
+func (s *OIDCService) BeginLink(ctx context.Context, providerName string, userID uuid.UUID) (string, error) {
+	return s.begin(ctx, providerName, &userID)
+}
+
+// begin stores a pending login, for linking to userID when set
+func (s *OIDCService) begin(ctx context.Context, providerName string, userID *uuid.UUID) (string, error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return "", ErrUnknownOIDCProvider
+	}
+
+	state, err := oidc.RandomString()
+	if err != nil {
+		return "", err
+	}
+	nonce, err := oidc.RandomString()
+	if err != nil {
+		return "", err
+	}
+	verifier, challenge, err := oidc.NewPKCE()
+	if err != nil {
+		return "", err
+	}
+
+	authURL, err := provider.Provider.AuthCodeURL(ctx, state, nonce, challenge)
+	if err != nil {
+		return "", err
+	}
+
+	var response struct {
+		InsertOidcLoginStatesOne struct {
+			StateHash string `json:"state_hash"`
+		} `json:"insert_oidc_login_states_one"`
+	}
+
+	err = s.hasura.execute(`
+        mutation ($stateHash: String!, $provider: String!, $codeVerifier: String!, $nonce: String!, $userId: uuid, $expiresAt: timestamptz!) {
+          insert_oidc_login_states_one(object: {state_hash: $stateHash, provider: $provider, code_verifier: $codeVerifier, nonce: $nonce, user_id: $userId, expires_at: $expiresAt}) {
+            state_hash
+          }
+        }
+        `, map[string]interface{}{
+		"stateHash":    auth.HashOpaqueToken(state),
+		"provider":     provider.Name,
+		"codeVerifier": verifier,
+		"nonce":        nonce,
+		"userId":       userID,
+		"expiresAt":    time.Now().Add(s.opts.StateTTL).UTC(),
+	}, &response)
+	if err != nil {
+		return "", err
+	}
+
+	return authURL, nil
+}
+
+// FinishLogin redeems the code the provider redirected back with and returns
+// the user it signs in, and whether the provider reports a multi-factor
+// login. A login begun with BeginLink signs in the user it was begun by.
+func (s *OIDCService) FinishLogin(ctx context.Context, code, state string) (uuid.UUID, bool, error) {
+	var stateResp struct {
+		DeleteOidcLoginStates struct {
+			Returning []struct {
+				Provider     string     `json:"provider"`
+				CodeVerifier string     `json:"code_verifier"`
+				Nonce        string     `json:"nonce"`
+				UserID       *uuid.UUID `json:"user_id"`
+			} `json:"returning"`
+		} `json:"delete_oidc_login_states"`
+	}
+
+	err := s.hasura.execute(`
+        mutation ($stateHash: String!, $now: timestamptz!) {
+          delete_oidc_login_states(where: {state_hash: {_eq: $stateHash}, expires_at: {_gt: $now}}) {
+            returning {
+              provider
+              code_verifier
+              nonce
+              user_id
+            }
+          }
+        }
+        `, map[string]interface{}{"stateHash": auth.HashOpaqueToken(state), "now": time.Now().UTC()}, &stateResp)
+	if err != nil {
+		return uuid.Nil, false, err
+	}
+
+	if len(stateResp.DeleteOidcLoginStates.Returning) == 0 {
+		return uuid.Nil, false, ErrInvalidOIDCState
+	}
+	pending := stateResp.DeleteOidcLoginStates.Returning[0]
+
+	provider, ok := s.providers[pending.Provider]
+	if !ok {
+		return uuid.Nil, false, ErrUnknownOIDCProvider
+	}
+
+	token, err := provider.Provider.Exchange(ctx, code, pending.CodeVerifier, pending.Nonce)
+	if err != nil {
+		if errors.Is(err, oidc.ErrTokenExchange) || errors.Is(err, oidc.ErrInvalidIDToken) {
+			return uuid.Nil, false, fmt.Errorf("%w: %v", ErrOIDCVerification, err)
+		}
+		return uuid.Nil, false, err
+	}
+
+	userID, err := s.linkIdentity(provider, token, pending.UserID)
+	if err != nil {
+		return uuid.Nil, false, err
+	}
+
+	mfaVerified := false
+	for _, method := range token.AMR {
+		if method == "mfa" {
+			mfaVerified = true
+		}
+	}
+
+	return userID, mfaVerified, nil
+}
+
+// PurgeExpiredStates removes logins that were never completed
+func (s *OIDCService) PurgeExpiredStates() (int, error) {
+	var response struct {
+		DeleteOidcLoginStates struct {
+			AffectedRows int `json:"affected_rows"`
+		} `json:"delete_oidc_login_states"`
+	}
+
+	err := s.hasura.execute(`
+        mutation ($now: timestamptz!) {
+          delete_oidc_login_states(where: {expires_at: {_lt: $now}}) {
+            affected_rows
+          }
+        }
+        `, map[string]interface{}{"now": time.Now().UTC()}, &response)
+	if err != nil {
+		return 0, err
+	}
+
+	return response.DeleteOidcLoginStates.AffectedRows, nil
+}
+
+// linkIdentity finds the user a provider account belongs to. An unknown
+// account is linked to linkTo when set. Otherwise it is linked to the user
+// with the same email address, which the provider must have verified, if
+// the provider is trusted, or to a new user.
+func (s *OIDCService) linkIdentity(provider *OIDCProvider, token *oidc.IDToken, linkTo *uuid.UUID) (uuid.UUID, error) {
+	var response struct {
+		UserIdentities []struct {
+			UserID uuid.UUID `json:"user_id"`
+			User   struct {
+				Role string `json:"role"`
+			} `json:"user"`
+		} `json:"user_identities"`
+	}
+
+	err := s.hasura.execute(`
+        query ($provider: String!, $subject: String!) {
+          user_identities(where: {provider: {_eq: $provider}, subject: {_eq: $subject}}, limit: 1) {
+            user_id
+            user {
+              role
+            }
+          }
+        }
+        `, map[string]interface{}{"provider": provider.Name, "subject": token.Subject}, &response)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	role := provider.mapRole(token)
+
+	if len(response.UserIdentities) > 0 {
+		identity := response.UserIdentities[0]
+		if linkTo != nil && *linkTo != identity.UserID {
+			return uuid.Nil, ErrOIDCIdentityInUse
+		}
+		if err := s.touchIdentity(provider.Name, token); err != nil {
+			return uuid.Nil, err
+		}
+		if err := s.syncRole(provider, identity.UserID, identity.User.Role, role); err != nil {
+			return uuid.Nil, err
+		}
+		return identity.UserID, nil
+	}
+
+	if linkTo != nil {
+		user, err := s.findUser(map[string]interface{}{"id": map[string]interface{}{"_eq": *linkTo}})
+		if err != nil {
+			return uuid.Nil, err
+		}
+		if user == nil {
+			return uuid.Nil, ErrUserNotFound
+		}
+		verified := token.EmailVerified && strings.EqualFold(token.Email, user.Email)
+		if err := s.addIdentity(user.ID, provider.Name, token, verified); err != nil {
+			return uuid.Nil, err
+		}
+		if err := s.syncRole(provider, user.ID, user.Role, role); err != nil {
+			return uuid.Nil, err
+		}
+		return user.ID, nil
+	}
+
+	if token.Email == "" || !token.EmailVerified {
+		return uuid.Nil, ErrOIDCEmailRequired
+	}
+
+	user, err := s.findUser(map[string]interface{}{"email": map[string]interface{}{"_ilike": likeEscaper.Replace(token.Email)}})
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	if user != nil {
+		if !provider.Trusted {
+			return uuid.Nil, ErrOIDCAccountExists
+		}
+		if err := s.addIdentity(user.ID, provider.Name, token, true); err != nil {
+			return uuid.Nil, err
+		}
+		if err := s.syncRole(provider, user.ID, user.Role, role); err != nil {
+			return uuid.Nil, err
+		}
+		return user.ID, nil
+	}
+
+	if role == "" {
+		role = defaultOIDCRole
+	}
+	return s.provisionUser(provider.Name, token, role)
+}
+
+type oidcUser struct {
+	ID    uuid.UUID `json:"id"`
+	Email string    `json:"email"`
+	Role  string    `json:"role"`
+}
+
+// findUser returns the user matching where, or nil
+func (s *OIDCService) findUser(where map[string]interface{}) (*oidcUser, error) {
+	var response struct {
+		Users []oidcUser `json:"users"`
+	}
+
+	err := s.hasura.execute(`
+        query ($where: users_bool_exp!) {
+          users(where: $where, limit: 1) {
+            id
+            email
+            role
+          }
+        }
+        `, map[string]interface{}{"where": where}, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(response.Users) == 0 {
+		return nil, nil
+	}
+	return &response.Users[0], nil
+}
+
+// addIdentity links a provider account to an existing user. When the
+// provider has verified the user's address, the user's email counts as
+// verified too.
+func (s *OIDCService) addIdentity(userID uuid.UUID, providerName string, token *oidc.IDToken, emailVerified bool) error {
+	var response struct {
+		InsertUserIdentitiesOne struct {
+			ID uuid.UUID `json:"id"`
+		} `json:"insert_user_identities_one"`
+	}
+
+	var email *string
+	if token.Email != "" {
+		email = &token.Email
+	}
+
+	now := time.Now().UTC()
+	err := s.hasura.execute(`
+        mutation ($userId: uuid!, $provider: String!, $subject: String!, $email: String, $now: timestamptz!) {
+          insert_user_identities_one(object: {user_id: $userId, provider: $provider, subject: $subject, email: $email, last_login_at: $now}) {
+            id
+          }
+        }
+        `, map[string]interface{}{
+		"userId":   userID,
+		"provider": providerName,
+		"subject":  token.Subject,
+		"email":    email,
+		"now":      now,
+	}, &response)
+	if err != nil || !emailVerified {
+		return err
+	}
+
+	var verifyResp struct {
+		UpdateUsers struct {
+			AffectedRows int `json:"affected_rows"`
+		} `json:"update_users"`
+	}
+
+	return s.hasura.execute(`
+        mutation ($userId: uuid!, $now: timestamptz!) {
+          update_users(where: {id: {_eq: $userId}, email_verified_at: {_is_null: true}}, _set: {email_verified_at: $now}) {
+            affected_rows
+          }
+        }
+        `, map[string]interface{}{"userId": userID, "now": now}, &verifyResp)
+}
+
+// provisionUser creates a user for a provider account. The password is
+// random and never shown, so the user signs in through the provider until
+// they set one with a password reset.
+func (s *OIDCService) provisionUser(providerName string, token *oidc.IDToken, role string) (uuid.UUID, error) {
+	password, err := auth.GenerateOpaqueToken()
+	if err != nil {
+		return uuid.Nil, err
+	}
+	passwordHash, err := s.opts.PasswordHasher.Hash(password)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	var response struct {
+		InsertUsersOne struct {
+			ID uuid.UUID `json:"id"`
+		} `json:"insert_users_one"`
+	}
+
+	now := time.Now().UTC()
+	err = s.hasura.execute(`
+        mutation ($email: String!, $passwordHash: String!, $role: String!, $now: timestamptz!, $provider: String!, $subject: String!) {
+          insert_users_one(object: {
+            email: $email,
+            password_hash: $passwordHash,
+            role: $role,
+            email_verified_at: $now,
+            user_identities: {data: [{provider: $provider, subject: $subject, email: $email, last_login_at: $now}]}
+          }) {
+            id
+          }
+        }
+        `, map[string]interface{}{
+		"email":        token.Email,
+		"passwordHash": passwordHash,
+		"role":         role,
+		"now":          now,
+		"provider":     providerName,
+		"subject":      token.Subject,
+	}, &response)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	return response.InsertUsersOne.ID, nil
+}
+
+// touchIdentity records a login and the email the provider now reports
+func (s *OIDCService) touchIdentity(providerName string, token *oidc.IDToken) error {
+	var response struct {
+		UpdateUserIdentities struct {
+			AffectedRows int `json:"affected_rows"`
+		} `json:"update_user_identities"`
+	}
+
+	var email *string
+	if token.Email != "" {
+		email = &token.Email
+	}
+
+	return s.hasura.execute(`
+        mutation ($provider: String!, $subject: String!, $email: String, $now: timestamptz!) {
+          update_user_identities(where: {provider: {_eq: $provider}, subject: {_eq: $subject}}, _set: {email: $email, last_login_at: $now}) {
+            affected_rows
+          }
+        }
+        `, map[string]interface{}{
+		"provider": providerName,
+		"subject":  token.Subject,
+		"email":    email,
+		"now":      time.Now().UTC(),
+	}, &response)
+}
+
+// syncRole gives the user the role the provider maps them to, if it
+// differs from their current one, and records the change in the audit log
+func (s *OIDCService) syncRole(provider *OIDCProvider, userID uuid.UUID, current, role string) error {
+	if role == "" || role == current {
+		return nil
+	}
+
+	if err := s.setRole(userID, role); err != nil {
+		return err
+	}
+
+	return s.audit.Record(model.AuditLog{
+		Action: model.AuditActionUserRoleSync,
+		UserID: userID,
+		Details: map[string]interface{}{
+			"provider": provider.Name,
+			"from":     current,
+			"to":       role,
+		},
+	})
+}
+
+func (s *OIDCService) setRole(userID uuid.UUID, role string) error {
+	var response struct {
+		UpdateUsersByPk *struct {
+			ID uuid.UUID `json:"id"`
+		} `json:"update_users_by_pk"`
+	}
+
+	return s.hasura.execute(`
+        mutation ($id: uuid!, $role: String!) {
+          update_users_by_pk(pk_columns: {id: $id}, _set: {role: $role}) {
+            id
+          }
+        }
+        `, map[string]interface{}{"id": userID, "role": role}, &response)
+}
+
+// mapRole returns the role the provider's claims give the user, or "" when
+// the provider does not manage roles
+func (p *OIDCProvider) mapRole(token *oidc.IDToken) string {
+	if p.RoleClaim == "" {
+		return ""
+	}
+
+	values := oidc.StringsClaim(token.Claims, p.RoleClaim)
+	for _, mapping := range p.RoleMappings {
+		for _, value := range values {
+			if value == mapping.Value {
+				return mapping.Role
+			}
+		}
+	}
+	return defaultOIDCRole
+}
@@ -0,0 +1,303 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+	"todo-app/backend/internal/oidc"
+	"todo-app/backend/internal/oidc/oidctest"
+
+	"github.com/google/uuid"
+)
+
+func newTestOIDCService(t *testing.T, client *HasuraClient, mock *oidctest.Provider, trusted bool) *OIDCService {
+	t.Helper()
+
+	return NewOIDCService(client, []OIDCProvider{{
+		Name: "company",
+		Provider: oidc.NewProvider(oidc.Config{
+			Issuer:       mock.Issuer,
+			ClientID:     mock.ClientID,
+			ClientSecret: mock.ClientSecret,
+			RedirectURL:  "http://localhost:3000/auth/oidc/callback",
+			Scopes:       []string{"email", "profile"},
+		}, nil),
+		Trusted:      trusted,
+		RoleClaim:    "groups",
+		RoleMappings: []RoleMapping{{Value: "todo-admins", Role: "admin"}},
+	}}, NewAuditService(client), OIDCOptions{StateTTL: 10 * time.Minute})
+}
+
+// oidcLoginResponses answers BeginLogin's state insert and FinishLogin's
+// state lookup, handing back what the service stored
+func oidcLoginResponses() []mockResponse {
+	var stored map[string]interface{}
+
+	return []mockResponse{
+		{respond: func(variables map[string]interface{}) string {
+			stored = variables
+			return `{"data":{"insert_oidc_login_states_one":{"state_hash":"hash"}}}`
+		}},
+		{respond: func(map[string]interface{}) string {
+			userID, _ := json.Marshal(stored["userId"])
+			return fmt.Sprintf(`{"data":{"delete_oidc_login_states":{"returning":[{"provider":%q,"code_verifier":%q,"nonce":%q,"user_id":%s}]}}}`,
+				stored["provider"], stored["codeVerifier"], stored["nonce"], userID)
+		}},
+	}
+}
+
+func TestOIDCService_Login(t *testing.T) {
+	ctx := context.Background()
+	mock, err := oidctest.New("todo-app", "client-secret")
+	if err != nil {
+		t.Fatalf("failed to start provider: %v", err)
+	}
+	defer mock.Close()
+
+	login := func(t *testing.T, service *OIDCService) (uuid.UUID, bool, error) {
+		t.Helper()
+
+		authURL, err := service.BeginLogin(ctx, "company")
+		if err != nil {
+			t.Fatalf("failed to begin login: %v", err)
+		}
+		code, state, err := mock.Authorize(authURL)
+		if err != nil {
+			t.Fatalf("failed to authorize: %v", err)
+		}
+		return service.FinishLogin(ctx, code, state)
+	}
+
+	t.Run("first login provisions a user", func(t *testing.T) {
+		userID := uuid.New()
+		mock.SignIn(map[string]interface{}{
+			"sub":            "emp-1",
+			"email":          "new@example.com",
+			"email_verified": true,
+			"groups":         []string{"todo-admins"},
+		})
+
+		var role interface{}
+		client, shutdown := newMockHasuraClient(t, append(oidcLoginResponses(),
+			mockResponse{body: `{"data":{"user_identities":[]}}`},
+			mockResponse{body: `{"data":{"users":[]}}`},
+			mockResponse{respond: func(variables map[string]interface{}) string {
+				role = variables["role"]
+				return fmt.Sprintf(`{"data":{"insert_users_one":{"id":"%s"}}}`, userID)
+			}},
+		))
+		defer shutdown()
+
+		got, mfaVerified, err := login(t, newTestOIDCService(t, client, mock, false))
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if got != userID {
+			t.Fatalf("expected user %s, got %s", userID, got)
+		}
+		if mfaVerified {
+			t.Fatalf("expected no MFA without an amr claim")
+		}
+		if role != "admin" {
+			t.Fatalf("expected mapped role admin, got %v", role)
+		}
+	})
+
+	t.Run("returning user follows the provider's role", func(t *testing.T) {
+		userID := uuid.New()
+		mock.SignIn(map[string]interface{}{
+			"sub":    "emp-1",
+			"email":  "new@example.com",
+			"groups": []string{"staff"},
+			"amr":    []string{"pwd", "mfa"},
+		})
+
+		var role interface{}
+		var audited map[string]interface{}
+		client, shutdown := newMockHasuraClient(t, append(oidcLoginResponses(),
+			mockResponse{body: fmt.Sprintf(`{"data":{"user_identities":[{"user_id":"%s","user":{"role":"admin"}}]}}`, userID)},
+			mockResponse{body: `{"data":{"update_user_identities":{"affected_rows":1}}}`},
+			mockResponse{respond: func(variables map[string]interface{}) string {
+				role = variables["role"]
+				return fmt.Sprintf(`{"data":{"update_users_by_pk":{"id":"%s"}}}`, userID)
+			}},
+			mockResponse{respond: func(variables map[string]interface{}) string {
+				audited = variables["object"].(map[string]interface{})
+				return fmt.Sprintf(`{"data":{"insert_audit_logs_one":{"id":"%s"}}}`, uuid.New())
+			}},
+		))
+		defer shutdown()
+
+		got, mfaVerified, err := login(t, newTestOIDCService(t, client, mock, false))
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if got != userID {
+			t.Fatalf("expected user %s, got %s", userID, got)
+		}
+		if !mfaVerified {
+			t.Fatalf("expected the provider's MFA to count")
+		}
+		if role != "user" {
+			t.Fatalf("expected role to be demoted to user, got %v", role)
+		}
+		if audited["action"] != "user.role_sync" || audited["user_id"] != userID.String() {
+			t.Fatalf("expected the role change to be audited, got %v", audited)
+		}
+	})
+
+	t.Run("trusted provider links an existing account by verified email", func(t *testing.T) {
+		userID := uuid.New()
+		mock.SignIn(map[string]interface{}{
+			"sub":            "emp-2",
+			"email":          "Existing_1@Example.com",
+			"email_verified": true,
+		})
+
+		var where interface{}
+		client, shutdown := newMockHasuraClient(t, append(oidcLoginResponses(),
+			mockResponse{body: `{"data":{"user_identities":[]}}`},
+			mockResponse{respond: func(variables map[string]interface{}) string {
+				where = variables["where"]
+				return fmt.Sprintf(`{"data":{"users":[{"id":"%s","email":"existing_1@example.com","role":"user"}]}}`, userID)
+			}},
+			mockResponse{body: fmt.Sprintf(`{"data":{"insert_user_identities_one":{"id":"%s"}}}`, uuid.New())},
+			mockResponse{body: `{"data":{"update_users":{"affected_rows":0}}}`},
+		))
+		defer shutdown()
+
+		got, _, err := login(t, newTestOIDCService(t, client, mock, true))
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if got != userID {
+			t.Fatalf("expected user %s, got %s", userID, got)
+		}
+		email := where.(map[string]interface{})["email"].(map[string]interface{})
+		if email["_ilike"] != `Existing\_1@Example.com` {
+			t.Fatalf("expected a case-insensitive exact match, got %v", email)
+		}
+	})
+
+	t.Run("untrusted provider does not link by email", func(t *testing.T) {
+		mock.SignIn(map[string]interface{}{
+			"sub":            "emp-2",
+			"email":          "existing@example.com",
+			"email_verified": true,
+		})
+
+		client, shutdown := newMockHasuraClient(t, append(oidcLoginResponses(),
+			mockResponse{body: `{"data":{"user_identities":[]}}`},
+			mockResponse{body: fmt.Sprintf(`{"data":{"users":[{"id":"%s","email":"existing@example.com","role":"user"}]}}`, uuid.New())},
+		))
+		defer shutdown()
+
+		_, _, err := login(t, newTestOIDCService(t, client, mock, false))
+		if !errors.Is(err, ErrOIDCAccountExists) {
+			t.Fatalf("expected ErrOIDCAccountExists, got %v", err)
+		}
+	})
+
+	t.Run("signed-in user links an account", func(t *testing.T) {
+		userID := uuid.New()
+		mock.SignIn(map[string]interface{}{
+			"sub":            "emp-4",
+			"email":          "other@example.com",
+			"email_verified": true,
+		})
+
+		var linked interface{}
+		client, shutdown := newMockHasuraClient(t, append(oidcLoginResponses(),
+			mockResponse{body: `{"data":{"user_identities":[]}}`},
+			mockResponse{body: fmt.Sprintf(`{"data":{"users":[{"id":"%s","email":"me@example.com","role":"user"}]}}`, userID)},
+			// The provider's address differs, so the user's stays unverified
+			mockResponse{respond: func(variables map[string]interface{}) string {
+				linked = variables["userId"]
+				return fmt.Sprintf(`{"data":{"insert_user_identities_one":{"id":"%s"}}}`, uuid.New())
+			}},
+		))
+		defer shutdown()
+
+		service := newTestOIDCService(t, client, mock, false)
+		authURL, err := service.BeginLink(ctx, "company", userID)
+		if err != nil {
+			t.Fatalf("failed to begin link: %v", err)
+		}
+		code, state, err := mock.Authorize(authURL)
+		if err != nil {
+			t.Fatalf("failed to authorize: %v", err)
+		}
+		got, _, err := service.FinishLogin(ctx, code, state)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if got != userID || linked != userID.String() {
+			t.Fatalf("expected the account linked to %s, got %s (%v)", userID, got, linked)
+		}
+	})
+
+	t.Run("account linked to another user is not relinked", func(t *testing.T) {
+		mock.SignIn(map[string]interface{}{"sub": "emp-4"})
+
+		client, shutdown := newMockHasuraClient(t, append(oidcLoginResponses(),
+			mockResponse{body: fmt.Sprintf(`{"data":{"user_identities":[{"user_id":"%s","user":{"role":"user"}}]}}`, uuid.New())},
+		))
+		defer shutdown()
+
+		service := newTestOIDCService(t, client, mock, false)
+		authURL, err := service.BeginLink(ctx, "company", uuid.New())
+		if err != nil {
+			t.Fatalf("failed to begin link: %v", err)
+		}
+		code, state, err := mock.Authorize(authURL)
+		if err != nil {
+			t.Fatalf("failed to authorize: %v", err)
+		}
+		if _, _, err := service.FinishLogin(ctx, code, state); !errors.Is(err, ErrOIDCIdentityInUse) {
+			t.Fatalf("expected ErrOIDCIdentityInUse, got %v", err)
+		}
+	})
+
+	t.Run("unverified email is not linked", func(t *testing.T) {
+		mock.SignIn(map[string]interface{}{
+			"sub":            "emp-3",
+			"email":          "existing@example.com",
+			"email_verified": false,
+		})
+
+		client, shutdown := newMockHasuraClient(t, append(oidcLoginResponses(),
+			mockResponse{body: `{"data":{"user_identities":[]}}`},
+		))
+		defer shutdown()
+
+		_, _, err := login(t, newTestOIDCService(t, client, mock, false))
+		if !errors.Is(err, ErrOIDCEmailRequired) {
+			t.Fatalf("expected ErrOIDCEmailRequired, got %v", err)
+		}
+	})
+
+	t.Run("unknown state", func(t *testing.T) {
+		client, shutdown := newMockHasuraClient(t, []mockResponse{
+			{body: `{"data":{"delete_oidc_login_states":{"returning":[]}}}`},
+		})
+		defer shutdown()
+
+		_, _, err := newTestOIDCService(t, client, mock, false).FinishLogin(ctx, "code", "forged")
+		if !errors.Is(err, ErrInvalidOIDCState) {
+			t.Fatalf("expected ErrInvalidOIDCState, got %v", err)
+		}
+	})
+
+	t.Run("unknown provider", func(t *testing.T) {
+		client, shutdown := newMockHasuraClient(t, nil)
+		defer shutdown()
+
+		_, err := newTestOIDCService(t, client, mock, false).BeginLogin(ctx, "other")
+		if !errors.Is(err, ErrUnknownOIDCProvider) {
+			t.Fatalf("expected ErrUnknownOIDCProvider, got %v", err)
+		}
+	})
+}
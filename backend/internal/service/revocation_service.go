@@ -0,0 +1,334 @@
+package service
+
+import (
+	"time"
+	"todo-app/backend/internal/auth"
+	"todo-app/backend/internal/cache"
+	"todo-app/backend/internal/model"
+
+	"github.com/google/uuid"
+)
+
+type RevocationOptions struct {
+	// CacheSize bounds the number of cached lookups
+	CacheSize int
+	// CacheTTL is how long a "not revoked" answer is trusted before
+	// Postgres is asked again, which bounds how long a revocation made on
+	// another instance can go unnoticed
+	CacheTTL time.Duration
+	// AccessTokenTTL bounds how long an ended session must be remembered
+	AccessTokenTTL time.Duration
+}
+
+// RevocationService records revoked access tokens. Individual tokens are
+// revoked by jti, a session by its sid, and revoking a user invalidates every
+// token issued to them before that moment. Tokens of suspended users are
+// refused as well. Lookups are answered from an in-memory LRU and fall back
+// to Postgres on a miss.
+type RevocationService struct {
+	hasura   *HasuraClient
+	tokens   *cache.LRU[string, bool]
+	sessions *cache.LRU[uuid.UUID, bool]
+	users    *cache.LRU[uuid.UUID, userRevocation]
+	opts     RevocationOptions
+}
+
+// userRevocation is what revokes all of a user's tokens: a cutoff before
+// which tokens are revoked, and suspension
+type userRevocation struct {
+	cutoff    time.Time
+	suspended bool
+}
+
+func NewRevocationService(hasura *HasuraClient, opts RevocationOptions) *RevocationService {
+	return &RevocationService{
+		hasura:   hasura,
+		tokens:   cache.NewLRU[string, bool](opts.CacheSize),
+		sessions: cache.NewLRU[uuid.UUID, bool](opts.CacheSize),
+		users:    cache.NewLRU[uuid.UUID, userRevocation](opts.CacheSize),
+		opts:     opts,
+	}
+}
+
+// IsRevoked reports whether the token has been revoked on its own, as part
+// of its session or as part of its user's tokens. Tokens of suspended users
+// fail with ErrUserSuspended.
+func (s *RevocationService) IsRevoked(claims *auth.Claims) (bool, error) {
+	tokenRevoked, tokenCached := s.tokens.Get(claims.ID)
+	sessionRevoked, sessionCached := s.sessions.Get(claims.SessionID)
+	if (tokenCached && tokenRevoked) || (sessionCached && sessionRevoked) {
+		return true, nil
+	}
+
+	user, userCached := s.users.Get(claims.UserID)
+	if userCached && user.suspended {
+		return true, ErrUserSuspended
+	}
+
+	if !tokenCached || !sessionCached || !userCached {
+		var response struct {
+			RevokedTokensByPk *struct {
+				Jti string `json:"jti"`
+			} `json:"revoked_tokens_by_pk"`
+			SessionsByPk *struct {
+				RevokedAt *time.Time `json:"revoked_at"`
+			} `json:"sessions_by_pk"`
+			UsersByPk *struct {
+				TokensRevokedBefore *time.Time `json:"tokens_revoked_before"`
+				Status              string     `json:"status"`
+			} `json:"users_by_pk"`
+		}
+
+		err := s.hasura.execute(`
+        query ($jti: String!, $sessionId: uuid!, $userId: uuid!) {
+          revoked_tokens_by_pk(jti: $jti) {
+            jti
+          }
+          sessions_by_pk(id: $sessionId) {
+            revoked_at
+          }
+          users_by_pk(id: $userId) {
+            tokens_revoked_before
+            status
+          }
+        }
+        `, map[string]interface{}{"jti": claims.ID, "sessionId": claims.SessionID, "userId": claims.UserID}, &response)
+		if err != nil {
+			return false, err
+		}
+
+		tokenRevoked = response.RevokedTokensByPk != nil
+		s.rememberToken(claims, tokenRevoked)
+
+		sessionRevoked = response.SessionsByPk != nil && response.SessionsByPk.RevokedAt != nil
+		s.rememberSession(claims.SessionID, sessionRevoked)
+
+		user = userRevocation{}
+		if response.UsersByPk != nil {
+			if response.UsersByPk.TokensRevokedBefore != nil {
+				user.cutoff = *response.UsersByPk.TokensRevokedBefore
+			}
+			user.suspended = response.UsersByPk.Status == model.UserStatusSuspended
+		}
+		s.users.Set(claims.UserID, user, time.Now().Add(s.opts.CacheTTL))
+
+		if user.suspended {
+			return true, ErrUserSuspended
+		}
+		if tokenRevoked || sessionRevoked {
+			return true, nil
+		}
+	}
+
+	return issuedBefore(claims, user.cutoff), nil
+}
+
+// RevokeToken revokes a single access token until it expires
+func (s *RevocationService) RevokeToken(claims *auth.Claims) error {
+	if claims.ID == "" || claims.ExpiresAt == nil {
+		return nil
+	}
+
+	var response struct {
+		InsertRevokedTokensOne *struct {
+			Jti string `json:"jti"`
+		} `json:"insert_revoked_tokens_one"`
+	}
+
+	err := s.hasura.execute(`
+        mutation ($jti: String!, $userId: uuid!, $expiresAt: timestamptz!) {
+          insert_revoked_tokens_one(
+            object: {jti: $jti, user_id: $userId, expires_at: $expiresAt},
+            on_conflict: {constraint: revoked_tokens_pkey, update_columns: []}
+          ) {
+            jti
+          }
+        }
+        `, map[string]interface{}{
+		"jti":       claims.ID,
+		"userId":    claims.UserID,
+		"expiresAt": claims.ExpiresAt.Time,
+	}, &response)
+	if err != nil {
+		return err
+	}
+
+	s.rememberToken(claims, true)
+	return nil
+}
+
+// RevokeSession ends a session: its refresh tokens stop working and access
+// tokens issued for it are rejected
+func (s *RevocationService) RevokeSession(sessionID uuid.UUID) error {
+	if sessionID == uuid.Nil {
+		return nil
+	}
+
+	now := time.Now().UTC()
+
+	var response struct {
+		UpdateSessions struct {
+			AffectedRows int `json:"affected_rows"`
+		} `json:"update_sessions"`
+		UpdateRefreshTokens struct {
+			AffectedRows int `json:"affected_rows"`
+		} `json:"update_refresh_tokens"`
+	}
+
+	err := s.hasura.execute(`
+        mutation ($sessionId: uuid!, $now: timestamptz!) {
+          update_sessions(where: {id: {_eq: $sessionId}, revoked_at: {_is_null: true}}, _set: {revoked_at: $now}) {
+            affected_rows
+          }
+          update_refresh_tokens(where: {family_id: {_eq: $sessionId}, revoked_at: {_is_null: true}}, _set: {revoked_at: $now}) {
+            affected_rows
+          }
+        }
+        `, map[string]interface{}{"sessionId": sessionID, "now": now}, &response)
+	if err != nil {
+		return err
+	}
+
+	s.rememberSession(sessionID, true)
+	return nil
+}
+
+// RevokeOtherSessions ends every session of the user except keepSessionID
+func (s *RevocationService) RevokeOtherSessions(userID, keepSessionID uuid.UUID) error {
+	now := time.Now().UTC()
+
+	var response struct {
+		UpdateSessions struct {
+			Returning []struct {
+				ID uuid.UUID `json:"id"`
+			} `json:"returning"`
+		} `json:"update_sessions"`
+		UpdateRefreshTokens struct {
+			AffectedRows int `json:"affected_rows"`
+		} `json:"update_refresh_tokens"`
+	}
+
+	err := s.hasura.execute(`
+        mutation ($userId: uuid!, $keepSessionId: uuid!, $now: timestamptz!) {
+          update_sessions(where: {user_id: {_eq: $userId}, id: {_neq: $keepSessionId}, revoked_at: {_is_null: true}}, _set: {revoked_at: $now}) {
+            returning {
+              id
+            }
+          }
+          update_refresh_tokens(where: {user_id: {_eq: $userId}, family_id: {_neq: $keepSessionId}, revoked_at: {_is_null: true}}, _set: {revoked_at: $now}) {
+            affected_rows
+          }
+        }
+        `, map[string]interface{}{"userId": userID, "keepSessionId": keepSessionID, "now": now}, &response)
+	if err != nil {
+		return err
+	}
+
+	for _, session := range response.UpdateSessions.Returning {
+		s.rememberSession(session.ID, true)
+	}
+	return nil
+}
+
+// RevokeUser revokes every access and refresh token issued to the user so far
+func (s *RevocationService) RevokeUser(userID uuid.UUID) error {
+	now := time.Now().UTC()
+
+	var response struct {
+		UpdateUsersByPk *struct {
+			ID uuid.UUID `json:"id"`
+		} `json:"update_users_by_pk"`
+		UpdateRefreshTokens struct {
+			AffectedRows int `json:"affected_rows"`
+		} `json:"update_refresh_tokens"`
+	}
+
+	err := s.hasura.execute(`
+        mutation ($userId: uuid!, $now: timestamptz!) {
+          update_users_by_pk(pk_columns: {id: $userId}, _set: {tokens_revoked_before: $now}) {
+            id
+          }
+          update_refresh_tokens(where: {user_id: {_eq: $userId}, revoked_at: {_is_null: true}}, _set: {revoked_at: $now}) {
+            affected_rows
+          }
+        }
+        `, map[string]interface{}{"userId": userID, "now": now}, &response)
+	if err != nil {
+		return err
+	}
+
+	if response.UpdateUsersByPk == nil {
+		return ErrUserNotFound
+	}
+
+	user, _ := s.users.Get(userID)
+	user.cutoff = now
+	s.users.Set(userID, user, now.Add(s.opts.CacheTTL))
+	return nil
+}
+
+// rememberSuspension records a suspension or reactivation made on this
+// instance, so it applies to this instance's lookups at once. Users not in
+// the cache are looked up anyway.
+func (s *RevocationService) rememberSuspension(userID uuid.UUID, suspended bool) {
+	if user, ok := s.users.Get(userID); ok {
+		user.suspended = suspended
+		s.users.Set(userID, user, time.Now().Add(s.opts.CacheTTL))
+	}
+}
+
+// PurgeExpired forgets revocations of tokens that have expired anyway
+func (s *RevocationService) PurgeExpired() (int, error) {
+	s.tokens.Prune()
+	s.sessions.Prune()
+	s.users.Prune()
+
+	var response struct {
+		DeleteRevokedTokens struct {
+			AffectedRows int `json:"affected_rows"`
+		} `json:"delete_revoked_tokens"`
+	}
+
+	err := s.hasura.execute(`
+        mutation ($now: timestamptz!) {
+          delete_revoked_tokens(where: {expires_at: {_lt: $now}}) {
+            affected_rows
+          }
+        }
+        `, map[string]interface{}{"now": time.Now().UTC()}, &response)
+	if err != nil {
+		return 0, err
+	}
+
+	return response.DeleteRevokedTokens.AffectedRows, nil
+}
+
+// rememberToken caches a lookup. Revocations are kept until the token
+// expires; negative answers only for CacheTTL.
+func (s *RevocationService) rememberToken(claims *auth.Claims, revoked bool) {
+	expiresAt := time.Now().Add(s.opts.CacheTTL)
+	if revoked && claims.ExpiresAt != nil {
+		expiresAt = claims.ExpiresAt.Time
+	}
+	s.tokens.Set(claims.ID, revoked, expiresAt)
+}
+
+// rememberSession caches a session lookup. Ended sessions never come back,
+// so they are kept for as long as an access token can live.
+func (s *RevocationService) rememberSession(sessionID uuid.UUID, revoked bool) {
+	ttl := s.opts.CacheTTL
+	if revoked && s.opts.AccessTokenTTL > ttl {
+		ttl = s.opts.AccessTokenTTL
+	}
+	s.sessions.Set(sessionID, revoked, time.Now().Add(ttl))
+}
+
+// issuedBefore reports whether the token predates the cutoff. iat only has
+// second precision, so a token issued in the same second as the cutoff is
+// treated as revoked.
+func issuedBefore(claims *auth.Claims, cutoff time.Time) bool {
+	if cutoff.IsZero() || claims.IssuedAt == nil {
+		return false
+	}
+	return !claims.IssuedAt.Time.After(cutoff.Truncate(time.Second))
+}
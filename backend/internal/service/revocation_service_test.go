@@ -0,0 +1,95 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+	"todo-app/backend/internal/auth"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+func TestRevocationService_IsRevoked(t *testing.T) {
+	userID := uuid.New()
+	opts := RevocationOptions{CacheSize: 10, CacheTTL: time.Minute}
+
+	newClaims := func(issuedAt time.Time) *auth.Claims {
+		return &auth.Claims{
+			UserID: userID,
+			RegisteredClaims: jwt.RegisteredClaims{
+				ID:        uuid.NewString(),
+				IssuedAt:  jwt.NewNumericDate(issuedAt),
+				ExpiresAt: jwt.NewNumericDate(issuedAt.Add(15 * time.Minute)),
+			},
+		}
+	}
+
+	t.Run("caches lookups", func(t *testing.T) {
+		client, shutdown := newMockHasuraClient(t, []mockResponse{
+			{body: `{"data":{"revoked_tokens_by_pk":null,"users_by_pk":{"tokens_revoked_before":null}}}`},
+		})
+		defer shutdown()
+
+		service := NewRevocationService(client, opts)
+		claims := newClaims(time.Now())
+
+		for i := 0; i < 2; i++ {
+			revoked, err := service.IsRevoked(claims)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if revoked {
+				t.Fatalf("expected token to be valid")
+			}
+		}
+	})
+
+	t.Run("revoked jti", func(t *testing.T) {
+		client, shutdown := newMockHasuraClient(t, []mockResponse{
+			{body: `{"data":{"revoked_tokens_by_pk":{"jti":"x"},"users_by_pk":{"tokens_revoked_before":null}}}`},
+		})
+		defer shutdown()
+
+		service := NewRevocationService(client, opts)
+		revoked, err := service.IsRevoked(newClaims(time.Now()))
+		if err != nil || !revoked {
+			t.Fatalf("expected token to be revoked, got %v (%v)", revoked, err)
+		}
+	})
+
+	t.Run("user cutoff", func(t *testing.T) {
+		cutoff := time.Now().Add(-time.Minute).UTC()
+		body := fmt.Sprintf(`{"data":{"revoked_tokens_by_pk":null,"users_by_pk":{"tokens_revoked_before":"%s"}}}`, cutoff.Format(time.RFC3339Nano))
+		client, shutdown := newMockHasuraClient(t, []mockResponse{
+			{body: body},
+			{body: body},
+		})
+		defer shutdown()
+
+		service := NewRevocationService(client, opts)
+
+		revoked, err := service.IsRevoked(newClaims(cutoff.Add(-time.Minute)))
+		if err != nil || !revoked {
+			t.Fatalf("expected older token to be revoked, got %v (%v)", revoked, err)
+		}
+
+		revoked, err = service.IsRevoked(newClaims(cutoff.Add(time.Minute)))
+		if err != nil || revoked {
+			t.Fatalf("expected newer token to be valid, got %v (%v)", revoked, err)
+		}
+	})
+
+	t.Run("revoke unknown user", func(t *testing.T) {
+		client, shutdown := newMockHasuraClient(t, []mockResponse{
+			{body: `{"data":{"update_users_by_pk":null,"update_refresh_tokens":{"affected_rows":0}}}`},
+		})
+		defer shutdown()
+
+		service := NewRevocationService(client, opts)
+		if err := service.RevokeUser(userID); !errors.Is(err, ErrUserNotFound) {
+			t.Fatalf("expected ErrUserNotFound, got %v", err)
+		}
+	})
+}
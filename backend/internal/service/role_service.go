@@ -0,0 +1,366 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+	"todo-app/backend/internal/auth"
+	"todo-app/backend/internal/cache"
+	"todo-app/backend/internal/model"
+)
+
+var (
+	ErrRoleNotFound      = errors.New("role not found")
+	ErrRoleExists        = errors.New("role already exists")
+	ErrRoleInUse         = errors.New("role is assigned to users")
+	ErrBuiltInRole       = errors.New("built-in roles cannot be changed")
+	ErrInvalidRoleName   = errors.New("role names are lowercase letters, digits, '-' and '_'")
+	ErrInvalidPermission = errors.New("invalid permission")
+	ErrPermissionNotHeld = errors.New("cannot grant permissions you do not hold")
+)
+
+// roleNamePattern keeps role names safe to show and to put in tokens
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{0,49}$`)
+
+type RoleOptions struct {
+	// CacheSize bounds the number of roles whose permissions are cached
+	CacheSize int
+	// CacheTTL is how long cached permissions are trusted, which bounds how
+	// long a change made on another instance can go unnoticed
+	CacheTTL time.Duration
+}
+
+// RoleService manages platform roles and resolves the administration
+// permissions they grant. The built-in roles never reach Postgres: admin
+// holds every permission and user none. Nobody can grant a permission they
+// do not hold themselves, through a role definition or by assigning a role.
+type RoleService struct {
+	hasura      *HasuraClient
+	permissions *cache.LRU[string, []string]
+	opts        RoleOptions
+}
+
+func NewRoleService(hasura *HasuraClient, opts RoleOptions) *RoleService {
+	return &RoleService{
+		hasura:      hasura,
+		permissions: cache.NewLRU[string, []string](opts.CacheSize),
+		opts:        opts,
+	}
+}
+
+// Permissions returns the permissions a role grants. Unknown roles grant
+// none.
+func (s *RoleService) Permissions(role string) ([]string, error) {
+	permissions, err := s.lookup(role)
+	if errors.Is(err, ErrRoleNotFound) {
+		return nil, nil
+	}
+	return permissions, err
+}
+
+// HasPermission reports whether a role grants the permission
+func (s *RoleService) HasPermission(role, permission string) (bool, error) {
+	permissions, err := s.Permissions(role)
+	if err != nil {
+		return false, err
+	}
+	return containsPermission(permissions, permission), nil
+}
+
+// CanAssign reports whether a user in actorRole may give someone role, or
+// take it away: they must hold every permission it grants
+func (s *RoleService) CanAssign(actorRole, role string) (bool, error) {
+	permissions, err := s.lookup(role)
+	if err != nil {
+		return false, err
+	}
+	return s.holdsAll(actorRole, permissions)
+}
+
+// lookup returns a role's permissions, or ErrRoleNotFound
+func (s *RoleService) lookup(role string) ([]string, error) {
+	switch role {
+	case auth.RoleAdmin:
+		return auth.Permissions, nil
+	case auth.RoleUser:
+		return nil, nil
+	}
+
+	if permissions, ok := s.permissions.Get(role); ok {
+		return permissions, nil
+	}
+
+	var response struct {
+		RolesByPk *struct {
+			Permissions []string `json:"permissions"`
+		} `json:"roles_by_pk"`
+	}
+
+	err := s.hasura.execute(`
+        query ($name: String!) {
+          roles_by_pk(name: $name) {
+            permissions
+          }
+        }
+        `, map[string]interface{}{"name": role}, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.RolesByPk == nil {
+		return nil, ErrRoleNotFound
+	}
+
+	s.permissions.Set(role, response.RolesByPk.Permissions, time.Now().Add(s.opts.CacheTTL))
+	return response.RolesByPk.Permissions, nil
+}
+
+func (s *RoleService) holdsAll(actorRole string, permissions []string) (bool, error) {
+	held, err := s.Permissions(actorRole)
+	if err != nil {
+		return false, err
+	}
+	for _, permission := range permissions {
+		if !containsPermission(held, permission) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// ListRoles retrieves every role, built-in ones included
+func (s *RoleService) ListRoles() ([]model.Role, error) {
+	var response struct {
+		Roles []model.Role `json:"roles"`
+	}
+
+	err := s.hasura.execute(`
+        query {
+          roles(order_by: [{built_in: desc}, {name: asc}]) {
+            name
+            description
+            permissions
+            built_in
+            created_at
+            updated_at
+          }
+        }
+        `, nil, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range response.Roles {
+		if response.Roles[i].Name == auth.RoleAdmin {
+			response.Roles[i].Permissions = auth.Permissions
+		}
+	}
+
+	return response.Roles, nil
+}
+
+// CreateRole defines a custom role
+func (s *RoleService) CreateRole(actorRole string, req model.CreateRoleRequest) (*model.Role, error) {
+	if !roleNamePattern.MatchString(req.Name) {
+		return nil, ErrInvalidRoleName
+	}
+
+	permissions, err := s.checkGrant(actorRole, req.Permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		InsertRolesOne *model.Role `json:"insert_roles_one"`
+	}
+
+	now := time.Now().UTC()
+	err = s.hasura.execute(`
+        mutation ($object: roles_insert_input!) {
+          insert_roles_one(object: $object, on_conflict: {constraint: roles_pkey, update_columns: []}) {
+            name
+            description
+            permissions
+            built_in
+            created_at
+            updated_at
+          }
+        }
+        `, map[string]interface{}{
+		"object": map[string]interface{}{
+			"name":        req.Name,
+			"description": req.Description,
+			"permissions": permissions,
+			"created_at":  now,
+			"updated_at":  now,
+		},
+	}, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.InsertRolesOne == nil {
+		return nil, ErrRoleExists
+	}
+
+	return response.InsertRolesOne, nil
+}
+
+// UpdateRole replaces a custom role's permissions. The caller must hold the
+// permissions taken away as well as those granted.
+func (s *RoleService) UpdateRole(actorRole, name string, req model.UpdateRoleRequest) (*model.Role, error) {
+	if auth.BuiltInRole(name) {
+		return nil, ErrBuiltInRole
+	}
+
+	current, err := s.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	if ok, err := s.holdsAll(actorRole, current); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, ErrPermissionNotHeld
+	}
+
+	permissions, err := s.checkGrant(actorRole, req.Permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	set := map[string]interface{}{
+		"permissions": permissions,
+		"updated_at":  time.Now().UTC(),
+	}
+	if req.Description != nil {
+		set["description"] = *req.Description
+	}
+
+	var response struct {
+		UpdateRoles struct {
+			Returning []model.Role `json:"returning"`
+		} `json:"update_roles"`
+	}
+
+	err = s.hasura.execute(`
+        mutation ($name: String!, $set: roles_set_input!) {
+          update_roles(where: {name: {_eq: $name}, built_in: {_eq: false}}, _set: $set) {
+            returning {
+              name
+              description
+              permissions
+              built_in
+              created_at
+              updated_at
+            }
+          }
+        }
+        `, map[string]interface{}{"name": name, "set": set}, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	s.permissions.Remove(name)
+
+	if len(response.UpdateRoles.Returning) == 0 {
+		return nil, ErrRoleNotFound
+	}
+
+	return &response.UpdateRoles.Returning[0], nil
+}
+
+// DeleteRole removes a custom role nobody has any more
+func (s *RoleService) DeleteRole(actorRole, name string) error {
+	if auth.BuiltInRole(name) {
+		return ErrBuiltInRole
+	}
+
+	if ok, err := s.CanAssign(actorRole, name); err != nil {
+		return err
+	} else if !ok {
+		return ErrPermissionNotHeld
+	}
+
+	var usage struct {
+		UsersAggregate struct {
+			Aggregate struct {
+				Count int `json:"count"`
+			} `json:"aggregate"`
+		} `json:"users_aggregate"`
+	}
+
+	err := s.hasura.execute(`
+        query ($name: String!) {
+          users_aggregate(where: {role: {_eq: $name}}) {
+            aggregate {
+              count
+            }
+          }
+        }
+        `, map[string]interface{}{"name": name}, &usage)
+	if err != nil {
+		return err
+	}
+
+	if usage.UsersAggregate.Aggregate.Count > 0 {
+		return ErrRoleInUse
+	}
+
+	var response struct {
+		DeleteRoles struct {
+			AffectedRows int `json:"affected_rows"`
+		} `json:"delete_roles"`
+	}
+
+	err = s.hasura.execute(`
+        mutation ($name: String!) {
+          delete_roles(where: {name: {_eq: $name}, built_in: {_eq: false}}) {
+            affected_rows
+          }
+        }
+        `, map[string]interface{}{"name": name}, &response)
+	if err != nil {
+		return err
+	}
+
+	s.permissions.Remove(name)
+
+	if response.DeleteRoles.AffectedRows == 0 {
+		return ErrRoleNotFound
+	}
+
+	return nil
+}
+
+// checkGrant validates and deduplicates permissions the actor is granting
+func (s *RoleService) checkGrant(actorRole string, requested []string) ([]string, error) {
+	permissions := make([]string, 0, len(requested))
+	for _, permission := range requested {
+		if !auth.ValidPermission(permission) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidPermission, permission)
+		}
+		if !containsPermission(permissions, permission) {
+			permissions = append(permissions, permission)
+		}
+	}
+
+	ok, err := s.holdsAll(actorRole, permissions)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrPermissionNotHeld
+	}
+
+	return permissions, nil
+}
+
+func containsPermission(permissions []string, permission string) bool {
+	for _, p := range permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,190 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+	"todo-app/backend/internal/auth"
+	"todo-app/backend/internal/model"
+
+	"github.com/google/uuid"
+)
+
+func newTestRoleService(client *HasuraClient) *RoleService {
+	return NewRoleService(client, RoleOptions{CacheSize: 10, CacheTTL: time.Minute})
+}
+
+func roleResponse(permissions string) mockResponse {
+	return mockResponse{body: fmt.Sprintf(`{"data":{"roles_by_pk":{"permissions":%s}}}`, permissions)}
+}
+
+func TestRoleService_Permissions(t *testing.T) {
+	client, shutdown := newMockHasuraClient(t, []mockResponse{
+		roleResponse(`["users.read","todos.read_all"]`),
+		{body: `{"data":{"roles_by_pk":null}}`},
+	})
+	defer shutdown()
+
+	service := newTestRoleService(client)
+
+	// Built-in roles are answered without a lookup
+	if ok, err := service.HasPermission(auth.RoleAdmin, auth.PermissionRolesManage); err != nil || !ok {
+		t.Fatalf("expected admin to hold every permission, got %v, %v", ok, err)
+	}
+	if ok, err := service.HasPermission(auth.RoleUser, auth.PermissionUsersRead); err != nil || ok {
+		t.Fatalf("expected user to hold no permission, got %v, %v", ok, err)
+	}
+
+	// Custom roles are looked up once and cached
+	for i := 0; i < 2; i++ {
+		if ok, err := service.HasPermission("support", auth.PermissionUsersRead); err != nil || !ok {
+			t.Fatalf("expected support to read users, got %v, %v", ok, err)
+		}
+	}
+	if ok, err := service.HasPermission("support", auth.PermissionUsersWrite); err != nil || ok {
+		t.Fatalf("expected support not to write users, got %v, %v", ok, err)
+	}
+
+	if permissions, err := service.Permissions("deleted"); err != nil || len(permissions) != 0 {
+		t.Fatalf("expected an unknown role to grant nothing, got %v, %v", permissions, err)
+	}
+}
+
+func TestRoleService_CreateRole(t *testing.T) {
+	t.Run("creates a role", func(t *testing.T) {
+		var granted []interface{}
+		client, shutdown := newMockHasuraClient(t, []mockResponse{
+			{respond: func(variables map[string]interface{}) string {
+				object := variables["object"].(map[string]interface{})
+				granted = object["permissions"].([]interface{})
+				return `{"data":{"insert_roles_one":{"name":"support","description":"","permissions":["users.read"],"built_in":false,"created_at":"2026-01-01T00:00:00Z","updated_at":"2026-01-01T00:00:00Z"}}}`
+			}},
+		})
+		defer shutdown()
+
+		role, err := newTestRoleService(client).CreateRole(auth.RoleAdmin, model.CreateRoleRequest{
+			Name:        "support",
+			Permissions: []string{auth.PermissionUsersRead, auth.PermissionUsersRead},
+		})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if role.Name != "support" || len(granted) != 1 {
+			t.Fatalf("expected deduplicated permissions, got %v", granted)
+		}
+	})
+
+	t.Run("existing role", func(t *testing.T) {
+		client, shutdown := newMockHasuraClient(t, []mockResponse{
+			{body: `{"data":{"insert_roles_one":null}}`},
+		})
+		defer shutdown()
+
+		_, err := newTestRoleService(client).CreateRole(auth.RoleAdmin, model.CreateRoleRequest{Name: "support", Permissions: []string{}})
+		if !errors.Is(err, ErrRoleExists) {
+			t.Fatalf("expected ErrRoleExists, got %v", err)
+		}
+	})
+
+	t.Run("invalid input", func(t *testing.T) {
+		service := newTestRoleService(nil)
+
+		if _, err := service.CreateRole(auth.RoleAdmin, model.CreateRoleRequest{Name: "Support Team"}); !errors.Is(err, ErrInvalidRoleName) {
+			t.Fatalf("expected ErrInvalidRoleName, got %v", err)
+		}
+		if _, err := service.CreateRole(auth.RoleAdmin, model.CreateRoleRequest{Name: "support", Permissions: []string{"users.everything"}}); !errors.Is(err, ErrInvalidPermission) {
+			t.Fatalf("expected ErrInvalidPermission, got %v", err)
+		}
+	})
+
+	t.Run("cannot grant more than held", func(t *testing.T) {
+		client, shutdown := newMockHasuraClient(t, []mockResponse{
+			roleResponse(`["roles.manage","users.read"]`),
+		})
+		defer shutdown()
+
+		_, err := newTestRoleService(client).CreateRole("role-admin", model.CreateRoleRequest{
+			Name:        "helpdesk",
+			Permissions: []string{auth.PermissionUsersRead, auth.PermissionUsersWrite},
+		})
+		if !errors.Is(err, ErrPermissionNotHeld) {
+			t.Fatalf("expected ErrPermissionNotHeld, got %v", err)
+		}
+	})
+}
+
+func TestRoleService_UpdateAndDelete(t *testing.T) {
+	t.Run("built-in roles are fixed", func(t *testing.T) {
+		service := newTestRoleService(nil)
+
+		if _, err := service.UpdateRole(auth.RoleAdmin, auth.RoleUser, model.UpdateRoleRequest{Permissions: []string{auth.PermissionUsersRead}}); !errors.Is(err, ErrBuiltInRole) {
+			t.Fatalf("expected ErrBuiltInRole, got %v", err)
+		}
+		if err := service.DeleteRole(auth.RoleAdmin, auth.RoleAdmin); !errors.Is(err, ErrBuiltInRole) {
+			t.Fatalf("expected ErrBuiltInRole, got %v", err)
+		}
+	})
+
+	t.Run("updating clears the cache", func(t *testing.T) {
+		client, shutdown := newMockHasuraClient(t, []mockResponse{
+			roleResponse(`["users.read"]`),
+			{body: `{"data":{"update_roles":{"returning":[{"name":"support","description":"","permissions":["users.read","todos.read_all"],"built_in":false,"created_at":"2026-01-01T00:00:00Z","updated_at":"2026-01-01T00:00:00Z"}]}}}`},
+			roleResponse(`["users.read","todos.read_all"]`),
+		})
+		defer shutdown()
+
+		service := newTestRoleService(client)
+		_, err := service.UpdateRole(auth.RoleAdmin, "support", model.UpdateRoleRequest{Permissions: []string{auth.PermissionUsersRead, auth.PermissionTodosReadAll}})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if ok, err := service.HasPermission("support", auth.PermissionTodosReadAll); err != nil || !ok {
+			t.Fatalf("expected the new permission to apply, got %v, %v", ok, err)
+		}
+	})
+
+	t.Run("roles in use are kept", func(t *testing.T) {
+		client, shutdown := newMockHasuraClient(t, []mockResponse{
+			roleResponse(`["users.read"]`),
+			{body: `{"data":{"users_aggregate":{"aggregate":{"count":2}}}}`},
+		})
+		defer shutdown()
+
+		if err := newTestRoleService(client).DeleteRole(auth.RoleAdmin, "support"); !errors.Is(err, ErrRoleInUse) {
+			t.Fatalf("expected ErrRoleInUse, got %v", err)
+		}
+	})
+}
+
+func TestUserService_UpdateUserRole(t *testing.T) {
+	userID := uuid.New()
+	user := fmt.Sprintf(`{"data":{"users_by_pk":{"id":"%s","email":"bob@example.com","role":"user","created_at":"2026-01-01T00:00:00Z","updated_at":"2026-01-01T00:00:00Z"}}}`, userID)
+
+	t.Run("cannot hand out more than held", func(t *testing.T) {
+		client, shutdown := newMockHasuraClient(t, []mockResponse{
+			{body: user},
+			roleResponse(`["users.read","users.write"]`),
+		})
+		defer shutdown()
+
+		service := NewUserService(client, nil, nil, nil, newTestRoleService(client), nil)
+		if _, err := service.UpdateUserRole("user-manager", userID, auth.RoleAdmin); !errors.Is(err, ErrPermissionNotHeld) {
+			t.Fatalf("expected ErrPermissionNotHeld, got %v", err)
+		}
+	})
+
+	t.Run("unknown role", func(t *testing.T) {
+		client, shutdown := newMockHasuraClient(t, []mockResponse{
+			{body: user},
+			{body: `{"data":{"roles_by_pk":null}}`},
+		})
+		defer shutdown()
+
+		service := NewUserService(client, nil, nil, nil, newTestRoleService(client), nil)
+		if _, err := service.UpdateUserRole(auth.RoleAdmin, userID, "superuser"); !errors.Is(err, ErrRoleNotFound) {
+			t.Fatalf("expected ErrRoleNotFound, got %v", err)
+		}
+	})
+}
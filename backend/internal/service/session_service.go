@@ -0,0 +1,166 @@
+package service
+
+import (
+	"errors"
+	"time"
+	"todo-app/backend/internal/cache"
+	"todo-app/backend/internal/model"
+
+	"github.com/google/uuid"
+)
+
+var ErrSessionNotFound = errors.New("session not found")
+
+// maxUserAgentLength caps the stored user agent
+const maxUserAgentLength = 512
+
+type SessionOptions struct {
+	// TouchInterval is the minimum time between last-seen writes for one
+	// session
+	TouchInterval time.Duration
+	// CacheSize bounds the number of sessions tracked for throttling
+	CacheSize int
+}
+
+type SessionService struct {
+	hasura      *HasuraClient
+	revocations *RevocationService
+	touched     *cache.LRU[uuid.UUID, struct{}]
+	opts        SessionOptions
+}
+
+func NewSessionService(hasura *HasuraClient, revocations *RevocationService, opts SessionOptions) *SessionService {
+	return &SessionService{
+		hasura:      hasura,
+		revocations: revocations,
+		touched:     cache.NewLRU[uuid.UUID, struct{}](opts.CacheSize),
+		opts:        opts,
+	}
+}
+
+// StartSession records a new login and returns its id, which is also the
+// refresh token family id. mfaVerified records that the login passed a
+// second factor.
+func (s *SessionService) StartSession(userID uuid.UUID, client model.ClientInfo, mfaVerified bool) (uuid.UUID, error) {
+	userAgent := client.UserAgent
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+
+	var mfaVerifiedAt *time.Time
+	if mfaVerified {
+		now := time.Now().UTC()
+		mfaVerifiedAt = &now
+	}
+
+	var response struct {
+		InsertSessionsOne struct {
+			ID uuid.UUID `json:"id"`
+		} `json:"insert_sessions_one"`
+	}
+
+	err := s.hasura.execute(`
+        mutation ($userId: uuid!, $userAgent: String!, $ipAddress: String!, $mfaVerifiedAt: timestamptz) {
+          insert_sessions_one(object: {user_id: $userId, user_agent: $userAgent, ip_address: $ipAddress, mfa_verified_at: $mfaVerifiedAt}) {
+            id
+          }
+        }
+        `, map[string]interface{}{
+		"userId":        userID,
+		"userAgent":     userAgent,
+		"ipAddress":     client.IPAddress,
+		"mfaVerifiedAt": mfaVerifiedAt,
+	}, &response)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	return response.InsertSessionsOne.ID, nil
+}
+
+// ListSessions retrieves the user's active sessions, flagging currentID
+func (s *SessionService) ListSessions(userID, currentID uuid.UUID) ([]model.Session, error) {
+	var response struct {
+		Sessions []model.Session `json:"sessions"`
+	}
+
+	// A session is active while it has a live refresh token
+	err := s.hasura.execute(`
+        query ($userId: uuid!, $now: timestamptz!) {
+          sessions(
+            where: {user_id: {_eq: $userId}, revoked_at: {_is_null: true}, refresh_tokens: {revoked_at: {_is_null: true}, expires_at: {_gt: $now}}},
+            order_by: {last_seen_at: desc}
+          ) {
+            id
+            user_agent
+            ip_address
+            created_at
+            last_seen_at
+          }
+        }
+        `, map[string]interface{}{"userId": userID, "now": time.Now().UTC()}, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range response.Sessions {
+		response.Sessions[i].Current = response.Sessions[i].ID == currentID
+	}
+
+	return response.Sessions, nil
+}
+
+// RevokeSession signs one of the user's sessions out
+func (s *SessionService) RevokeSession(userID, sessionID uuid.UUID) error {
+	var response struct {
+		Sessions []struct {
+			ID uuid.UUID `json:"id"`
+		} `json:"sessions"`
+	}
+
+	err := s.hasura.execute(`
+        query ($id: uuid!, $userId: uuid!) {
+          sessions(where: {id: {_eq: $id}, user_id: {_eq: $userId}, revoked_at: {_is_null: true}}) {
+            id
+          }
+        }
+        `, map[string]interface{}{"id": sessionID, "userId": userID}, &response)
+	if err != nil {
+		return err
+	}
+
+	if len(response.Sessions) == 0 {
+		return ErrSessionNotFound
+	}
+
+	return s.revocations.RevokeSession(sessionID)
+}
+
+// Touch records that the session was just used. Writes are throttled to one
+// per TouchInterval per session.
+func (s *SessionService) Touch(sessionID uuid.UUID) error {
+	if sessionID == uuid.Nil {
+		return nil
+	}
+
+	if _, ok := s.touched.Get(sessionID); ok {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	s.touched.Set(sessionID, struct{}{}, now.Add(s.opts.TouchInterval))
+
+	var response struct {
+		UpdateSessionsByPk *struct {
+			ID uuid.UUID `json:"id"`
+		} `json:"update_sessions_by_pk"`
+	}
+
+	return s.hasura.execute(`
+        mutation ($id: uuid!, $now: timestamptz!) {
+          update_sessions_by_pk(pk_columns: {id: $id}, _set: {last_seen_at: $now}) {
+            id
+          }
+        }
+        `, map[string]interface{}{"id": sessionID, "now": now}, &response)
+}
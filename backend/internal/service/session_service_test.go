@@ -0,0 +1,62 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func newTestSessionService(client *HasuraClient) *SessionService {
+	revocations := NewRevocationService(client, RevocationOptions{CacheSize: 10, CacheTTL: time.Minute})
+	return NewSessionService(client, revocations, SessionOptions{TouchInterval: time.Minute, CacheSize: 10})
+}
+
+func TestSessionService_Touch(t *testing.T) {
+	sessionID := uuid.New()
+
+	// Only the first touch within the interval reaches Hasura
+	client, shutdown := newMockHasuraClient(t, []mockResponse{
+		{body: fmt.Sprintf(`{"data":{"update_sessions_by_pk":{"id":"%s"}}}`, sessionID)},
+	})
+	defer shutdown()
+
+	service := newTestSessionService(client)
+	for i := 0; i < 3; i++ {
+		if err := service.Touch(sessionID); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+}
+
+func TestSessionService_RevokeSession(t *testing.T) {
+	userID := uuid.New()
+	sessionID := uuid.New()
+
+	t.Run("another user's session", func(t *testing.T) {
+		client, shutdown := newMockHasuraClient(t, []mockResponse{
+			{body: `{"data":{"sessions":[]}}`},
+		})
+		defer shutdown()
+
+		service := newTestSessionService(client)
+		if err := service.RevokeSession(userID, sessionID); !errors.Is(err, ErrSessionNotFound) {
+			t.Fatalf("expected ErrSessionNotFound, got %v", err)
+		}
+	})
+
+	t.Run("own session", func(t *testing.T) {
+		client, shutdown := newMockHasuraClient(t, []mockResponse{
+			{body: fmt.Sprintf(`{"data":{"sessions":[{"id":"%s"}]}}`, sessionID)},
+			{body: `{"data":{"update_sessions":{"affected_rows":1},"update_refresh_tokens":{"affected_rows":1}}}`},
+		})
+		defer shutdown()
+
+		service := newTestSessionService(client)
+		if err := service.RevokeSession(userID, sessionID); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,265 @@
+package service
+
+import (
+	"errors"
+	"time"
+	"todo-app/backend/internal/model"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrShareNotFound       = errors.New("share not found")
+	ErrShareForbidden      = errors.New("only owners can manage shares")
+	ErrCannotShareWithSelf = errors.New("cannot share a todo with its owner")
+)
+
+type ShareService struct {
+	hasura *HasuraClient
+	todos  *TodoService
+}
+
+func NewShareService(hasura *HasuraClient, todos *TodoService) *ShareService {
+	return &ShareService{
+		hasura: hasura,
+		todos:  todos,
+	}
+}
+
+// shareRecord is a share row together with the grantee's email
+type shareRecord struct {
+	ID        uuid.UUID  `json:"id"`
+	TodoID    uuid.UUID  `json:"todo_id"`
+	UserID    uuid.UUID  `json:"user_id"`
+	Role      string     `json:"role"`
+	GrantedBy *uuid.UUID `json:"granted_by"`
+	CreatedAt time.Time  `json:"created_at"`
+	User      struct {
+		Email string `json:"email"`
+	} `json:"user"`
+}
+
+func (r shareRecord) toModel() model.Share {
+	return model.Share{
+		ID:        r.ID,
+		TodoID:    r.TodoID,
+		UserID:    r.UserID,
+		Email:     r.User.Email,
+		Role:      r.Role,
+		GrantedBy: r.GrantedBy,
+		CreatedAt: r.CreatedAt,
+	}
+}
+
+func sharesToModel(records []shareRecord) []model.Share {
+	shares := make([]model.Share, 0, len(records))
+	for _, record := range records {
+		shares = append(shares, record.toModel())
+	}
+	return shares
+}
+
+// CreateShare grants a registered user access to a todo. Inviting a user who
+// already holds a grant updates their role.
+func (s *ShareService) CreateShare(userID uuid.UUID, req model.CreateShareRequest) (*model.Share, error) {
+	if err := s.requireOwner(userID, req.TodoID); err != nil {
+		return nil, err
+	}
+
+	var usersResp struct {
+		Users []struct {
+			ID uuid.UUID `json:"id"`
+		} `json:"users"`
+	}
+
+	err := s.hasura.execute(`
+        query ($email: String!) {
+          users(where: {email: {_eq: $email}}, limit: 1) {
+            id
+          }
+        }
+        `, map[string]interface{}{"email": req.Email}, &usersResp)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(usersResp.Users) == 0 {
+		return nil, ErrUserNotFound
+	}
+
+	granteeID := usersResp.Users[0].ID
+
+	todo, err := s.todos.GetTodo(userID, req.TodoID)
+	if err != nil {
+		return nil, err
+	}
+
+	if granteeID == todo.UserID {
+		return nil, ErrCannotShareWithSelf
+	}
+
+	var response struct {
+		InsertTodoSharesOne shareRecord `json:"insert_todo_shares_one"`
+	}
+
+	err = s.hasura.execute(`
+        mutation ($todoId: uuid!, $userId: uuid!, $role: String!, $grantedBy: uuid!) {
+          insert_todo_shares_one(
+            object: {todo_id: $todoId, user_id: $userId, role: $role, granted_by: $grantedBy},
+            on_conflict: {constraint: todo_shares_todo_id_user_id_key, update_columns: [role, granted_by]}
+          ) {
+            id
+            todo_id
+            user_id
+            role
+            granted_by
+            created_at
+            user {
+              email
+            }
+          }
+        }
+        `, map[string]interface{}{
+		"todoId":    req.TodoID,
+		"userId":    granteeID,
+		"role":      req.Role,
+		"grantedBy": userID,
+	}, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	share := response.InsertTodoSharesOne.toModel()
+	return &share, nil
+}
+
+// ListTodoShares retrieves the grants on a todo the user can view
+func (s *ShareService) ListTodoShares(userID, todoID uuid.UUID) ([]model.Share, error) {
+	if _, err := s.todos.Permission(userID, todoID); err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		TodoShares []shareRecord `json:"todo_shares"`
+	}
+
+	err := s.hasura.execute(`
+        query ($todoId: uuid!) {
+          todo_shares(where: {todo_id: {_eq: $todoId}}, order_by: {created_at: asc}) {
+            id
+            todo_id
+            user_id
+            role
+            granted_by
+            created_at
+            user {
+              email
+            }
+          }
+        }
+        `, map[string]interface{}{"todoId": todoID}, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	return sharesToModel(response.TodoShares), nil
+}
+
+// ListReceivedShares retrieves the grants other users have given the user
+func (s *ShareService) ListReceivedShares(userID uuid.UUID) ([]model.Share, error) {
+	var response struct {
+		TodoShares []shareRecord `json:"todo_shares"`
+	}
+
+	err := s.hasura.execute(`
+        query ($userId: uuid!) {
+          todo_shares(where: {user_id: {_eq: $userId}}, order_by: {created_at: desc}) {
+            id
+            todo_id
+            user_id
+            role
+            granted_by
+            created_at
+            user {
+              email
+            }
+          }
+        }
+        `, map[string]interface{}{"userId": userID}, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	return sharesToModel(response.TodoShares), nil
+}
+
+// DeleteShare revokes a grant. Owners can revoke any grant on their todo and
+// grantees can remove their own.
+func (s *ShareService) DeleteShare(userID, shareID uuid.UUID) error {
+	var shareResp struct {
+		TodoSharesByPk *shareRecord `json:"todo_shares_by_pk"`
+	}
+
+	err := s.hasura.execute(`
+        query ($id: uuid!) {
+          todo_shares_by_pk(id: $id) {
+            id
+            todo_id
+            user_id
+          }
+        }
+        `, map[string]interface{}{"id": shareID}, &shareResp)
+	if err != nil {
+		return err
+	}
+
+	share := shareResp.TodoSharesByPk
+	if share == nil {
+		return ErrShareNotFound
+	}
+
+	if share.UserID != userID {
+		if err := s.requireOwner(userID, share.TodoID); err != nil {
+			if errors.Is(err, ErrTodoNotFound) {
+				return ErrShareNotFound
+			}
+			return err
+		}
+	}
+
+	var response struct {
+		DeleteTodoSharesByPk *struct {
+			ID uuid.UUID `json:"id"`
+		} `json:"delete_todo_shares_by_pk"`
+	}
+
+	err = s.hasura.execute(`
+        mutation ($id: uuid!) {
+          delete_todo_shares_by_pk(id: $id) {
+            id
+          }
+        }
+        `, map[string]interface{}{"id": shareID}, &response)
+	if err != nil {
+		return err
+	}
+
+	if response.DeleteTodoSharesByPk == nil {
+		return ErrShareNotFound
+	}
+
+	return nil
+}
+
+func (s *ShareService) requireOwner(userID, todoID uuid.UUID) error {
+	permission, err := s.todos.Permission(userID, todoID)
+	if err != nil {
+		return err
+	}
+
+	if permission != model.PermissionOwner {
+		return ErrShareForbidden
+	}
+
+	return nil
+}
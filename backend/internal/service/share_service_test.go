@@ -0,0 +1,43 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"todo-app/backend/internal/model"
+)
+
+func TestShareService_CreateShare(t *testing.T) {
+	userID := uuid.New()
+	todoID := uuid.New()
+
+	t.Run("editor cannot invite", func(t *testing.T) {
+		client, shutdown := newMockHasuraClient(t, []mockResponse{
+			{body: fmt.Sprintf(`{"data":{"todos_by_pk":{"user_id":"%s","shares":[{"role":"editor"}]}}}`, uuid.New())},
+		})
+		defer shutdown()
+
+		service := NewShareService(client, NewTodoService(client))
+		_, err := service.CreateShare(userID, model.CreateShareRequest{TodoID: todoID, Email: "bob@example.com", Role: model.PermissionViewer})
+		if !errors.Is(err, ErrShareForbidden) {
+			t.Fatalf("expected ErrShareForbidden, got %v", err)
+		}
+	})
+
+	t.Run("unknown invitee", func(t *testing.T) {
+		client, shutdown := newMockHasuraClient(t, []mockResponse{
+			{body: fmt.Sprintf(`{"data":{"todos_by_pk":{"user_id":"%s","shares":[]}}}`, userID)},
+			{body: `{"data":{"users":[]}}`},
+		})
+		defer shutdown()
+
+		service := NewShareService(client, NewTodoService(client))
+		_, err := service.CreateShare(userID, model.CreateShareRequest{TodoID: todoID, Email: "nobody@example.com", Role: model.PermissionViewer})
+		if !errors.Is(err, ErrUserNotFound) {
+			t.Fatalf("expected ErrUserNotFound, got %v", err)
+		}
+	})
+}
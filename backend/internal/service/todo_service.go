@@ -8,8 +8,9 @@ import (
 )
 
 var (
-	ErrTodoNotFound  = errors.New("todo not found")
-	ErrInvalidTodoID = errors.New("invalid todo id")
+	ErrTodoNotFound     = errors.New("todo not found")
+	ErrInvalidTodoID    = errors.New("invalid todo id")
+	ErrAssigneeNoAccess = errors.New("assignee does not have access to the todo")
 )
 
 type TodoService struct {
@@ -20,51 +21,179 @@ func NewTodoService(hasura *HasuraClient) *TodoService {
 	return &TodoService{hasura: hasura}
 }
 
-// GetTodos retrieves all todos for a user
+// ForCaller returns the service with its requests made as the caller, see
+// HasuraClient.ForCaller. It is meant for reads: the user role is not
+// granted every column and check the writes here rely on.
+func (s *TodoService) ForCaller(token string) *TodoService {
+	return &TodoService{hasura: s.hasura.ForCaller(token)}
+}
+
+// todoRecord is a todo together with the caller's share grants on it and
+// membership in its workspace
+type todoRecord struct {
+	model.Todo
+	Shares []struct {
+		Role string `json:"role"`
+	} `json:"shares"`
+	Workspace *struct {
+		Members []struct {
+			Role string `json:"role"`
+		} `json:"members"`
+	} `json:"workspace"`
+}
+
+// withPermission resolves the caller's effective permission on the todo
+func (r todoRecord) withPermission(userID uuid.UUID) model.Todo {
+	todo := r.Todo
+	todo.Permission = ""
+
+	if todo.UserID == userID {
+		todo.Permission = model.PermissionOwner
+		return todo
+	}
+
+	for _, share := range r.Shares {
+		if !hasPermission(todo.Permission, share.Role) {
+			todo.Permission = share.Role
+		}
+	}
+
+	if r.Workspace != nil {
+		for _, member := range r.Workspace.Members {
+			granted := workspaceRolePermission(member.Role)
+			if !hasPermission(todo.Permission, granted) {
+				todo.Permission = granted
+			}
+		}
+	}
+
+	return todo
+}
+
+func todosWithPermission(records []todoRecord, userID uuid.UUID) []model.Todo {
+	todos := make([]model.Todo, 0, len(records))
+	for _, record := range records {
+		todos = append(todos, record.withPermission(userID))
+	}
+	return todos
+}
+
+// GetTodos retrieves all todos a user can access
 func (s *TodoService) GetTodos(userID uuid.UUID) ([]model.Todo, error) {
+	return s.ListTodos(userID, model.TodoFilter{})
+}
+
+// ListTodos retrieves the todos a user can access that match the filter
+func (s *TodoService) ListTodos(userID uuid.UUID, filter model.TodoFilter) ([]model.Todo, error) {
 	var response struct {
-		Todos []model.Todo `json:"todos"`
+		Todos []todoRecord `json:"todos"`
 	}
 
+	where := todoAccessWhere(userID, model.PermissionViewer)
+	applyTodoFilter(where, filter)
+
 	err := s.hasura.execute(`
-        query ($userId: uuid!) {
-          todos(where: {user_id: {_eq: $userId}}, order_by: {created_at: desc}) {
+        query ($userId: uuid!, $where: todos_bool_exp!) {
+          todos(where: $where, order_by: {created_at: desc}) {
             id
             user_id
             title
             description
             completed
+            assignee_id
+            workspace_id
             created_at
             updated_at
+            shares(where: {user_id: {_eq: $userId}}) {
+              role
+            }
+            workspace {
+              members(where: {user_id: {_eq: $userId}}) {
+                role
+              }
+            }
           }
         }
-        `, map[string]interface{}{"userId": userID}, &response)
+        `, map[string]interface{}{"userId": userID, "where": where}, &response)
 	if err != nil {
 		return nil, err
 	}
 
-	return response.Todos, nil
+	return todosWithPermission(response.Todos, userID), nil
 }
 
-// GetTodo retrieves a specific todo for a user
+// Inbox retrieves the open todos assigned to a user, most recently changed first
+func (s *TodoService) Inbox(userID uuid.UUID) ([]model.Todo, error) {
+	var response struct {
+		Todos []todoRecord `json:"todos"`
+	}
+
+	open := false
+	where := todoAccessWhere(userID, model.PermissionViewer)
+	applyTodoFilter(where, model.TodoFilter{AssigneeID: &userID, Completed: &open})
+
+	err := s.hasura.execute(`
+        query ($userId: uuid!, $where: todos_bool_exp!) {
+          todos(where: $where, order_by: {updated_at: desc}) {
+            id
+            user_id
+            title
+            description
+            completed
+            assignee_id
+            workspace_id
+            created_at
+            updated_at
+            shares(where: {user_id: {_eq: $userId}}) {
+              role
+            }
+            workspace {
+              members(where: {user_id: {_eq: $userId}}) {
+                role
+              }
+            }
+          }
+        }
+        `, map[string]interface{}{"userId": userID, "where": where}, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	return todosWithPermission(response.Todos, userID), nil
+}
+
+// GetTodo retrieves a specific todo the user can access
 func (s *TodoService) GetTodo(userID, todoID uuid.UUID) (*model.Todo, error) {
 	var response struct {
-		Todos []model.Todo `json:"todos"`
+		Todos []todoRecord `json:"todos"`
 	}
 
+	where := todoAccessWhere(userID, model.PermissionViewer)
+	where["id"] = map[string]interface{}{"_eq": todoID}
+
 	err := s.hasura.execute(`
-        query ($id: uuid!, $userId: uuid!) {
-          todos(where: {id: {_eq: $id}, user_id: {_eq: $userId}}, limit: 1) {
+        query ($userId: uuid!, $where: todos_bool_exp!) {
+          todos(where: $where, limit: 1) {
             id
             user_id
             title
             description
             completed
+            assignee_id
+            workspace_id
             created_at
             updated_at
+            shares(where: {user_id: {_eq: $userId}}) {
+              role
+            }
+            workspace {
+              members(where: {user_id: {_eq: $userId}}) {
+                role
+              }
+            }
           }
         }
-        `, map[string]interface{}{"id": todoID, "userId": userID}, &response)
+        `, map[string]interface{}{"userId": userID, "where": where}, &response)
 	if err != nil {
 		return nil, err
 	}
@@ -73,36 +202,40 @@ func (s *TodoService) GetTodo(userID, todoID uuid.UUID) (*model.Todo, error) {
 		return nil, ErrTodoNotFound
 	}
 
-	return &response.Todos[0], nil
+	todo := response.Todos[0].withPermission(userID)
+	return &todo, nil
 }
 
-// CreateTodo creates a new todo for a user
-func (s *TodoService) CreateTodo(userID uuid.UUID, title string, description *string) (*model.Todo, error) {
+// CreateTodo creates a new todo for a user, optionally inside a workspace
+func (s *TodoService) CreateTodo(userID uuid.UUID, workspaceID *uuid.UUID, title string, description *string) (*model.Todo, error) {
 	var response struct {
 		InsertTodosOne model.Todo `json:"insert_todos_one"`
 	}
 
 	err := s.hasura.execute(`
-        mutation ($userId: uuid!, $title: String!, $description: String) {
-          insert_todos_one(object: {user_id: $userId, title: $title, description: $description}) {
+        mutation ($userId: uuid!, $workspaceId: uuid, $title: String!, $description: String) {
+          insert_todos_one(object: {user_id: $userId, workspace_id: $workspaceId, title: $title, description: $description}) {
             id
             user_id
             title
             description
             completed
+            assignee_id
+            workspace_id
             created_at
             updated_at
           }
         }
-        `, map[string]interface{}{"userId": userID, "title": title, "description": description}, &response)
+        `, map[string]interface{}{"userId": userID, "workspaceId": workspaceID, "title": title, "description": description}, &response)
 	if err != nil {
 		return nil, err
 	}
 
+	response.InsertTodosOne.Permission = model.PermissionOwner
 	return &response.InsertTodosOne, nil
 }
 
-// UpdateTodo updates a todo for a user
+// UpdateTodo updates a todo the user can edit
 func (s *TodoService) UpdateTodo(userID, todoID uuid.UUID, req model.UpdateTodoRequest) (*model.Todo, error) {
 	changes := map[string]interface{}{}
 
@@ -124,25 +257,38 @@ func (s *TodoService) UpdateTodo(userID, todoID uuid.UUID, req model.UpdateTodoR
 
 	var response struct {
 		UpdateTodos struct {
-			Returning []model.Todo `json:"returning"`
+			Returning []todoRecord `json:"returning"`
 		} `json:"update_todos"`
 	}
 
+	where := todoAccessWhere(userID, model.PermissionEditor)
+	where["id"] = map[string]interface{}{"_eq": todoID}
+
 	err := s.hasura.execute(`
-        mutation ($id: uuid!, $userId: uuid!, $changes: todos_set_input!) {
-          update_todos(where: {id: {_eq: $id}, user_id: {_eq: $userId}}, _set: $changes) {
+        mutation ($userId: uuid!, $where: todos_bool_exp!, $changes: todos_set_input!) {
+          update_todos(where: $where, _set: $changes) {
             returning {
               id
               user_id
               title
               description
               completed
+              assignee_id
+              workspace_id
               created_at
               updated_at
+              shares(where: {user_id: {_eq: $userId}}) {
+                role
+              }
+              workspace {
+                members(where: {user_id: {_eq: $userId}}) {
+                  role
+                }
+              }
             }
           }
         }
-        `, map[string]interface{}{"id": todoID, "userId": userID, "changes": changes}, &response)
+        `, map[string]interface{}{"userId": userID, "where": where, "changes": changes}, &response)
 	if err != nil {
 		return nil, err
 	}
@@ -151,10 +297,11 @@ func (s *TodoService) UpdateTodo(userID, todoID uuid.UUID, req model.UpdateTodoR
 		return nil, ErrTodoNotFound
 	}
 
-	return &response.UpdateTodos.Returning[0], nil
+	todo := response.UpdateTodos.Returning[0].withPermission(userID)
+	return &todo, nil
 }
 
-// DeleteTodo deletes a todo for a user
+// DeleteTodo deletes a todo the user holds owner permission on
 func (s *TodoService) DeleteTodo(userID, todoID uuid.UUID) error {
 	var response struct {
 		DeleteTodos struct {
@@ -162,13 +309,16 @@ func (s *TodoService) DeleteTodo(userID, todoID uuid.UUID) error {
 		} `json:"delete_todos"`
 	}
 
+	where := todoAccessWhere(userID, model.PermissionOwner)
+	where["id"] = map[string]interface{}{"_eq": todoID}
+
 	err := s.hasura.execute(`
-        mutation ($id: uuid!, $userId: uuid!) {
-          delete_todos(where: {id: {_eq: $id}, user_id: {_eq: $userId}}) {
+        mutation ($where: todos_bool_exp!) {
+          delete_todos(where: $where) {
             affected_rows
           }
         }
-        `, map[string]interface{}{"id": todoID, "userId": userID}, &response)
+        `, map[string]interface{}{"where": where}, &response)
 	if err != nil {
 		return err
 	}
@@ -179,3 +329,230 @@ func (s *TodoService) DeleteTodo(userID, todoID uuid.UUID) error {
 
 	return nil
 }
+
+// AssignTodo assigns a todo the user can edit to another user with access to
+// it, or clears the assignment when assigneeID is nil
+func (s *TodoService) AssignTodo(userID, todoID uuid.UUID, assigneeID *uuid.UUID) (*model.Todo, error) {
+	permission, err := s.Permission(userID, todoID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !hasPermission(permission, model.PermissionEditor) {
+		return nil, ErrTodoNotFound
+	}
+
+	if _, err := s.assign(userID, todoID, assigneeID); err != nil {
+		return nil, err
+	}
+
+	return s.GetTodo(userID, todoID)
+}
+
+// ListAssignmentEvents retrieves the assignment history of a todo the user can view
+func (s *TodoService) ListAssignmentEvents(userID, todoID uuid.UUID) ([]model.AssignmentEvent, error) {
+	if _, err := s.Permission(userID, todoID); err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		TodoAssignmentEvents []model.AssignmentEvent `json:"todo_assignment_events"`
+	}
+
+	err := s.hasura.execute(`
+        query ($todoId: uuid!) {
+          todo_assignment_events(where: {todo_id: {_eq: $todoId}}, order_by: {created_at: desc}) {
+            id
+            todo_id
+            assignee_id
+            previous_assignee_id
+            assigned_by
+            created_at
+          }
+        }
+        `, map[string]interface{}{"todoId": todoID}, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	return response.TodoAssignmentEvents, nil
+}
+
+// assign sets a todo's assignee and records the change. The assignee must be
+// able to access the todo. Callers are responsible for authorizing actorID.
+func (s *TodoService) assign(actorID, todoID uuid.UUID, assigneeID *uuid.UUID) (*model.Todo, error) {
+	if assigneeID != nil {
+		if _, err := s.Permission(*assigneeID, todoID); err != nil {
+			if errors.Is(err, ErrTodoNotFound) {
+				return nil, ErrAssigneeNoAccess
+			}
+			return nil, err
+		}
+	}
+
+	var current struct {
+		TodosByPk *model.Todo `json:"todos_by_pk"`
+	}
+
+	err := s.hasura.execute(`
+        query ($id: uuid!) {
+          todos_by_pk(id: $id) {
+            id
+            assignee_id
+          }
+        }
+        `, map[string]interface{}{"id": todoID}, &current)
+	if err != nil {
+		return nil, err
+	}
+
+	if current.TodosByPk == nil {
+		return nil, ErrTodoNotFound
+	}
+
+	var response struct {
+		UpdateTodosByPk *model.Todo `json:"update_todos_by_pk"`
+	}
+
+	err = s.hasura.execute(`
+        mutation ($id: uuid!, $assigneeId: uuid, $previousAssigneeId: uuid, $actorId: uuid!) {
+          update_todos_by_pk(pk_columns: {id: $id}, _set: {assignee_id: $assigneeId}) {
+            id
+            user_id
+            title
+            description
+            completed
+            assignee_id
+            workspace_id
+            created_at
+            updated_at
+          }
+          insert_todo_assignment_events_one(object: {todo_id: $id, assignee_id: $assigneeId, previous_assignee_id: $previousAssigneeId, assigned_by: $actorId}) {
+            id
+          }
+        }
+        `, map[string]interface{}{
+		"id":                 todoID,
+		"assigneeId":         assigneeID,
+		"previousAssigneeId": current.TodosByPk.AssigneeID,
+		"actorId":            actorID,
+	}, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.UpdateTodosByPk == nil {
+		return nil, ErrTodoNotFound
+	}
+
+	return response.UpdateTodosByPk, nil
+}
+
+// Permission returns the caller's effective permission on a todo. Todos the
+// caller cannot access are reported as ErrTodoNotFound.
+func (s *TodoService) Permission(userID, todoID uuid.UUID) (string, error) {
+	var response struct {
+		TodosByPk *todoRecord `json:"todos_by_pk"`
+	}
+
+	err := s.hasura.execute(`
+        query ($id: uuid!, $userId: uuid!) {
+          todos_by_pk(id: $id) {
+            user_id
+            shares(where: {user_id: {_eq: $userId}}) {
+              role
+            }
+            workspace {
+              members(where: {user_id: {_eq: $userId}}) {
+                role
+              }
+            }
+          }
+        }
+        `, map[string]interface{}{"id": todoID, "userId": userID}, &response)
+	if err != nil {
+		return "", err
+	}
+
+	if response.TodosByPk == nil {
+		return "", ErrTodoNotFound
+	}
+
+	permission := response.TodosByPk.withPermission(userID).Permission
+	if permission == "" {
+		return "", ErrTodoNotFound
+	}
+
+	return permission, nil
+}
+
+// hasPermission reports whether the granted permission satisfies the required one
+func hasPermission(granted, required string) bool {
+	rank := map[string]int{
+		model.PermissionViewer: 1,
+		model.PermissionEditor: 2,
+		model.PermissionOwner:  3,
+	}
+	return rank[granted] >= rank[required] && rank[required] > 0
+}
+
+// workspaceRolePermission maps a workspace membership role to the todo
+// permission it grants on the workspace's todos
+func workspaceRolePermission(role string) string {
+	switch role {
+	case model.WorkspaceRoleOwner, model.WorkspaceRoleAdmin:
+		return model.PermissionOwner
+	case model.WorkspaceRoleMember:
+		return model.PermissionEditor
+	case model.WorkspaceRoleGuest:
+		return model.PermissionViewer
+	default:
+		return ""
+	}
+}
+
+// todoAccessWhere builds a todos_bool_exp matching the todos on which the
+// user holds at least the required permission, through ownership, a share
+// grant or workspace membership
+func todoAccessWhere(userID uuid.UUID, required string) map[string]interface{} {
+	var shareRoles, memberRoles []string
+	for _, role := range []string{model.PermissionViewer, model.PermissionEditor, model.PermissionOwner} {
+		if hasPermission(role, required) {
+			shareRoles = append(shareRoles, role)
+		}
+	}
+	for _, role := range []string{model.WorkspaceRoleOwner, model.WorkspaceRoleAdmin, model.WorkspaceRoleMember, model.WorkspaceRoleGuest} {
+		if hasPermission(workspaceRolePermission(role), required) {
+			memberRoles = append(memberRoles, role)
+		}
+	}
+
+	return map[string]interface{}{
+		"_or": []interface{}{
+			map[string]interface{}{"user_id": map[string]interface{}{"_eq": userID}},
+			map[string]interface{}{"shares": map[string]interface{}{
+				"user_id": map[string]interface{}{"_eq": userID},
+				"role":    map[string]interface{}{"_in": shareRoles},
+			}},
+			map[string]interface{}{"workspace": map[string]interface{}{"members": map[string]interface{}{
+				"user_id": map[string]interface{}{"_eq": userID},
+				"role":    map[string]interface{}{"_in": memberRoles},
+			}}},
+		},
+	}
+}
+
+// applyTodoFilter adds the filter's conditions to a todos_bool_exp
+func applyTodoFilter(where map[string]interface{}, filter model.TodoFilter) {
+	if filter.AssigneeID != nil {
+		where["assignee_id"] = map[string]interface{}{"_eq": *filter.AssigneeID}
+	}
+
+	if filter.Completed != nil {
+		where["completed"] = map[string]interface{}{"_eq": *filter.Completed}
+	}
+
+	if filter.WorkspaceID != nil {
+		where["workspace_id"] = map[string]interface{}{"_eq": *filter.WorkspaceID}
+	}
+}
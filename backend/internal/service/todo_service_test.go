@@ -1,6 +1,7 @@
 package service
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
@@ -16,6 +17,9 @@ import (
 type mockResponse struct {
 	status int
 	body   string
+	// respond, when set, builds the body from the request's variables, for
+	// responses that depend on values the service generated
+	respond func(variables map[string]interface{}) string
 }
 
 func newMockHasuraClient(t *testing.T, responses []mockResponse) (*HasuraClient, func()) {
@@ -39,8 +43,19 @@ func newMockHasuraClient(t *testing.T, responses []mockResponse) (*HasuraClient,
 			status = http.StatusOK
 		}
 
+		body := resp.body
+		if resp.respond != nil {
+			var req struct {
+				Variables map[string]interface{} `json:"variables"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Fatalf("failed to decode request: %v", err)
+			}
+			body = resp.respond(req.Variables)
+		}
+
 		w.WriteHeader(status)
-		if _, err := w.Write([]byte(resp.body)); err != nil {
+		if _, err := w.Write([]byte(body)); err != nil {
 			t.Fatalf("failed to write mock response: %v", err)
 		}
 	}))
@@ -137,7 +152,7 @@ func TestTodoService_CreateTodo(t *testing.T) {
 
 	service := NewTodoService(client)
 	desc := "created"
-	todo, err := service.CreateTodo(userID, "New", &desc)
+	todo, err := service.CreateTodo(userID, nil, "New", &desc)
 	if err != nil {
 		t.Fatalf("CreateTodo returned error: %v", err)
 	}
@@ -242,5 +257,87 @@ func TestTodoService_DeleteTodo(t *testing.T) {
 	})
 }
 
+func TestTodoService_SharedTodos(t *testing.T) {
+	userID := uuid.New()
+	ownerID := uuid.New()
+	todoID := uuid.New()
+	now := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC).Format(time.RFC3339)
+
+	t.Run("shared todo carries effective permission", func(t *testing.T) {
+		client, shutdown := newMockHasuraClient(t, []mockResponse{
+			{
+				body: fmt.Sprintf(`{"data":{"todos":[{"id":"%s","user_id":"%s","title":"Shared","description":null,"completed":false,"created_at":"%s","updated_at":"%s","shares":[{"role":"viewer"},{"role":"editor"}]}]}}`, todoID, ownerID, now, now),
+			},
+		})
+		defer shutdown()
+
+		service := NewTodoService(client)
+		todos, err := service.GetTodos(userID)
+		if err != nil {
+			t.Fatalf("GetTodos returned error: %v", err)
+		}
+
+		if len(todos) != 1 || todos[0].Permission != model.PermissionEditor {
+			t.Fatalf("unexpected todos: %+v", todos)
+		}
+	})
+
+	t.Run("permission without grant", func(t *testing.T) {
+		client, shutdown := newMockHasuraClient(t, []mockResponse{
+			{body: fmt.Sprintf(`{"data":{"todos_by_pk":{"user_id":"%s","shares":[]}}}`, ownerID)},
+		})
+		defer shutdown()
+
+		service := NewTodoService(client)
+		if _, err := service.Permission(userID, todoID); !errors.Is(err, ErrTodoNotFound) {
+			t.Fatalf("expected ErrTodoNotFound, got %v", err)
+		}
+	})
+}
+
+func TestTodoService_AssignTodo(t *testing.T) {
+	userID := uuid.New()
+	assigneeID := uuid.New()
+	todoID := uuid.New()
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC).Format(time.RFC3339)
+
+	t.Run("assignee with access", func(t *testing.T) {
+		client, shutdown := newMockHasuraClient(t, []mockResponse{
+			{body: fmt.Sprintf(`{"data":{"todos_by_pk":{"user_id":"%s","shares":[]}}}`, userID)},
+			{body: fmt.Sprintf(`{"data":{"todos_by_pk":{"user_id":"%s","shares":[{"role":"viewer"}]}}}`, userID)},
+			{body: fmt.Sprintf(`{"data":{"todos_by_pk":{"id":"%s","assignee_id":null}}}`, todoID)},
+			{body: fmt.Sprintf(`{"data":{"update_todos_by_pk":{"id":"%s","assignee_id":"%s"},"insert_todo_assignment_events_one":{"id":"%s"}}}`, todoID, assigneeID, uuid.New())},
+			{
+				body: fmt.Sprintf(`{"data":{"todos":[{"id":"%s","user_id":"%s","title":"Task","description":null,"completed":false,"assignee_id":"%s","created_at":"%s","updated_at":"%s","shares":[]}]}}`, todoID, userID, assigneeID, now, now),
+			},
+		})
+		defer shutdown()
+
+		service := NewTodoService(client)
+		todo, err := service.AssignTodo(userID, todoID, &assigneeID)
+		if err != nil {
+			t.Fatalf("AssignTodo returned error: %v", err)
+		}
+
+		if todo.AssigneeID == nil || *todo.AssigneeID != assigneeID {
+			t.Fatalf("unexpected todo: %+v", todo)
+		}
+	})
+
+	t.Run("assignee without access", func(t *testing.T) {
+		client, shutdown := newMockHasuraClient(t, []mockResponse{
+			{body: fmt.Sprintf(`{"data":{"todos_by_pk":{"user_id":"%s","shares":[]}}}`, userID)},
+			{body: fmt.Sprintf(`{"data":{"todos_by_pk":{"user_id":"%s","shares":[]}}}`, userID)},
+		})
+		defer shutdown()
+
+		service := NewTodoService(client)
+		_, err := service.AssignTodo(userID, todoID, &assigneeID)
+		if !errors.Is(err, ErrAssigneeNoAccess) {
+			t.Fatalf("expected ErrAssigneeNoAccess, got %v", err)
+		}
+	})
+}
+
 func strPtr(v string) *string { return &v }
 func boolPtr(v bool) *bool    { return &v }
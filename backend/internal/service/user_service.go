@@ -2,45 +2,187 @@ package service
 
 import (
 	"errors"
+	"strings"
+	"time"
+	"todo-app/backend/internal/auth"
 	"todo-app/backend/internal/model"
 
 	"github.com/google/uuid"
 )
 
 var (
-	ErrCannotDeleteSelf = errors.New("cannot delete your own account")
+	ErrCannotDeleteSelf       = errors.New("cannot delete your own account")
+	ErrCannotSuspendSelf      = errors.New("cannot suspend your own account")
+	ErrUserAlreadySuspended   = errors.New("user is already suspended")
+	ErrUserNotSuspended       = errors.New("user is not suspended")
+	ErrInvalidReassignTarget  = errors.New("todos cannot be reassigned to the same user")
+	ErrReassignTargetNotFound = errors.New("target user not found")
+	ErrInvalidUserSort        = errors.New("invalid sort order")
 )
 
 type UserService struct {
-	hasura *HasuraClient
+	hasura      *HasuraClient
+	todos       *TodoService
+	revocations *RevocationService
+	sessions    *SessionService
+	mfa         *MFAService
+	roles       *RoleService
+	audit       *AuditService
 }
 
-func NewUserService(hasura *HasuraClient) *UserService {
-	return &UserService{hasura: hasura}
+func NewUserService(hasura *HasuraClient, revocations *RevocationService, sessions *SessionService, mfa *MFAService, roles *RoleService, audit *AuditService) *UserService {
+	return &UserService{
+		hasura:      hasura,
+		todos:       NewTodoService(hasura),
+		revocations: revocations,
+		sessions:    sessions,
+		mfa:         mfa,
+		roles:       roles,
+		audit:       audit,
+	}
+}
+
+// defaultUserLimit and maxUserLimit bound user listings
+const (
+	defaultUserLimit = 50
+	maxUserLimit     = 500
+)
+
+// userOrders maps each listing order to Hasura's order_by, ascending and
+// descending. Ties fall back to the id so pages never overlap.
+var userOrders = map[string][2]map[string]interface{}{
+	model.UserSortCreatedAt: {{"created_at": "asc"}, {"created_at": "desc"}},
+	model.UserSortEmail:     {{"email": "asc"}, {"email": "desc"}},
+	model.UserSortTodoCount: {
+		{"todos_aggregate": map[string]interface{}{"count": "asc"}},
+		{"todos_aggregate": map[string]interface{}{"count": "desc"}},
+	},
+	model.UserSortLastLogin: {
+		{"sessions_aggregate": map[string]interface{}{"max": map[string]interface{}{"created_at": "asc_nulls_first"}}},
+		{"sessions_aggregate": map[string]interface{}{"max": map[string]interface{}{"created_at": "desc_nulls_last"}}},
+	},
 }
 
-// GetAllUsers retrieves all users (admin function)
-func (s *UserService) GetAllUsers() ([]model.User, error) {
+// likeEscaper escapes the wildcards of a LIKE pattern
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// SearchUsers returns a page of the users matching the filter, newest first
+// unless sorted otherwise, with how many todos each has and when they last
+// signed in, and the number of matching users across all pages (admin
+// function)
+func (s *UserService) SearchUsers(filter model.UserFilter) ([]model.UserSummary, int, error) {
+	sort, descending := strings.TrimPrefix(filter.Sort, "-"), strings.HasPrefix(filter.Sort, "-")
+	if filter.Sort == "" {
+		sort, descending = model.UserSortCreatedAt, true
+	}
+	orders, ok := userOrders[sort]
+	if !ok {
+		return nil, 0, ErrInvalidUserSort
+	}
+	order := orders[0]
+	if descending {
+		order = orders[1]
+	}
+
+	where := map[string]interface{}{}
+	if filter.Query != "" {
+		where["email"] = map[string]interface{}{"_ilike": "%" + likeEscaper.Replace(filter.Query) + "%"}
+	}
+	if filter.Role != "" {
+		where["role"] = map[string]interface{}{"_eq": filter.Role}
+	}
+	if filter.Status != "" {
+		where["status"] = map[string]interface{}{"_eq": filter.Status}
+	}
+	if filter.CreatedAfter != nil {
+		where["created_at"] = map[string]interface{}{"_gt": filter.CreatedAfter.UTC()}
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultUserLimit
+	}
+	if limit > maxUserLimit {
+		limit = maxUserLimit
+	}
+
+	type count struct {
+		Aggregate struct {
+			Count int `json:"count"`
+		} `json:"aggregate"`
+	}
 	var response struct {
-		Users []model.User `json:"users"`
+		Users []struct {
+			model.User
+			Todos     count `json:"todos_aggregate"`
+			Completed count `json:"completed_aggregate"`
+			Sessions  struct {
+				Aggregate struct {
+					Max struct {
+						CreatedAt *time.Time `json:"created_at"`
+					} `json:"max"`
+				} `json:"aggregate"`
+			} `json:"sessions_aggregate"`
+		} `json:"users"`
+		UsersAggregate count `json:"users_aggregate"`
 	}
 
 	err := s.hasura.execute(`
-        query {
-          users(order_by: {created_at: desc}) {
+        query ($where: users_bool_exp!, $orderBy: [users_order_by!]!, $limit: Int!, $offset: Int!) {
+          users(where: $where, order_by: $orderBy, limit: $limit, offset: $offset) {
             id
             email
             role
+            email_verified_at
+            status
+            suspended_at
+            suspension_reason
             created_at
             updated_at
+            todos_aggregate {
+              aggregate {
+                count
+              }
+            }
+            completed_aggregate: todos_aggregate(where: {completed: {_eq: true}}) {
+              aggregate {
+                count
+              }
+            }
+            sessions_aggregate {
+              aggregate {
+                max {
+                  created_at
+                }
+              }
+            }
+          }
+          users_aggregate(where: $where) {
+            aggregate {
+              count
+            }
           }
         }
-        `, nil, &response)
+        `, map[string]interface{}{
+		"where":   where,
+		"orderBy": []map[string]interface{}{order, {"id": "asc"}},
+		"limit":   limit,
+		"offset":  filter.Offset,
+	}, &response)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
-	return response.Users, nil
+	users := make([]model.UserSummary, 0, len(response.Users))
+	for _, user := range response.Users {
+		users = append(users, model.UserSummary{
+			User:           user.User,
+			TodoCount:      user.Todos.Aggregate.Count,
+			CompletedCount: user.Completed.Aggregate.Count,
+			LastLoginAt:    user.Sessions.Aggregate.Max.CreatedAt,
+		})
+	}
+	return users, response.UsersAggregate.Aggregate.Count, nil
 }
 
 // GetUser retrieves a specific user by ID (admin function)
@@ -55,6 +197,10 @@ func (s *UserService) GetUser(userID uuid.UUID) (*model.User, error) {
             id
             email
             role
+            email_verified_at
+            status
+            suspended_at
+            suspension_reason
             created_at
             updated_at
           }
@@ -71,18 +217,37 @@ func (s *UserService) GetUser(userID uuid.UUID) (*model.User, error) {
 	return response.UsersByPk, nil
 }
 
-// UpdateUserRole updates a user's role (admin function)
-func (s *UserService) UpdateUserRole(userID uuid.UUID, role string) (*model.User, error) {
+// UpdateUserRole updates a user's role (admin function). The actor must hold
+// every permission of both the user's current role and the new one, so
+// nobody can hand out or take away more than they have.
+func (s *UserService) UpdateUserRole(actorRole string, userID uuid.UUID, role string) (*model.User, error) {
+	user, err := s.GetUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range []string{user.Role, role} {
+		ok, err := s.roles.CanAssign(actorRole, r)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, ErrPermissionNotHeld
+		}
+	}
+
 	var response struct {
 		UpdateUsersByPk *model.User `json:"update_users_by_pk"`
 	}
 
-	err := s.hasura.execute(`
+	err = s.hasura.execute(`
         mutation ($id: uuid!, $role: String!) {
           update_users_by_pk(pk_columns: {id: $id}, _set: {role: $role}) {
             id
             email
             role
+            email_verified_at
+            status
             created_at
             updated_at
           }
@@ -99,13 +264,26 @@ func (s *UserService) UpdateUserRole(userID uuid.UUID, role string) (*model.User
 	return response.UpdateUsersByPk, nil
 }
 
-// DeleteUser deletes a user (admin function)
-func (s *UserService) DeleteUser(currentUserID, targetUserID uuid.UUID) error {
+// DeleteUser deletes a user (admin function), first handing their todos to
+// reassignTo when given. The actor must hold every permission of the user's
+// role.
+func (s *UserService) DeleteUser(actor *auth.Claims, userID uuid.UUID, reassignTo *uuid.UUID) error {
 	// Prevent self-deletion
-	if currentUserID == targetUserID {
+	if actor.UserID == userID {
 		return ErrCannotDeleteSelf
 	}
 
+	if err := s.checkManageable(actor.Role, userID); err != nil {
+		return err
+	}
+
+	// Hand the user's todos to someone else before they cascade away
+	if reassignTo != nil {
+		if _, err := s.reassignTodos(actor, userID, *reassignTo); err != nil {
+			return err
+		}
+	}
+
 	var response struct {
 		DeleteUsersByPk *struct {
 			ID uuid.UUID `json:"id"`
@@ -118,7 +296,7 @@ func (s *UserService) DeleteUser(currentUserID, targetUserID uuid.UUID) error {
             id
           }
         }
-        `, map[string]interface{}{"id": targetUserID}, &response)
+        `, map[string]interface{}{"id": userID}, &response)
 	if err != nil {
 		return err
 	}
@@ -130,28 +308,337 @@ func (s *UserService) DeleteUser(currentUserID, targetUserID uuid.UUID) error {
 	return nil
 }
 
-// GetAllTodos retrieves all todos from all users (admin function)
-func (s *UserService) GetAllTodos() ([]model.Todo, error) {
+// SuspendUser stops a user from signing in and ends every session and token
+// they have, keeping their data (admin function). The actor must hold every
+// permission of the user's role.
+func (s *UserService) SuspendUser(actor *auth.Claims, userID uuid.UUID, reason string) (*model.User, error) {
+	if actor.UserID == userID {
+		return nil, ErrCannotSuspendSelf
+	}
+
+	if err := s.checkManageable(actor.Role, userID); err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		UpdateUsers struct {
+			Returning []model.User `json:"returning"`
+		} `json:"update_users"`
+	}
+
+	err := s.hasura.execute(`
+        mutation ($id: uuid!, $suspended: String!, $now: timestamptz!, $reason: String!) {
+          update_users(where: {id: {_eq: $id}, status: {_neq: $suspended}}, _set: {status: $suspended, suspended_at: $now, suspension_reason: $reason}) {
+            returning {
+              id
+              email
+              role
+              email_verified_at
+              status
+              suspended_at
+              suspension_reason
+              created_at
+              updated_at
+            }
+          }
+        }
+        `, map[string]interface{}{
+		"id":        userID,
+		"suspended": model.UserStatusSuspended,
+		"now":       time.Now().UTC(),
+		"reason":    reason,
+	}, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(response.UpdateUsers.Returning) == 0 {
+		return nil, ErrUserAlreadySuspended
+	}
+
+	if err := s.revocations.RevokeUser(userID); err != nil {
+		return nil, err
+	}
+	s.revocations.rememberSuspension(userID, true)
+
+	if err := s.recordAudit(actor, model.AuditActionUserSuspend, userID, map[string]interface{}{"reason": reason}); err != nil {
+		return nil, err
+	}
+
+	return &response.UpdateUsers.Returning[0], nil
+}
+
+// ReactivateUser lets a suspended user sign in again (admin function). An
+// account that was pending deletion when it was suspended is pending
+// deletion again. Tokens revoked by the suspension stay revoked.
+func (s *UserService) ReactivateUser(actor *auth.Claims, userID uuid.UUID) (*model.User, error) {
+	if err := s.checkManageable(actor.Role, userID); err != nil {
+		return nil, err
+	}
+
+	type updated struct {
+		Returning []model.User `json:"returning"`
+	}
+	var response struct {
+		Active  updated `json:"active"`
+		Pending updated `json:"pending"`
+	}
+
+	err := s.hasura.execute(`
+        mutation ($id: uuid!, $suspended: String!, $active: String!, $pending: String!) {
+          active: update_users(where: {id: {_eq: $id}, status: {_eq: $suspended}, deletion_scheduled_at: {_is_null: true}}, _set: {status: $active, suspended_at: null, suspension_reason: ""}) {
+            returning {
+              id
+              email
+              role
+              email_verified_at
+              status
+              created_at
+              updated_at
+            }
+          }
+          pending: update_users(where: {id: {_eq: $id}, status: {_eq: $suspended}, deletion_scheduled_at: {_is_null: false}}, _set: {status: $pending, suspended_at: null, suspension_reason: ""}) {
+            returning {
+              id
+              email
+              role
+              email_verified_at
+              status
+              created_at
+              updated_at
+            }
+          }
+        }
+        `, map[string]interface{}{
+		"id":        userID,
+		"suspended": model.UserStatusSuspended,
+		"active":    model.UserStatusActive,
+		"pending":   model.UserStatusPendingDeletion,
+	}, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	users := append(response.Active.Returning, response.Pending.Returning...)
+	if len(users) == 0 {
+		return nil, ErrUserNotSuspended
+	}
+
+	s.revocations.rememberSuspension(userID, false)
+
+	if err := s.recordAudit(actor, model.AuditActionUserReactivate, userID, nil); err != nil {
+		return nil, err
+	}
+
+	return &users[0], nil
+}
+
+// checkManageable returns ErrUserNotFound for unknown users and
+// ErrPermissionNotHeld when the user's role grants more than actorRole
+func (s *UserService) checkManageable(actorRole string, userID uuid.UUID) error {
+	user, err := s.GetUser(userID)
+	if err != nil {
+		return err
+	}
+
+	ok, err := s.roles.CanAssign(actorRole, user.Role)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrPermissionNotHeld
+	}
+	return nil
+}
+
+// recordAudit logs an administrator's action on a user
+func (s *UserService) recordAudit(actor *auth.Claims, action string, userID uuid.UUID, details map[string]interface{}) error {
+	actorID := actor.UserID
+	return s.audit.Record(model.AuditLog{
+		Action:     action,
+		ActorID:    &actorID,
+		ActorEmail: actor.Email,
+		UserID:     userID,
+		Details:    details,
+	})
+}
+
+// GetAllTodos retrieves all todos from all users matching the filter (admin function)
+func (s *UserService) GetAllTodos(filter model.TodoFilter) ([]model.Todo, error) {
 	var response struct {
 		Todos []model.Todo `json:"todos"`
 	}
 
+	where := map[string]interface{}{}
+	applyTodoFilter(where, filter)
+
 	err := s.hasura.execute(`
-        query {
-          todos(order_by: {created_at: desc}) {
+        query ($where: todos_bool_exp!) {
+          todos(where: $where, order_by: {created_at: desc}) {
             id
             user_id
             title
             description
             completed
+            assignee_id
             created_at
             updated_at
           }
         }
-        `, nil, &response)
+        `, map[string]interface{}{"where": where}, &response)
 	if err != nil {
 		return nil, err
 	}
 
 	return response.Todos, nil
 }
+
+// AssignTodo sets the assignee of any todo (admin function)
+func (s *UserService) AssignTodo(adminID, todoID uuid.UUID, assigneeID *uuid.UUID) (*model.Todo, error) {
+	return s.todos.assign(adminID, todoID, assigneeID)
+}
+
+// ReassignTodos moves ownership and assignments of every todo from one user
+// to another and returns the number of todos whose owner changed (admin
+// function). Assignments only move on todos the new user can access, as with
+// any assignment; the rest stay with the old user. The actor must hold every
+// permission of the old user's role.
+func (s *UserService) ReassignTodos(actor *auth.Claims, fromUserID, toUserID uuid.UUID) (int, error) {
+	if fromUserID == toUserID {
+		return 0, ErrInvalidReassignTarget
+	}
+
+	if err := s.checkManageable(actor.Role, fromUserID); err != nil {
+		return 0, err
+	}
+
+	return s.reassignTodos(actor, fromUserID, toUserID)
+}
+
+// reassignTodos moves the todos of a user the actor may manage and records
+// it in the audit log
+func (s *UserService) reassignTodos(actor *auth.Claims, fromUserID, toUserID uuid.UUID) (int, error) {
+	if fromUserID == toUserID {
+		return 0, ErrInvalidReassignTarget
+	}
+
+	if _, err := s.GetUser(toUserID); err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return 0, ErrReassignTargetNotFound
+		}
+		return 0, err
+	}
+
+	// The new user can access the todos they are about to own, and those
+	// they can already see
+	var assigned struct {
+		Todos []struct {
+			ID uuid.UUID `json:"id"`
+		} `json:"todos"`
+	}
+
+	err := s.hasura.execute(`
+        query ($where: todos_bool_exp!) {
+          todos(where: $where) {
+            id
+          }
+        }
+        `, map[string]interface{}{
+		"where": map[string]interface{}{
+			"assignee_id": map[string]interface{}{"_eq": fromUserID},
+			"_or": []interface{}{
+				map[string]interface{}{"user_id": map[string]interface{}{"_eq": fromUserID}},
+				todoAccessWhere(toUserID, model.PermissionViewer),
+			},
+		},
+	}, &assigned)
+	if err != nil {
+		return 0, err
+	}
+
+	todoIDs := make([]uuid.UUID, 0, len(assigned.Todos))
+	events := make([]map[string]interface{}, 0, len(assigned.Todos))
+	for _, todo := range assigned.Todos {
+		todoIDs = append(todoIDs, todo.ID)
+		events = append(events, map[string]interface{}{
+			"todo_id":              todo.ID,
+			"assignee_id":          toUserID,
+			"previous_assignee_id": fromUserID,
+			"assigned_by":          actor.UserID,
+		})
+	}
+
+	var response struct {
+		Owned struct {
+			AffectedRows int `json:"affected_rows"`
+		} `json:"owned"`
+	}
+
+	err = s.hasura.execute(`
+        mutation ($from: uuid!, $to: uuid!, $todoIds: [uuid!]!, $events: [todo_assignment_events_insert_input!]!) {
+          owned: update_todos(where: {user_id: {_eq: $from}}, _set: {user_id: $to}) {
+            affected_rows
+          }
+          assigned: update_todos(where: {id: {_in: $todoIds}, assignee_id: {_eq: $from}}, _set: {assignee_id: $to}) {
+            affected_rows
+          }
+          insert_todo_assignment_events(objects: $events) {
+            affected_rows
+          }
+        }
+        `, map[string]interface{}{
+		"from":    fromUserID,
+		"to":      toUserID,
+		"todoIds": todoIDs,
+		"events":  events,
+	}, &response)
+	if err != nil {
+		return 0, err
+	}
+
+	err = s.recordAudit(actor, model.AuditActionUserReassignTodos, fromUserID, map[string]interface{}{
+		"to_user_id": toUserID,
+		"owned":      response.Owned.AffectedRows,
+		"assigned":   len(todoIDs),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return response.Owned.AffectedRows, nil
+}
+
+// RevokeSessions signs the user out everywhere (admin function). The actor
+// must hold every permission of the user's role.
+func (s *UserService) RevokeSessions(actor *auth.Claims, userID uuid.UUID) error {
+	if err := s.checkManageable(actor.Role, userID); err != nil {
+		return err
+	}
+	return s.revocations.RevokeUser(userID)
+}
+
+// GetUserSessions retrieves a user's active sessions (admin function)
+func (s *UserService) GetUserSessions(userID uuid.UUID) ([]model.Session, error) {
+	if _, err := s.GetUser(userID); err != nil {
+		return nil, err
+	}
+	return s.sessions.ListSessions(userID, uuid.Nil)
+}
+
+// RevokeUserSession signs one of a user's sessions out (admin function). The
+// actor must hold every permission of the user's role.
+func (s *UserService) RevokeUserSession(actor *auth.Claims, userID, sessionID uuid.UUID) error {
+	if err := s.checkManageable(actor.Role, userID); err != nil {
+		return err
+	}
+	return s.sessions.RevokeSession(userID, sessionID)
+}
+
+// ResetMFA removes a user's second factor so they can enroll again (admin
+// function). The actor must hold every permission of the user's role.
+func (s *UserService) ResetMFA(actor *auth.Claims, userID uuid.UUID) error {
+	if err := s.checkManageable(actor.Role, userID); err != nil {
+		return err
+	}
+	return s.mfa.Reset(userID)
+}
@@ -0,0 +1,252 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+	"todo-app/backend/internal/auth"
+	"todo-app/backend/internal/model"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+func TestUserService_SuspendUser(t *testing.T) {
+	actor := &auth.Claims{UserID: uuid.New(), Email: "admin@example.com", Role: auth.RoleAdmin}
+	userID := uuid.New()
+
+	newService := func(client *HasuraClient) (*UserService, *RevocationService) {
+		revocations := NewRevocationService(client, RevocationOptions{CacheSize: 10, CacheTTL: time.Minute})
+		sessions := NewSessionService(client, revocations, SessionOptions{TouchInterval: time.Minute, CacheSize: 10})
+		return NewUserService(client, revocations, sessions, nil, newTestRoleService(client), NewAuditService(client)), revocations
+	}
+	userRecord := mockResponse{body: fmt.Sprintf(`{"data":{"users_by_pk":{"id":"%s","email":"alice@example.com","role":"user","status":"active","created_at":"2026-01-01T00:00:00Z","updated_at":"2026-01-01T00:00:00Z"}}}`, userID)}
+
+	t.Run("suspends, revokes and records", func(t *testing.T) {
+		var recorded map[string]interface{}
+		client, shutdown := newMockHasuraClient(t, []mockResponse{
+			userRecord,
+			{body: fmt.Sprintf(`{"data":{"update_users":{"returning":[{"id":"%s","email":"alice@example.com","role":"user","status":"suspended","suspension_reason":"spam","created_at":"2026-01-01T00:00:00Z","updated_at":"2026-01-01T00:00:00Z"}]}}}`, userID)},
+			{body: fmt.Sprintf(`{"data":{"update_users_by_pk":{"id":"%s"},"update_refresh_tokens":{"affected_rows":1}}}`, userID)},
+			{respond: func(vars map[string]interface{}) string {
+				recorded = vars["object"].(map[string]interface{})
+				return fmt.Sprintf(`{"data":{"insert_audit_logs_one":{"id":"%s"}}}`, uuid.New())
+			}},
+		})
+		defer shutdown()
+
+		service, revocations := newService(client)
+		user, err := service.SuspendUser(actor, userID, "spam")
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if user.Status != model.UserStatusSuspended || user.SuspensionReason != "spam" {
+			t.Fatalf("unexpected user %+v", user)
+		}
+
+		details := recorded["details"].(map[string]interface{})
+		if recorded["action"] != model.AuditActionUserSuspend || recorded["user_id"] != userID.String() || details["reason"] != "spam" {
+			t.Fatalf("unexpected audit entry %+v", recorded)
+		}
+
+		// Tokens issued after the suspension are refused without a lookup
+		now := time.Now().Add(time.Second)
+		claims := &auth.Claims{
+			UserID:    userID,
+			SessionID: uuid.New(),
+			RegisteredClaims: jwt.RegisteredClaims{
+				ID:        uuid.NewString(),
+				IssuedAt:  jwt.NewNumericDate(now),
+				ExpiresAt: jwt.NewNumericDate(now.Add(15 * time.Minute)),
+			},
+		}
+		if revoked, err := revocations.IsRevoked(claims); !revoked || !errors.Is(err, ErrUserSuspended) {
+			t.Fatalf("expected ErrUserSuspended, got %v (%v)", revoked, err)
+		}
+	})
+
+	t.Run("already suspended", func(t *testing.T) {
+		client, shutdown := newMockHasuraClient(t, []mockResponse{
+			userRecord,
+			{body: `{"data":{"update_users":{"returning":[]}}}`},
+		})
+		defer shutdown()
+
+		service, _ := newService(client)
+		if _, err := service.SuspendUser(actor, userID, "spam"); !errors.Is(err, ErrUserAlreadySuspended) {
+			t.Fatalf("expected ErrUserAlreadySuspended, got %v", err)
+		}
+	})
+
+	t.Run("cannot suspend self", func(t *testing.T) {
+		client, shutdown := newMockHasuraClient(t, nil)
+		defer shutdown()
+
+		service, _ := newService(client)
+		if _, err := service.SuspendUser(actor, actor.UserID, "spam"); !errors.Is(err, ErrCannotSuspendSelf) {
+			t.Fatalf("expected ErrCannotSuspendSelf, got %v", err)
+		}
+	})
+}
+
+func TestUserService_SearchUsers(t *testing.T) {
+	userID := uuid.New()
+
+	t.Run("filters, sorts and counts", func(t *testing.T) {
+		var variables map[string]interface{}
+		client, shutdown := newMockHasuraClient(t, []mockResponse{
+			{respond: func(vars map[string]interface{}) string {
+				variables = vars
+				return fmt.Sprintf(`{"data":{"users":[{"id":"%s","email":"alice@example.com","role":"user","status":"active","created_at":"2026-01-01T00:00:00Z","updated_at":"2026-01-01T00:00:00Z","todos_aggregate":{"aggregate":{"count":5}},"completed_aggregate":{"aggregate":{"count":2}},"sessions_aggregate":{"aggregate":{"max":{"created_at":"2026-02-01T00:00:00Z"}}}}],"users_aggregate":{"aggregate":{"count":12}}}}`, userID)
+			}},
+		})
+		defer shutdown()
+
+		service := NewUserService(client, nil, nil, nil, nil, nil)
+		users, total, err := service.SearchUsers(model.UserFilter{Query: "100%_al", Status: model.UserStatusActive, Sort: "-todo_count", Limit: 10000})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if total != 12 || len(users) != 1 {
+			t.Fatalf("expected 1 of 12 users, got %d of %d", len(users), total)
+		}
+		user := users[0]
+		if user.ID != userID || user.TodoCount != 5 || user.CompletedCount != 2 || user.LastLoginAt == nil || user.LastLoginAt.Month() != time.February {
+			t.Fatalf("unexpected user %+v", user)
+		}
+
+		where := variables["where"].(map[string]interface{})
+		if email := where["email"].(map[string]interface{}); email["_ilike"] != `%100\%\_al%` {
+			t.Fatalf("expected wildcards to be escaped, got %v", email)
+		}
+		if status := where["status"].(map[string]interface{}); status["_eq"] != model.UserStatusActive {
+			t.Fatalf("unexpected status filter %v", status)
+		}
+		orderBy := variables["orderBy"].([]interface{})
+		if order := orderBy[0].(map[string]interface{})["todos_aggregate"].(map[string]interface{}); order["count"] != "desc" {
+			t.Fatalf("unexpected order %v", orderBy)
+		}
+		if variables["limit"] != float64(maxUserLimit) {
+			t.Fatalf("expected the limit to be capped, got %v", variables["limit"])
+		}
+	})
+
+	t.Run("invalid sort", func(t *testing.T) {
+		client, shutdown := newMockHasuraClient(t, nil)
+		defer shutdown()
+
+		service := NewUserService(client, nil, nil, nil, nil, nil)
+		if _, _, err := service.SearchUsers(model.UserFilter{Sort: "password_hash"}); !errors.Is(err, ErrInvalidUserSort) {
+			t.Fatalf("expected ErrInvalidUserSort, got %v", err)
+		}
+	})
+}
+
+func TestUserService_ManageRequiresTargetPermissions(t *testing.T) {
+	support := &auth.Claims{UserID: uuid.New(), Email: "support@example.com", Role: "support"}
+	adminID := uuid.New()
+	adminRecord := mockResponse{body: fmt.Sprintf(`{"data":{"users_by_pk":{"id":"%s","email":"admin@example.com","role":"admin","status":"active","created_at":"2026-01-01T00:00:00Z","updated_at":"2026-01-01T00:00:00Z"}}}`, adminID)}
+
+	// Only the lookups are expected: nothing is changed
+	client, shutdown := newMockHasuraClient(t, []mockResponse{
+		adminRecord,
+		roleResponse(`["users.read","users.write"]`),
+		adminRecord,
+		adminRecord,
+		adminRecord,
+		adminRecord,
+	})
+	defer shutdown()
+
+	revocations := NewRevocationService(client, RevocationOptions{CacheSize: 10, CacheTTL: time.Minute})
+	sessions := NewSessionService(client, revocations, SessionOptions{TouchInterval: time.Minute, CacheSize: 10})
+	service := NewUserService(client, revocations, sessions, nil, newTestRoleService(client), NewAuditService(client))
+
+	actions := []struct {
+		name string
+		run  func() error
+	}{
+		{"delete", func() error { return service.DeleteUser(support, adminID, nil) }},
+		{"revoke sessions", func() error { return service.RevokeSessions(support, adminID) }},
+		{"revoke session", func() error { return service.RevokeUserSession(support, adminID, uuid.New()) }},
+		{"reset mfa", func() error { return service.ResetMFA(support, adminID) }},
+		{"reassign todos", func() error { _, err := service.ReassignTodos(support, adminID, uuid.New()); return err }},
+	}
+	for _, action := range actions {
+		if err := action.run(); !errors.Is(err, ErrPermissionNotHeld) {
+			t.Fatalf("%s: expected ErrPermissionNotHeld, got %v", action.name, err)
+		}
+	}
+}
+
+func TestUserService_DeleteUser(t *testing.T) {
+	actor := &auth.Claims{UserID: uuid.New(), Email: "admin@example.com", Role: auth.RoleAdmin}
+	userID := uuid.New()
+
+	client, shutdown := newMockHasuraClient(t, []mockResponse{
+		{body: fmt.Sprintf(`{"data":{"users_by_pk":{"id":"%s","email":"alice@example.com","role":"user","status":"active","created_at":"2026-01-01T00:00:00Z","updated_at":"2026-01-01T00:00:00Z"}}}`, userID)},
+		{body: fmt.Sprintf(`{"data":{"delete_users_by_pk":{"id":"%s"}}}`, userID)},
+	})
+	defer shutdown()
+
+	service := NewUserService(client, nil, nil, nil, newTestRoleService(client), nil)
+	if err := service.DeleteUser(actor, userID, nil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := service.DeleteUser(actor, actor.UserID, nil); !errors.Is(err, ErrCannotDeleteSelf) {
+		t.Fatalf("expected ErrCannotDeleteSelf, got %v", err)
+	}
+}
+
+func TestUserService_ReassignTodos(t *testing.T) {
+	actor := &auth.Claims{UserID: uuid.New(), Email: "admin@example.com", Role: auth.RoleAdmin}
+	adminID, fromID, toID := actor.UserID, uuid.New(), uuid.New()
+	visibleID := uuid.New()
+
+	var where, mutation, audited map[string]interface{}
+	client, shutdown := newMockHasuraClient(t, []mockResponse{
+		{body: fmt.Sprintf(`{"data":{"users_by_pk":{"id":"%s","email":"alice@example.com","role":"user","status":"active","created_at":"2026-01-01T00:00:00Z","updated_at":"2026-01-01T00:00:00Z"}}}`, fromID)},
+		{body: fmt.Sprintf(`{"data":{"users_by_pk":{"id":"%s","email":"bob@example.com","role":"user","status":"active","created_at":"2026-01-01T00:00:00Z","updated_at":"2026-01-01T00:00:00Z"}}}`, toID)},
+		{respond: func(vars map[string]interface{}) string {
+			where = vars["where"].(map[string]interface{})
+			return fmt.Sprintf(`{"data":{"todos":[{"id":"%s"}]}}`, visibleID)
+		}},
+		{respond: func(vars map[string]interface{}) string {
+			mutation = vars
+			return `{"data":{"owned":{"affected_rows":3},"assigned":{"affected_rows":1},"insert_todo_assignment_events":{"affected_rows":1}}}`
+		}},
+		{respond: func(vars map[string]interface{}) string {
+			audited = vars["object"].(map[string]interface{})
+			return fmt.Sprintf(`{"data":{"insert_audit_logs_one":{"id":"%s"}}}`, uuid.New())
+		}},
+	})
+	defer shutdown()
+
+	service := NewUserService(client, nil, nil, nil, newTestRoleService(client), NewAuditService(client))
+	count, err := service.ReassignTodos(actor, fromID, toID)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if count != 3 {
+		t.Fatalf("expected 3 todos to change owner, got %d", count)
+	}
+
+	// Only todos the new user owns or can access are looked up
+	if len(where["_or"].([]interface{})) != 2 {
+		t.Fatalf("expected an access check, got %v", where)
+	}
+
+	todoIDs := mutation["todoIds"].([]interface{})
+	events := mutation["events"].([]interface{})
+	if len(todoIDs) != 1 || todoIDs[0] != visibleID.String() || len(events) != 1 {
+		t.Fatalf("expected only the accessible todo to be reassigned, got %v and %v", todoIDs, events)
+	}
+	event := events[0].(map[string]interface{})
+	if event["assignee_id"] != toID.String() || event["previous_assignee_id"] != fromID.String() || event["assigned_by"] != adminID.String() {
+		t.Fatalf("unexpected event %v", event)
+	}
+	if audited["action"] != "user.reassign_todos" || audited["user_id"] != fromID.String() || audited["actor_id"] != adminID.String() {
+		t.Fatalf("expected the reassignment to be audited, got %v", audited)
+	}
+}
@@ -0,0 +1,367 @@
+package service
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+	"todo-app/backend/internal/auth"
+	"todo-app/backend/internal/model"
+	"todo-app/backend/internal/webauthn"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidWebAuthnChallenge   = errors.New("invalid or expired challenge")
+	ErrWebAuthnVerification       = errors.New("passkey verification failed")
+	ErrWebAuthnCredentialExists   = errors.New("passkey already registered")
+	ErrWebAuthnCredentialNotFound = errors.New("passkey not found")
+)
+
+// Ceremonies a WebAuthn challenge can be issued for
+const (
+	webauthnPurposeRegistration = "registration"
+	webauthnPurposeLogin        = "login"
+)
+
+type WebAuthnOptions struct {
+	// ChallengeTTL is how long a ceremony may take
+	ChallengeTTL time.Duration
+}
+
+// WebAuthnService registers passkeys and verifies passkey logins. Challenges
+// are stored hashed and deleted when used, so each response is accepted once.
+type WebAuthnService struct {
+	hasura   *HasuraClient
+	accounts *AccountService
+	config   *webauthn.Config
+	opts     WebAuthnOptions
+}
+
+func NewWebAuthnService(hasura *HasuraClient, accounts *AccountService, config *webauthn.Config, opts WebAuthnOptions) *WebAuthnService {
+	return &WebAuthnService{
+		hasura:   hasura,
+		accounts: accounts,
+		config:   config,
+		opts:     opts,
+	}
+}
+
+// BeginRegistration returns the options for creating a passkey for the user
+func (s *WebAuthnService) BeginRegistration(userID uuid.UUID, password string) (*webauthn.CreationOptions, error) {
+	email, err := s.accounts.checkPassword(userID, password)
+	if err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		WebauthnCredentials []struct {
+			CredentialID string `json:"credential_id"`
+		} `json:"webauthn_credentials"`
+	}
+
+	err = s.hasura.execute(`
+        query ($userId: uuid!) {
+          webauthn_credentials(where: {user_id: {_eq: $userId}}) {
+            credential_id
+          }
+        }
+        `, map[string]interface{}{"userId": userID}, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	exclude := make([][]byte, 0, len(response.WebauthnCredentials))
+	for _, credential := range response.WebauthnCredentials {
+		if id, err := base64.RawURLEncoding.DecodeString(credential.CredentialID); err == nil {
+			exclude = append(exclude, id)
+		}
+	}
+
+	challenge, err := s.storeChallenge(&userID, webauthnPurposeRegistration)
+	if err != nil {
+		return nil, err
+	}
+
+	user := webauthn.User{ID: userID[:], Name: email, DisplayName: email}
+	return s.config.NewCreationOptions(challenge, user, exclude), nil
+}
+
+// FinishRegistration verifies the browser's response and stores the passkey
+func (s *WebAuthnService) FinishRegistration(userID uuid.UUID, name string, credential *webauthn.RegistrationCredential) (*model.WebAuthnCredential, error) {
+	clientData, err := webauthn.ParseClientData(credential.Response.ClientDataJSON)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrWebAuthnVerification, err)
+	}
+
+	owner, err := s.consumeChallenge(clientData.Challenge, webauthnPurposeRegistration)
+	if err != nil {
+		return nil, err
+	}
+	if owner == nil || *owner != userID {
+		return nil, ErrInvalidWebAuthnChallenge
+	}
+
+	verified, err := s.config.VerifyRegistration(credential, clientData.Challenge)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrWebAuthnVerification, err)
+	}
+
+	var response struct {
+		InsertWebauthnCredentialsOne *model.WebAuthnCredential `json:"insert_webauthn_credentials_one"`
+	}
+
+	err = s.hasura.execute(`
+        mutation ($userId: uuid!, $credentialId: String!, $publicKey: String!, $signCount: bigint!, $name: String!) {
+          insert_webauthn_credentials_one(
+            object: {user_id: $userId, credential_id: $credentialId, public_key: $publicKey, sign_count: $signCount, name: $name},
+            on_conflict: {constraint: webauthn_credentials_credential_id_key, update_columns: []}
+          ) {
+            id
+            name
+            created_at
+            last_used_at
+          }
+        }
+        `, map[string]interface{}{
+		"userId":       userID,
+		"credentialId": base64.RawURLEncoding.EncodeToString(verified.ID),
+		"publicKey":    base64.StdEncoding.EncodeToString(verified.PublicKey),
+		"signCount":    verified.SignCount,
+		"name":         name,
+	}, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.InsertWebauthnCredentialsOne == nil {
+		return nil, ErrWebAuthnCredentialExists
+	}
+
+	return response.InsertWebauthnCredentialsOne, nil
+}
+
+// BeginLogin returns the options for signing in with any passkey
+func (s *WebAuthnService) BeginLogin() (*webauthn.RequestOptions, error) {
+	challenge, err := s.storeChallenge(nil, webauthnPurposeLogin)
+	if err != nil {
+		return nil, err
+	}
+	return s.config.NewRequestOptions(challenge), nil
+}
+
+// FinishLogin verifies a passkey assertion and returns the user it belongs to
+func (s *WebAuthnService) FinishLogin(credential *webauthn.AssertionCredential) (uuid.UUID, error) {
+	clientData, err := webauthn.ParseClientData(credential.Response.ClientDataJSON)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%w: %v", ErrWebAuthnVerification, err)
+	}
+
+	if _, err := s.consumeChallenge(clientData.Challenge, webauthnPurposeLogin); err != nil {
+		return uuid.Nil, err
+	}
+
+	var response struct {
+		WebauthnCredentials []struct {
+			ID        uuid.UUID `json:"id"`
+			UserID    uuid.UUID `json:"user_id"`
+			PublicKey string    `json:"public_key"`
+			SignCount uint32    `json:"sign_count"`
+		} `json:"webauthn_credentials"`
+	}
+
+	err = s.hasura.execute(`
+        query ($credentialId: String!) {
+          webauthn_credentials(where: {credential_id: {_eq: $credentialId}}, limit: 1) {
+            id
+            user_id
+            public_key
+            sign_count
+          }
+        }
+        `, map[string]interface{}{"credentialId": base64.RawURLEncoding.EncodeToString(credential.RawID)}, &response)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	// An unknown credential fails like a bad signature, so responses do not
+	// reveal which passkeys are registered
+	if len(response.WebauthnCredentials) == 0 {
+		return uuid.Nil, ErrWebAuthnVerification
+	}
+
+	record := response.WebauthnCredentials[0]
+
+	handle := credential.Response.UserHandle
+	if len(handle) > 0 && string(handle) != string(record.UserID[:]) {
+		return uuid.Nil, ErrWebAuthnVerification
+	}
+
+	publicKey, err := base64.StdEncoding.DecodeString(record.PublicKey)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	signCount, err := s.config.VerifyAssertion(credential, clientData.Challenge, publicKey, record.SignCount)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%w: %v", ErrWebAuthnVerification, err)
+	}
+
+	var updateResp struct {
+		UpdateWebauthnCredentialsByPk *struct {
+			ID uuid.UUID `json:"id"`
+		} `json:"update_webauthn_credentials_by_pk"`
+	}
+
+	err = s.hasura.execute(`
+        mutation ($id: uuid!, $signCount: bigint!, $now: timestamptz!) {
+          update_webauthn_credentials_by_pk(pk_columns: {id: $id}, _set: {sign_count: $signCount, last_used_at: $now}) {
+            id
+          }
+        }
+        `, map[string]interface{}{"id": record.ID, "signCount": signCount, "now": time.Now().UTC()}, &updateResp)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	return record.UserID, nil
+}
+
+// ListCredentials retrieves the user's passkeys
+func (s *WebAuthnService) ListCredentials(userID uuid.UUID) ([]model.WebAuthnCredential, error) {
+	var response struct {
+		WebauthnCredentials []model.WebAuthnCredential `json:"webauthn_credentials"`
+	}
+
+	err := s.hasura.execute(`
+        query ($userId: uuid!) {
+          webauthn_credentials(where: {user_id: {_eq: $userId}}, order_by: {created_at: asc}) {
+            id
+            name
+            created_at
+            last_used_at
+          }
+        }
+        `, map[string]interface{}{"userId": userID}, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	return response.WebauthnCredentials, nil
+}
+
+// DeleteCredential removes one of the user's passkeys
+func (s *WebAuthnService) DeleteCredential(userID, credentialID uuid.UUID) error {
+	var response struct {
+		DeleteWebauthnCredentials struct {
+			AffectedRows int `json:"affected_rows"`
+		} `json:"delete_webauthn_credentials"`
+	}
+
+	err := s.hasura.execute(`
+        mutation ($id: uuid!, $userId: uuid!) {
+          delete_webauthn_credentials(where: {id: {_eq: $id}, user_id: {_eq: $userId}}) {
+            affected_rows
+          }
+        }
+        `, map[string]interface{}{"id": credentialID, "userId": userID}, &response)
+	if err != nil {
+		return err
+	}
+
+	if response.DeleteWebauthnCredentials.AffectedRows == 0 {
+		return ErrWebAuthnCredentialNotFound
+	}
+
+	return nil
+}
+
+// PurgeExpiredChallenges removes challenges of abandoned ceremonies
+func (s *WebAuthnService) PurgeExpiredChallenges() (int, error) {
+	var response struct {
+		DeleteWebauthnChallenges struct {
+			AffectedRows int `json:"affected_rows"`
+		} `json:"delete_webauthn_challenges"`
+	}
+
+	err := s.hasura.execute(`
+        mutation ($now: timestamptz!) {
+          delete_webauthn_challenges(where: {expires_at: {_lt: $now}}) {
+            affected_rows
+          }
+        }
+        `, map[string]interface{}{"now": time.Now().UTC()}, &response)
+	if err != nil {
+		return 0, err
+	}
+
+	return response.DeleteWebauthnChallenges.AffectedRows, nil
+}
+
+// storeChallenge creates a challenge for a ceremony
+func (s *WebAuthnService) storeChallenge(userID *uuid.UUID, purpose string) (string, error) {
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		return "", err
+	}
+
+	var response struct {
+		InsertWebauthnChallengesOne struct {
+			ChallengeHash string `json:"challenge_hash"`
+		} `json:"insert_webauthn_challenges_one"`
+	}
+
+	err = s.hasura.execute(`
+        mutation ($challengeHash: String!, $userId: uuid, $purpose: String!, $expiresAt: timestamptz!) {
+          insert_webauthn_challenges_one(object: {challenge_hash: $challengeHash, user_id: $userId, purpose: $purpose, expires_at: $expiresAt}) {
+            challenge_hash
+          }
+        }
+        `, map[string]interface{}{
+		"challengeHash": auth.HashOpaqueToken(challenge),
+		"userId":        userID,
+		"purpose":       purpose,
+		"expiresAt":     time.Now().Add(s.opts.ChallengeTTL).UTC(),
+	}, &response)
+	if err != nil {
+		return "", err
+	}
+
+	return challenge, nil
+}
+
+// consumeChallenge deletes a live challenge and returns the user it was
+// issued to, if any
+func (s *WebAuthnService) consumeChallenge(challenge, purpose string) (*uuid.UUID, error) {
+	var response struct {
+		DeleteWebauthnChallenges struct {
+			Returning []struct {
+				UserID *uuid.UUID `json:"user_id"`
+			} `json:"returning"`
+		} `json:"delete_webauthn_challenges"`
+	}
+
+	err := s.hasura.execute(`
+        mutation ($challengeHash: String!, $purpose: String!, $now: timestamptz!) {
+          delete_webauthn_challenges(where: {challenge_hash: {_eq: $challengeHash}, purpose: {_eq: $purpose}, expires_at: {_gt: $now}}) {
+            returning {
+              user_id
+            }
+          }
+        }
+        `, map[string]interface{}{
+		"challengeHash": auth.HashOpaqueToken(challenge),
+		"purpose":       purpose,
+		"now":           time.Now().UTC(),
+	}, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(response.DeleteWebauthnChallenges.Returning) == 0 {
+		return nil, ErrInvalidWebAuthnChallenge
+	}
+
+	return response.DeleteWebauthnChallenges.Returning[0].UserID, nil
+}
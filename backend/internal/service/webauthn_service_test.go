@@ -0,0 +1,220 @@
+package service
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+	"todo-app/backend/internal/webauthn"
+	"todo-app/backend/internal/webauthn/webauthntest"
+
+	"github.com/google/uuid"
+)
+
+const testWebAuthnOrigin = "http://localhost:3000"
+
+func testWebAuthnConfig() *webauthn.Config {
+	return &webauthn.Config{RPID: "localhost", RPName: "Todo App", Origins: []string{testWebAuthnOrigin}}
+}
+
+func newTestWebAuthnService(client *HasuraClient) *WebAuthnService {
+	return NewWebAuthnService(client, newTestAccountService(client, &recordingMailer{}), testWebAuthnConfig(), WebAuthnOptions{
+		ChallengeTTL: 5 * time.Minute,
+	})
+}
+
+// consumedChallenge is Hasura's reply to deleting a live challenge
+func consumedChallenge(userID *uuid.UUID) string {
+	owner := "null"
+	if userID != nil {
+		owner = fmt.Sprintf("%q", userID.String())
+	}
+	return fmt.Sprintf(`{"data":{"delete_webauthn_challenges":{"returning":[{"user_id":%s}]}}}`, owner)
+}
+
+func TestWebAuthnService_PasskeyCeremonies(t *testing.T) {
+	userID := uuid.New()
+	credentialRowID := uuid.New()
+	authenticator := webauthntest.New("localhost", testWebAuthnOrigin)
+
+	// Registration
+	client, shutdown := newMockHasuraClient(t, []mockResponse{
+		{body: passwordState(t, "user@example.com", "password123")},
+		{body: `{"data":{"webauthn_credentials":[]}}`},
+		{body: `{"data":{"insert_webauthn_challenges_one":{"challenge_hash":"hash"}}}`},
+		{body: consumedChallenge(&userID)},
+		{body: fmt.Sprintf(`{"data":{"insert_webauthn_credentials_one":{"id":"%s","name":"Laptop","created_at":"2026-01-01T00:00:00Z","last_used_at":null}}}`, credentialRowID)},
+	})
+	service := newTestWebAuthnService(client)
+
+	creationOptions, err := service.BeginRegistration(userID, "password123")
+	if err != nil {
+		t.Fatalf("failed to begin registration: %v", err)
+	}
+	if string(creationOptions.User.ID) != string(userID[:]) {
+		t.Fatalf("expected user handle to be the user id")
+	}
+
+	registration, err := authenticator.Register(creationOptions)
+	if err != nil {
+		t.Fatalf("authenticator failed to register: %v", err)
+	}
+
+	stored, err := service.FinishRegistration(userID, "Laptop", registration)
+	if err != nil {
+		t.Fatalf("failed to finish registration: %v", err)
+	}
+	if stored.ID != credentialRowID {
+		t.Fatalf("expected credential %s, got %s", credentialRowID, stored.ID)
+	}
+	shutdown()
+
+	// The key Hasura would have stored, recovered the same way the service
+	// derives it
+	verified, err := testWebAuthnConfig().VerifyRegistration(registration, creationOptions.Challenge)
+	if err != nil {
+		t.Fatalf("failed to verify registration: %v", err)
+	}
+	storedCredential := fmt.Sprintf(`{"data":{"webauthn_credentials":[{"id":"%s","user_id":"%s","public_key":"%s","sign_count":0}]}}`,
+		credentialRowID, userID, base64.StdEncoding.EncodeToString(verified.PublicKey))
+
+	// Login
+	client, shutdown = newMockHasuraClient(t, []mockResponse{
+		{body: `{"data":{"insert_webauthn_challenges_one":{"challenge_hash":"hash"}}}`},
+		{body: consumedChallenge(nil)},
+		{body: storedCredential},
+		{body: fmt.Sprintf(`{"data":{"update_webauthn_credentials_by_pk":{"id":"%s"}}}`, credentialRowID)},
+	})
+	defer shutdown()
+	service = newTestWebAuthnService(client)
+
+	requestOptions, err := service.BeginLogin()
+	if err != nil {
+		t.Fatalf("failed to begin login: %v", err)
+	}
+
+	assertion, err := authenticator.Assert(requestOptions)
+	if err != nil {
+		t.Fatalf("authenticator failed to assert: %v", err)
+	}
+
+	got, err := service.FinishLogin(assertion)
+	if err != nil {
+		t.Fatalf("failed to finish login: %v", err)
+	}
+	if got != userID {
+		t.Fatalf("expected user %s, got %s", userID, got)
+	}
+}
+
+func TestWebAuthnService_FinishRegistration(t *testing.T) {
+	userID := uuid.New()
+	authenticator := webauthntest.New("localhost", testWebAuthnOrigin)
+
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		t.Fatalf("failed to create challenge: %v", err)
+	}
+	options := testWebAuthnConfig().NewCreationOptions(challenge, webauthn.User{ID: userID[:], Name: "user@example.com"}, nil)
+	registration, err := authenticator.Register(options)
+	if err != nil {
+		t.Fatalf("authenticator failed to register: %v", err)
+	}
+
+	t.Run("challenge issued to another user", func(t *testing.T) {
+		otherID := uuid.New()
+		client, shutdown := newMockHasuraClient(t, []mockResponse{
+			{body: consumedChallenge(&otherID)},
+		})
+		defer shutdown()
+
+		_, err := newTestWebAuthnService(client).FinishRegistration(userID, "Laptop", registration)
+		if !errors.Is(err, ErrInvalidWebAuthnChallenge) {
+			t.Fatalf("expected ErrInvalidWebAuthnChallenge, got %v", err)
+		}
+	})
+
+	t.Run("credential already registered", func(t *testing.T) {
+		client, shutdown := newMockHasuraClient(t, []mockResponse{
+			{body: consumedChallenge(&userID)},
+			{body: `{"data":{"insert_webauthn_credentials_one":null}}`},
+		})
+		defer shutdown()
+
+		_, err := newTestWebAuthnService(client).FinishRegistration(userID, "Laptop", registration)
+		if !errors.Is(err, ErrWebAuthnCredentialExists) {
+			t.Fatalf("expected ErrWebAuthnCredentialExists, got %v", err)
+		}
+	})
+}
+
+func TestWebAuthnService_FinishLogin(t *testing.T) {
+	userID := uuid.New()
+	authenticator := webauthntest.New("localhost", testWebAuthnOrigin)
+
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		t.Fatalf("failed to create challenge: %v", err)
+	}
+	registration, err := authenticator.Register(testWebAuthnConfig().NewCreationOptions(challenge, webauthn.User{ID: userID[:], Name: "user@example.com"}, nil))
+	if err != nil {
+		t.Fatalf("authenticator failed to register: %v", err)
+	}
+	verified, err := testWebAuthnConfig().VerifyRegistration(registration, challenge)
+	if err != nil {
+		t.Fatalf("failed to verify registration: %v", err)
+	}
+	publicKey := base64.StdEncoding.EncodeToString(verified.PublicKey)
+
+	assert := func(t *testing.T) *webauthn.AssertionCredential {
+		t.Helper()
+		challenge, err := webauthn.NewChallenge()
+		if err != nil {
+			t.Fatalf("failed to create challenge: %v", err)
+		}
+		assertion, err := authenticator.Assert(testWebAuthnConfig().NewRequestOptions(challenge))
+		if err != nil {
+			t.Fatalf("authenticator failed to assert: %v", err)
+		}
+		return assertion
+	}
+
+	t.Run("expired challenge", func(t *testing.T) {
+		client, shutdown := newMockHasuraClient(t, []mockResponse{
+			{body: `{"data":{"delete_webauthn_challenges":{"returning":[]}}}`},
+		})
+		defer shutdown()
+
+		_, err := newTestWebAuthnService(client).FinishLogin(assert(t))
+		if !errors.Is(err, ErrInvalidWebAuthnChallenge) {
+			t.Fatalf("expected ErrInvalidWebAuthnChallenge, got %v", err)
+		}
+	})
+
+	t.Run("unknown credential", func(t *testing.T) {
+		client, shutdown := newMockHasuraClient(t, []mockResponse{
+			{body: consumedChallenge(nil)},
+			{body: `{"data":{"webauthn_credentials":[]}}`},
+		})
+		defer shutdown()
+
+		_, err := newTestWebAuthnService(client).FinishLogin(assert(t))
+		if !errors.Is(err, ErrWebAuthnVerification) {
+			t.Fatalf("expected ErrWebAuthnVerification, got %v", err)
+		}
+	})
+
+	t.Run("cloned authenticator", func(t *testing.T) {
+		client, shutdown := newMockHasuraClient(t, []mockResponse{
+			{body: consumedChallenge(nil)},
+			{body: fmt.Sprintf(`{"data":{"webauthn_credentials":[{"id":"%s","user_id":"%s","public_key":"%s","sign_count":1000}]}}`, uuid.New(), userID, publicKey)},
+		})
+		defer shutdown()
+
+		_, err := newTestWebAuthnService(client).FinishLogin(assert(t))
+		if !errors.Is(err, ErrWebAuthnVerification) {
+			t.Fatalf("expected ErrWebAuthnVerification, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,495 @@
+package service
+
+import (
+	"errors"
+	"time"
+	"todo-app/backend/internal/model"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrWorkspaceNotFound  = errors.New("workspace not found")
+	ErrNotWorkspaceMember = errors.New("not a member of this workspace")
+	ErrWorkspaceForbidden = errors.New("insufficient workspace role")
+	ErrMemberNotFound     = errors.New("workspace member not found")
+	ErrLastWorkspaceOwner = errors.New("a workspace must keep at least one owner")
+	ErrAlreadyMember      = errors.New("user is already a member of this workspace")
+)
+
+type WorkspaceService struct {
+	hasura *HasuraClient
+}
+
+func NewWorkspaceService(hasura *HasuraClient) *WorkspaceService {
+	return &WorkspaceService{hasura: hasura}
+}
+
+// memberRecord is a membership row together with the member's email
+type memberRecord struct {
+	WorkspaceID uuid.UUID `json:"workspace_id"`
+	UserID      uuid.UUID `json:"user_id"`
+	Role        string    `json:"role"`
+	CreatedAt   time.Time `json:"created_at"`
+	User        struct {
+		Email string `json:"email"`
+	} `json:"user"`
+}
+
+func (r memberRecord) toModel() model.WorkspaceMember {
+	return model.WorkspaceMember{
+		WorkspaceID: r.WorkspaceID,
+		UserID:      r.UserID,
+		Email:       r.User.Email,
+		Role:        r.Role,
+		CreatedAt:   r.CreatedAt,
+	}
+}
+
+// MemberRole returns the user's role in a workspace
+func (s *WorkspaceService) MemberRole(workspaceID, userID uuid.UUID) (string, error) {
+	var response struct {
+		WorkspaceMembersByPk *struct {
+			Role string `json:"role"`
+		} `json:"workspace_members_by_pk"`
+	}
+
+	err := s.hasura.execute(`
+        query ($workspaceId: uuid!, $userId: uuid!) {
+          workspace_members_by_pk(workspace_id: $workspaceId, user_id: $userId) {
+            role
+          }
+        }
+        `, map[string]interface{}{"workspaceId": workspaceID, "userId": userID}, &response)
+	if err != nil {
+		return "", err
+	}
+
+	if response.WorkspaceMembersByPk == nil {
+		return "", ErrNotWorkspaceMember
+	}
+
+	return response.WorkspaceMembersByPk.Role, nil
+}
+
+// CreateWorkspace creates a workspace owned by the user
+func (s *WorkspaceService) CreateWorkspace(userID uuid.UUID, name string) (*model.Workspace, error) {
+	var response struct {
+		InsertWorkspacesOne model.Workspace `json:"insert_workspaces_one"`
+	}
+
+	err := s.hasura.execute(`
+        mutation ($name: String!, $userId: uuid!) {
+          insert_workspaces_one(object: {name: $name, created_by: $userId, members: {data: [{user_id: $userId, role: "owner"}]}}) {
+            id
+            name
+            created_by
+            created_at
+            updated_at
+          }
+        }
+        `, map[string]interface{}{"name": name, "userId": userID}, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	workspace := response.InsertWorkspacesOne
+	workspace.Role = model.WorkspaceRoleOwner
+	return &workspace, nil
+}
+
+// ListWorkspaces retrieves the workspaces the user belongs to with their role
+func (s *WorkspaceService) ListWorkspaces(userID uuid.UUID) ([]model.Workspace, error) {
+	var response struct {
+		WorkspaceMembers []struct {
+			Role      string          `json:"role"`
+			Workspace model.Workspace `json:"workspace"`
+		} `json:"workspace_members"`
+	}
+
+	err := s.hasura.execute(`
+        query ($userId: uuid!) {
+          workspace_members(where: {user_id: {_eq: $userId}}, order_by: {created_at: asc}) {
+            role
+            workspace {
+              id
+              name
+              created_by
+              created_at
+              updated_at
+            }
+          }
+        }
+        `, map[string]interface{}{"userId": userID}, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	workspaces := make([]model.Workspace, 0, len(response.WorkspaceMembers))
+	for _, membership := range response.WorkspaceMembers {
+		workspace := membership.Workspace
+		workspace.Role = membership.Role
+		workspaces = append(workspaces, workspace)
+	}
+
+	return workspaces, nil
+}
+
+// GetWorkspace retrieves a workspace the user belongs to
+func (s *WorkspaceService) GetWorkspace(userID, workspaceID uuid.UUID) (*model.Workspace, error) {
+	role, err := s.MemberRole(workspaceID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		WorkspacesByPk *model.Workspace `json:"workspaces_by_pk"`
+	}
+
+	err = s.hasura.execute(`
+        query ($id: uuid!) {
+          workspaces_by_pk(id: $id) {
+            id
+            name
+            created_by
+            created_at
+            updated_at
+          }
+        }
+        `, map[string]interface{}{"id": workspaceID}, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.WorkspacesByPk == nil {
+		return nil, ErrWorkspaceNotFound
+	}
+
+	response.WorkspacesByPk.Role = role
+	return response.WorkspacesByPk, nil
+}
+
+// UpdateWorkspace renames a workspace (workspace admin function)
+func (s *WorkspaceService) UpdateWorkspace(userID, workspaceID uuid.UUID, name string) (*model.Workspace, error) {
+	role, err := s.requireRole(workspaceID, userID, model.WorkspaceRoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		UpdateWorkspacesByPk *model.Workspace `json:"update_workspaces_by_pk"`
+	}
+
+	err = s.hasura.execute(`
+        mutation ($id: uuid!, $name: String!, $now: timestamptz!) {
+          update_workspaces_by_pk(pk_columns: {id: $id}, _set: {name: $name, updated_at: $now}) {
+            id
+            name
+            created_by
+            created_at
+            updated_at
+          }
+        }
+        `, map[string]interface{}{"id": workspaceID, "name": name, "now": time.Now().UTC()}, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.UpdateWorkspacesByPk == nil {
+		return nil, ErrWorkspaceNotFound
+	}
+
+	response.UpdateWorkspacesByPk.Role = role
+	return response.UpdateWorkspacesByPk, nil
+}
+
+// DeleteWorkspace deletes a workspace and its todos (workspace owner function)
+func (s *WorkspaceService) DeleteWorkspace(userID, workspaceID uuid.UUID) error {
+	if _, err := s.requireRole(workspaceID, userID, model.WorkspaceRoleOwner); err != nil {
+		return err
+	}
+
+	var response struct {
+		DeleteWorkspacesByPk *struct {
+			ID uuid.UUID `json:"id"`
+		} `json:"delete_workspaces_by_pk"`
+	}
+
+	err := s.hasura.execute(`
+        mutation ($id: uuid!) {
+          delete_workspaces_by_pk(id: $id) {
+            id
+          }
+        }
+        `, map[string]interface{}{"id": workspaceID}, &response)
+	if err != nil {
+		return err
+	}
+
+	if response.DeleteWorkspacesByPk == nil {
+		return ErrWorkspaceNotFound
+	}
+
+	return nil
+}
+
+// ListMembers retrieves the members of a workspace the user belongs to
+func (s *WorkspaceService) ListMembers(userID, workspaceID uuid.UUID) ([]model.WorkspaceMember, error) {
+	if _, err := s.MemberRole(workspaceID, userID); err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		WorkspaceMembers []memberRecord `json:"workspace_members"`
+	}
+
+	err := s.hasura.execute(`
+        query ($workspaceId: uuid!) {
+          workspace_members(where: {workspace_id: {_eq: $workspaceId}}, order_by: {created_at: asc}) {
+            workspace_id
+            user_id
+            role
+            created_at
+            user {
+              email
+            }
+          }
+        }
+        `, map[string]interface{}{"workspaceId": workspaceID}, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	members := make([]model.WorkspaceMember, 0, len(response.WorkspaceMembers))
+	for _, record := range response.WorkspaceMembers {
+		members = append(members, record.toModel())
+	}
+
+	return members, nil
+}
+
+// AddMember adds a registered user to a workspace (workspace admin function).
+// Only owners can add other owners.
+func (s *WorkspaceService) AddMember(userID, workspaceID uuid.UUID, req model.AddWorkspaceMemberRequest) (*model.WorkspaceMember, error) {
+	required := model.WorkspaceRoleAdmin
+	if req.Role == model.WorkspaceRoleOwner {
+		required = model.WorkspaceRoleOwner
+	}
+
+	if _, err := s.requireRole(workspaceID, userID, required); err != nil {
+		return nil, err
+	}
+
+	var usersResp struct {
+		Users []struct {
+			ID uuid.UUID `json:"id"`
+		} `json:"users"`
+	}
+
+	err := s.hasura.execute(`
+        query ($email: String!) {
+          users(where: {email: {_eq: $email}}, limit: 1) {
+            id
+          }
+        }
+        `, map[string]interface{}{"email": req.Email}, &usersResp)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(usersResp.Users) == 0 {
+		return nil, ErrUserNotFound
+	}
+
+	memberID := usersResp.Users[0].ID
+	if _, err := s.MemberRole(workspaceID, memberID); err == nil {
+		return nil, ErrAlreadyMember
+	} else if !errors.Is(err, ErrNotWorkspaceMember) {
+		return nil, err
+	}
+
+	var response struct {
+		InsertWorkspaceMembersOne memberRecord `json:"insert_workspace_members_one"`
+	}
+
+	err = s.hasura.execute(`
+        mutation ($workspaceId: uuid!, $userId: uuid!, $role: String!) {
+          insert_workspace_members_one(object: {workspace_id: $workspaceId, user_id: $userId, role: $role}) {
+            workspace_id
+            user_id
+            role
+            created_at
+            user {
+              email
+            }
+          }
+        }
+        `, map[string]interface{}{"workspaceId": workspaceID, "userId": memberID, "role": req.Role}, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	member := response.InsertWorkspaceMembersOne.toModel()
+	return &member, nil
+}
+
+// UpdateMemberRole changes a member's role (workspace admin function). Only
+// owners can grant or revoke the owner role.
+func (s *WorkspaceService) UpdateMemberRole(userID, workspaceID, memberID uuid.UUID, role string) (*model.WorkspaceMember, error) {
+	currentRole, err := s.MemberRole(workspaceID, memberID)
+	if errors.Is(err, ErrNotWorkspaceMember) {
+		return nil, ErrMemberNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	required := model.WorkspaceRoleAdmin
+	if role == model.WorkspaceRoleOwner || currentRole == model.WorkspaceRoleOwner {
+		required = model.WorkspaceRoleOwner
+	}
+
+	if _, err := s.requireRole(workspaceID, userID, required); err != nil {
+		return nil, err
+	}
+
+	if currentRole == model.WorkspaceRoleOwner && role != model.WorkspaceRoleOwner {
+		if err := s.ensureAnotherOwner(workspaceID, memberID); err != nil {
+			return nil, err
+		}
+	}
+
+	var response struct {
+		UpdateWorkspaceMembersByPk *memberRecord `json:"update_workspace_members_by_pk"`
+	}
+
+	err = s.hasura.execute(`
+        mutation ($workspaceId: uuid!, $userId: uuid!, $role: String!) {
+          update_workspace_members_by_pk(pk_columns: {workspace_id: $workspaceId, user_id: $userId}, _set: {role: $role}) {
+            workspace_id
+            user_id
+            role
+            created_at
+            user {
+              email
+            }
+          }
+        }
+        `, map[string]interface{}{"workspaceId": workspaceID, "userId": memberID, "role": role}, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.UpdateWorkspaceMembersByPk == nil {
+		return nil, ErrMemberNotFound
+	}
+
+	member := response.UpdateWorkspaceMembersByPk.toModel()
+	return &member, nil
+}
+
+// RemoveMember removes a member from a workspace. Admins can remove members
+// and any member can leave on their own.
+func (s *WorkspaceService) RemoveMember(userID, workspaceID, memberID uuid.UUID) error {
+	currentRole, err := s.MemberRole(workspaceID, memberID)
+	if errors.Is(err, ErrNotWorkspaceMember) {
+		return ErrMemberNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if userID != memberID {
+		required := model.WorkspaceRoleAdmin
+		if currentRole == model.WorkspaceRoleOwner {
+			required = model.WorkspaceRoleOwner
+		}
+		if _, err := s.requireRole(workspaceID, userID, required); err != nil {
+			return err
+		}
+	}
+
+	if currentRole == model.WorkspaceRoleOwner {
+		if err := s.ensureAnotherOwner(workspaceID, memberID); err != nil {
+			return err
+		}
+	}
+
+	var response struct {
+		DeleteWorkspaceMembersByPk *struct {
+			UserID uuid.UUID `json:"user_id"`
+		} `json:"delete_workspace_members_by_pk"`
+	}
+
+	err = s.hasura.execute(`
+        mutation ($workspaceId: uuid!, $userId: uuid!) {
+          delete_workspace_members_by_pk(workspace_id: $workspaceId, user_id: $userId) {
+            user_id
+          }
+        }
+        `, map[string]interface{}{"workspaceId": workspaceID, "userId": memberID}, &response)
+	if err != nil {
+		return err
+	}
+
+	if response.DeleteWorkspaceMembersByPk == nil {
+		return ErrMemberNotFound
+	}
+
+	return nil
+}
+
+// requireRole checks that the user holds at least the given workspace role
+func (s *WorkspaceService) requireRole(workspaceID, userID uuid.UUID, required string) (string, error) {
+	role, err := s.MemberRole(workspaceID, userID)
+	if err != nil {
+		return "", err
+	}
+
+	if !WorkspaceRoleAtLeast(role, required) {
+		return "", ErrWorkspaceForbidden
+	}
+
+	return role, nil
+}
+
+// ensureAnotherOwner fails when memberID is the workspace's only owner
+func (s *WorkspaceService) ensureAnotherOwner(workspaceID, memberID uuid.UUID) error {
+	var response struct {
+		WorkspaceMembersAggregate struct {
+			Aggregate struct {
+				Count int `json:"count"`
+			} `json:"aggregate"`
+		} `json:"workspace_members_aggregate"`
+	}
+
+	err := s.hasura.execute(`
+        query ($workspaceId: uuid!, $userId: uuid!) {
+          workspace_members_aggregate(where: {workspace_id: {_eq: $workspaceId}, role: {_eq: "owner"}, user_id: {_neq: $userId}}) {
+            aggregate {
+              count
+            }
+          }
+        }
+        `, map[string]interface{}{"workspaceId": workspaceID, "userId": memberID}, &response)
+	if err != nil {
+		return err
+	}
+
+	if response.WorkspaceMembersAggregate.Aggregate.Count == 0 {
+		return ErrLastWorkspaceOwner
+	}
+
+	return nil
+}
+
+// WorkspaceRoleAtLeast reports whether role is at least as strong as required
+func WorkspaceRoleAtLeast(role, required string) bool {
+	rank := map[string]int{
+		model.WorkspaceRoleGuest:  1,
+		model.WorkspaceRoleMember: 2,
+		model.WorkspaceRoleAdmin:  3,
+		model.WorkspaceRoleOwner:  4,
+	}
+	return rank[role] >= rank[required] && rank[required] > 0
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"todo-app/backend/internal/model"
+)
+
+func TestWorkspaceService_RemoveMember(t *testing.T) {
+	userID := uuid.New()
+	workspaceID := uuid.New()
+
+	t.Run("member cannot remove another member", func(t *testing.T) {
+		client, shutdown := newMockHasuraClient(t, []mockResponse{
+			{body: `{"data":{"workspace_members_by_pk":{"role":"member"}}}`},
+			{body: `{"data":{"workspace_members_by_pk":{"role":"member"}}}`},
+		})
+		defer shutdown()
+
+		service := NewWorkspaceService(client)
+		err := service.RemoveMember(userID, workspaceID, uuid.New())
+		if !errors.Is(err, ErrWorkspaceForbidden) {
+			t.Fatalf("expected ErrWorkspaceForbidden, got %v", err)
+		}
+	})
+
+	t.Run("last owner cannot leave", func(t *testing.T) {
+		client, shutdown := newMockHasuraClient(t, []mockResponse{
+			{body: `{"data":{"workspace_members_by_pk":{"role":"owner"}}}`},
+			{body: `{"data":{"workspace_members_aggregate":{"aggregate":{"count":0}}}}`},
+		})
+		defer shutdown()
+
+		service := NewWorkspaceService(client)
+		err := service.RemoveMember(userID, workspaceID, userID)
+		if !errors.Is(err, ErrLastWorkspaceOwner) {
+			t.Fatalf("expected ErrLastWorkspaceOwner, got %v", err)
+		}
+	})
+}
+
+func TestWorkspaceRoleAtLeast(t *testing.T) {
+	tests := []struct {
+		role     string
+		required string
+		want     bool
+	}{
+		{model.WorkspaceRoleOwner, model.WorkspaceRoleAdmin, true},
+		{model.WorkspaceRoleMember, model.WorkspaceRoleMember, true},
+		{model.WorkspaceRoleGuest, model.WorkspaceRoleMember, false},
+		{"", model.WorkspaceRoleGuest, false},
+		{model.WorkspaceRoleOwner, "unknown", false},
+	}
+
+	for _, tt := range tests {
+		if got := WorkspaceRoleAtLeast(tt.role, tt.required); got != tt.want {
+			t.Errorf("WorkspaceRoleAtLeast(%q, %q) = %v, want %v", tt.role, tt.required, got, tt.want)
+		}
+	}
+}
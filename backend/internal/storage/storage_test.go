@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeS3 is a minimal in-memory stand-in for an S3-compatible server
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=test-key/") {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch r.Method {
+	case http.MethodPut:
+		body, _ := io.ReadAll(r.Body)
+		f.objects[r.URL.Path] = body
+	case http.MethodGet:
+		body, ok := f.objects[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(body)
+	case http.MethodDelete:
+		delete(f.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func TestBlobStores(t *testing.T) {
+	fake := &fakeS3{objects: map[string][]byte{}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	local, err := NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalStore returned error: %v", err)
+	}
+
+	stores := map[string]BlobStore{
+		"local": local,
+		"s3": NewS3Store(S3Config{
+			Endpoint:        server.URL,
+			Bucket:          "attachments",
+			AccessKeyID:     "test-key",
+			SecretAccessKey: "test-secret",
+		}),
+	}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			key := "todos/abc/file"
+			content := "hello attachment"
+
+			if err := store.Put(ctx, key, strings.NewReader(content), int64(len(content)), "text/plain"); err != nil {
+				t.Fatalf("Put returned error: %v", err)
+			}
+
+			r, err := store.Get(ctx, key)
+			if err != nil {
+				t.Fatalf("Get returned error: %v", err)
+			}
+			got, _ := io.ReadAll(r)
+			r.Close()
+
+			if string(got) != content {
+				t.Fatalf("expected %q, got %q", content, got)
+			}
+
+			if err := store.Delete(ctx, key); err != nil {
+				t.Fatalf("Delete returned error: %v", err)
+			}
+
+			if _, err := store.Get(ctx, key); !errors.Is(err, ErrBlobNotFound) {
+				t.Fatalf("expected ErrBlobNotFound, got %v", err)
+			}
+
+			if err := store.Put(ctx, "../escape", strings.NewReader(""), 0, ""); !errors.Is(err, ErrInvalidKey) {
+				t.Fatalf("expected ErrInvalidKey, got %v", err)
+			}
+		})
+	}
+}
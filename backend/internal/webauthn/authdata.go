@@ -0,0 +1,93 @@
+package webauthn
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Authenticator data flags
+const (
+	flagUserPresent  = 0x01
+	flagUserVerified = 0x04
+	flagAttestedData = 0x40
+	flagExtensions   = 0x80
+)
+
+// authDataMinLength covers the RP id hash, flags and signature counter, and
+// attestedHeaderLength the AAGUID and credential id length
+const (
+	authDataMinLength    = 37
+	attestedHeaderLength = 18
+)
+
+var ErrInvalidAuthenticatorData = errors.New("invalid authenticator data")
+
+// authenticatorData is the parsed binary structure an authenticator signs
+type authenticatorData struct {
+	RPIDHash     []byte
+	Flags        byte
+	SignCount    uint32
+	CredentialID []byte
+	// PublicKey is the COSE encoding of the credential public key, present
+	// at registration
+	PublicKey []byte
+}
+
+func (a *authenticatorData) UserPresent() bool {
+	return a.Flags&flagUserPresent != 0
+}
+
+func (a *authenticatorData) UserVerified() bool {
+	return a.Flags&flagUserVerified != 0
+}
+
+func parseAuthenticatorData(data []byte) (*authenticatorData, error) {
+	if len(data) < authDataMinLength {
+		return nil, fmt.Errorf("%w: too short", ErrInvalidAuthenticatorData)
+	}
+
+	parsed := &authenticatorData{
+		RPIDHash:  data[:32],
+		Flags:     data[32],
+		SignCount: binary.BigEndian.Uint32(data[33:37]),
+	}
+	rest := data[authDataMinLength:]
+
+	if parsed.Flags&flagAttestedData != 0 {
+		// AAGUID (16 bytes) then the credential id length
+		if len(rest) < attestedHeaderLength {
+			return nil, fmt.Errorf("%w: truncated attested credential data", ErrInvalidAuthenticatorData)
+		}
+		idLength := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[attestedHeaderLength:]
+		if idLength == 0 || len(rest) < idLength {
+			return nil, fmt.Errorf("%w: bad credential id", ErrInvalidAuthenticatorData)
+		}
+		parsed.CredentialID = rest[:idLength]
+		rest = rest[idLength:]
+
+		// The key is a CBOR item of unknown length; decoding it tells us
+		// where it ends
+		_, after, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidAuthenticatorData, err)
+		}
+		parsed.PublicKey = rest[:len(rest)-len(after)]
+		rest = after
+	}
+
+	if parsed.Flags&flagExtensions != 0 {
+		_, after, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidAuthenticatorData, err)
+		}
+		rest = after
+	}
+
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("%w: trailing data", ErrInvalidAuthenticatorData)
+	}
+
+	return parsed, nil
+}
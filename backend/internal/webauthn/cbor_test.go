@@ -0,0 +1,52 @@
+package webauthn
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestDecodeCBOR(t *testing.T) {
+	// {1: 2, 3: -7, "k": h'0102'} followed by one trailing byte
+	input := []byte{0xa3, 0x01, 0x02, 0x03, 0x26, 0x61, 'k', 0x42, 0x01, 0x02, 0xff}
+
+	value, rest, err := decodeCBOR(input)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	entries, ok := value.(map[interface{}]interface{})
+	if !ok {
+		t.Fatalf("expected a map, got %T", value)
+	}
+	if entries[int64(1)] != int64(2) || entries[int64(3)] != int64(-7) || !bytes.Equal(entries["k"].([]byte), []byte{1, 2}) {
+		t.Fatalf("unexpected entries: %v", entries)
+	}
+	if !bytes.Equal(rest, []byte{0xff}) {
+		t.Fatalf("expected the trailing byte to be returned, got %x", rest)
+	}
+}
+
+func TestDecodeCBOR_Malformed(t *testing.T) {
+	nested := bytes.Repeat([]byte{0x81}, maxCBORDepth+2)
+
+	tests := []struct {
+		name  string
+		input []byte
+	}{
+		{"empty", nil},
+		{"truncated string", []byte{0x45, 0x01}},
+		{"huge array length", []byte{0x9b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{"indefinite length", []byte{0x5f}},
+		{"duplicate key", []byte{0xa2, 0x01, 0x00, 0x01, 0x00}},
+		{"nested too deeply", append(nested, 0x00)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := decodeCBOR(tt.input); !errors.Is(err, errCBOR) {
+				t.Fatalf("expected errCBOR, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,363 @@
+// Package webauthn implements the relying party side of the WebAuthn
+// registration and authentication ceremonies (W3C Web Authentication Level
+// 2, sections 7.1 and 7.2). Challenges are created here but stored by the
+// caller, who also keeps the credentials.
+//
+// Attestation statements are not verified: options ask for "none"
+// conveyance, and the credential is trusted on first use rather than because
+// of the authenticator's make.
+package webauthn
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// challengeBytes is the amount of randomness in a challenge
+const challengeBytes = 32
+
+// publicKeyType is the only credential type WebAuthn defines
+const publicKeyType = "public-key"
+
+var (
+	ErrInvalidCredential   = errors.New("invalid credential")
+	ErrInvalidClientData   = errors.New("invalid client data")
+	ErrChallengeMismatch   = errors.New("challenge mismatch")
+	ErrOriginMismatch      = errors.New("origin not allowed")
+	ErrRPIDMismatch        = errors.New("relying party mismatch")
+	ErrUserNotPresent      = errors.New("user not present")
+	ErrUserNotVerified     = errors.New("user not verified")
+	ErrSignCountRegression = errors.New("signature counter went backwards")
+)
+
+// Config identifies the relying party
+type Config struct {
+	// RPID is the domain credentials are scoped to, e.g. "example.com"
+	RPID string
+	// RPName is shown by the browser during registration
+	RPName string
+	// Origins are the exact origins ceremonies may come from, e.g.
+	// "https://app.example.com"
+	Origins []string
+	// Timeout is the ceremony timeout hint given to the browser, in ms
+	Timeout int
+}
+
+// Bytes is binary data that travels as unpadded base64url in JSON, as in the
+// WebAuthn Level 3 JSON serialization
+type Bytes []byte
+
+func (b Bytes) MarshalJSON() ([]byte, error) {
+	return json.Marshal(base64.RawURLEncoding.EncodeToString(b))
+}
+
+func (b *Bytes) UnmarshalJSON(data []byte) error {
+	var encoded string
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return err
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(encoded, "="))
+	if err != nil {
+		return err
+	}
+	*b = decoded
+	return nil
+}
+
+// NewChallenge returns a random challenge in the encoding the browser echoes
+// back in clientDataJSON
+func NewChallenge() (string, error) {
+	buf := make([]byte, challengeBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// User is the account a credential is registered for
+type User struct {
+	// ID is the opaque user handle stored on the authenticator
+	ID          []byte
+	Name        string
+	DisplayName string
+}
+
+type RelyingPartyEntity struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type UserEntity struct {
+	ID          Bytes  `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+type CredentialParameter struct {
+	Type string `json:"type"`
+	Alg  int64  `json:"alg"`
+}
+
+type CredentialDescriptor struct {
+	Type string `json:"type"`
+	ID   Bytes  `json:"id"`
+}
+
+type AuthenticatorSelection struct {
+	ResidentKey      string `json:"residentKey"`
+	UserVerification string `json:"userVerification"`
+}
+
+// CreationOptions are the PublicKeyCredentialCreationOptions for
+// navigator.credentials.create
+type CreationOptions struct {
+	RP                     RelyingPartyEntity     `json:"rp"`
+	User                   UserEntity             `json:"user"`
+	Challenge              string                 `json:"challenge"`
+	PubKeyCredParams       []CredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int                    `json:"timeout,omitempty"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection AuthenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                 `json:"attestation"`
+}
+
+// RequestOptions are the PublicKeyCredentialRequestOptions for
+// navigator.credentials.get. Without allowCredentials the browser offers the
+// user's discoverable credentials (passkeys).
+type RequestOptions struct {
+	Challenge        string `json:"challenge"`
+	Timeout          int    `json:"timeout,omitempty"`
+	RPID             string `json:"rpId"`
+	UserVerification string `json:"userVerification"`
+}
+
+// NewCreationOptions builds registration options. Credentials in exclude are
+// already registered and will not be created twice on one authenticator.
+func (c *Config) NewCreationOptions(challenge string, user User, exclude [][]byte) *CreationOptions {
+	descriptors := make([]CredentialDescriptor, 0, len(exclude))
+	for _, id := range exclude {
+		descriptors = append(descriptors, CredentialDescriptor{Type: publicKeyType, ID: id})
+	}
+
+	return &CreationOptions{
+		RP:        RelyingPartyEntity{ID: c.RPID, Name: c.RPName},
+		User:      UserEntity{ID: user.ID, Name: user.Name, DisplayName: user.DisplayName},
+		Challenge: challenge,
+		PubKeyCredParams: []CredentialParameter{
+			{Type: publicKeyType, Alg: AlgES256},
+			{Type: publicKeyType, Alg: AlgEdDSA},
+			{Type: publicKeyType, Alg: AlgRS256},
+		},
+		Timeout:            c.Timeout,
+		ExcludeCredentials: descriptors,
+		AuthenticatorSelection: AuthenticatorSelection{
+			ResidentKey:      "required",
+			UserVerification: "required",
+		},
+		Attestation: "none",
+	}
+}
+
+// NewRequestOptions builds authentication options
+func (c *Config) NewRequestOptions(challenge string) *RequestOptions {
+	return &RequestOptions{
+		Challenge:        challenge,
+		Timeout:          c.Timeout,
+		RPID:             c.RPID,
+		UserVerification: "required",
+	}
+}
+
+// RegistrationCredential is the JSON form of the PublicKeyCredential returned
+// by navigator.credentials.create
+type RegistrationCredential struct {
+	ID       string `json:"id"`
+	RawID    Bytes  `json:"rawId"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    Bytes `json:"clientDataJSON"`
+		AttestationObject Bytes `json:"attestationObject"`
+	} `json:"response"`
+}
+
+// AssertionCredential is the JSON form of the PublicKeyCredential returned by
+// navigator.credentials.get
+type AssertionCredential struct {
+	ID       string `json:"id"`
+	RawID    Bytes  `json:"rawId"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    Bytes `json:"clientDataJSON"`
+		AuthenticatorData Bytes `json:"authenticatorData"`
+		Signature         Bytes `json:"signature"`
+		UserHandle        Bytes `json:"userHandle"`
+	} `json:"response"`
+}
+
+// ClientData is the collected client data the browser passes to the
+// authenticator
+type ClientData struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Origin    string `json:"origin"`
+}
+
+// ParseClientData decodes clientDataJSON. Callers use the challenge in it to
+// find the ceremony the response belongs to.
+func ParseClientData(raw []byte) (*ClientData, error) {
+	var clientData ClientData
+	if err := json.Unmarshal(raw, &clientData); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidClientData, err)
+	}
+	if clientData.Challenge == "" {
+		return nil, fmt.Errorf("%w: missing challenge", ErrInvalidClientData)
+	}
+	return &clientData, nil
+}
+
+// Credential is a newly registered credential to store
+type Credential struct {
+	ID []byte
+	// PublicKey is the COSE_Key, to be passed to VerifyAssertion
+	PublicKey []byte
+	SignCount uint32
+}
+
+// VerifyRegistration checks a registration response against the challenge
+// that was issued for it (section 7.1)
+func (c *Config) VerifyRegistration(credential *RegistrationCredential, challenge string) (*Credential, error) {
+	if credential.Type != publicKeyType || len(credential.RawID) == 0 {
+		return nil, ErrInvalidCredential
+	}
+
+	if err := c.verifyClientData(credential.Response.ClientDataJSON, "webauthn.create", challenge); err != nil {
+		return nil, err
+	}
+
+	value, rest, err := decodeCBOR(credential.Response.AttestationObject)
+	if err != nil || len(rest) != 0 {
+		return nil, fmt.Errorf("%w: bad attestation object", ErrInvalidCredential)
+	}
+	attestation, ok := value.(map[interface{}]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: bad attestation object", ErrInvalidCredential)
+	}
+	rawAuthData, ok := attestation["authData"].([]byte)
+	if !ok {
+		return nil, fmt.Errorf("%w: missing authenticator data", ErrInvalidCredential)
+	}
+
+	authData, err := c.verifyAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, err
+	}
+
+	if authData.CredentialID == nil {
+		return nil, fmt.Errorf("%w: missing attested credential data", ErrInvalidCredential)
+	}
+	if !bytes.Equal(authData.CredentialID, credential.RawID) {
+		return nil, fmt.Errorf("%w: credential id mismatch", ErrInvalidCredential)
+	}
+
+	if _, err := ParsePublicKey(authData.PublicKey); err != nil {
+		return nil, err
+	}
+
+	return &Credential{
+		ID:        authData.CredentialID,
+		PublicKey: authData.PublicKey,
+		SignCount: authData.SignCount,
+	}, nil
+}
+
+// VerifyAssertion checks an authentication response against the challenge
+// issued for it and the stored credential (section 7.2), and returns the new
+// signature counter to store
+func (c *Config) VerifyAssertion(credential *AssertionCredential, challenge string, publicKey []byte, storedSignCount uint32) (uint32, error) {
+	if credential.Type != publicKeyType || len(credential.RawID) == 0 {
+		return 0, ErrInvalidCredential
+	}
+
+	if err := c.verifyClientData(credential.Response.ClientDataJSON, "webauthn.get", challenge); err != nil {
+		return 0, err
+	}
+
+	authData, err := c.verifyAuthenticatorData(credential.Response.AuthenticatorData)
+	if err != nil {
+		return 0, err
+	}
+
+	key, err := ParsePublicKey(publicKey)
+	if err != nil {
+		return 0, err
+	}
+
+	clientDataHash := sha256.Sum256(credential.Response.ClientDataJSON)
+	signed := append(append([]byte(nil), credential.Response.AuthenticatorData...), clientDataHash[:]...)
+	if err := key.Verify(signed, credential.Response.Signature); err != nil {
+		return 0, err
+	}
+
+	// A counter that does not move forward suggests a cloned authenticator.
+	// Authenticators that do not count always report zero.
+	if (authData.SignCount != 0 || storedSignCount != 0) && authData.SignCount <= storedSignCount {
+		return 0, ErrSignCountRegression
+	}
+
+	return authData.SignCount, nil
+}
+
+func (c *Config) verifyClientData(raw []byte, ceremony, challenge string) error {
+	clientData, err := ParseClientData(raw)
+	if err != nil {
+		return err
+	}
+
+	if clientData.Type != ceremony {
+		return fmt.Errorf("%w: unexpected type %q", ErrInvalidClientData, clientData.Type)
+	}
+
+	if subtle.ConstantTimeCompare([]byte(clientData.Challenge), []byte(challenge)) != 1 {
+		return ErrChallengeMismatch
+	}
+
+	for _, origin := range c.Origins {
+		if clientData.Origin == origin {
+			return nil
+		}
+	}
+	return ErrOriginMismatch
+}
+
+// verifyAuthenticatorData checks the RP id hash and that the user was present
+// and verified
+func (c *Config) verifyAuthenticatorData(raw []byte) (*authenticatorData, error) {
+	authData, err := parseAuthenticatorData(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	rpIDHash := sha256.Sum256([]byte(c.RPID))
+	if !bytes.Equal(authData.RPIDHash, rpIDHash[:]) {
+		return nil, ErrRPIDMismatch
+	}
+
+	if !authData.UserPresent() {
+		return nil, ErrUserNotPresent
+	}
+
+	// Options always require user verification, which is what lets a
+	// passkey stand in for both the password and a second factor
+	if !authData.UserVerified() {
+		return nil, ErrUserNotVerified
+	}
+
+	return authData, nil
+}
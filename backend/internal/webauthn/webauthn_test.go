@@ -0,0 +1,141 @@
+package webauthn_test
+
+import (
+	"errors"
+	"testing"
+	"todo-app/backend/internal/webauthn"
+	"todo-app/backend/internal/webauthn/webauthntest"
+)
+
+const (
+	testRPID   = "app.test"
+	testOrigin = "https://app.test"
+)
+
+func newTestConfig() *webauthn.Config {
+	return &webauthn.Config{RPID: testRPID, RPName: "Todo App", Origins: []string{testOrigin}}
+}
+
+// register runs a registration ceremony and returns the stored credential
+func register(t *testing.T, config *webauthn.Config, authenticator *webauthntest.Authenticator) *webauthn.Credential {
+	t.Helper()
+
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		t.Fatalf("failed to create challenge: %v", err)
+	}
+
+	options := config.NewCreationOptions(challenge, webauthn.User{ID: []byte("user-1"), Name: "alice@example.com"}, nil)
+	response, err := authenticator.Register(options)
+	if err != nil {
+		t.Fatalf("authenticator failed to register: %v", err)
+	}
+
+	credential, err := config.VerifyRegistration(response, challenge)
+	if err != nil {
+		t.Fatalf("expected registration to verify, got %v", err)
+	}
+	return credential
+}
+
+func TestRegistration(t *testing.T) {
+	config := newTestConfig()
+	authenticator := webauthntest.New(testRPID, testOrigin)
+
+	credential := register(t, config, authenticator)
+	if string(credential.ID) != string(authenticator.CredentialID()) {
+		t.Fatalf("expected credential id %x, got %x", authenticator.CredentialID(), credential.ID)
+	}
+
+	t.Run("rejects another challenge", func(t *testing.T) {
+		options := config.NewCreationOptions("issued", webauthn.User{ID: []byte("user-1")}, nil)
+		response, err := authenticator.Register(options)
+		if err != nil {
+			t.Fatalf("authenticator failed to register: %v", err)
+		}
+
+		if _, err := config.VerifyRegistration(response, "expected"); !errors.Is(err, webauthn.ErrChallengeMismatch) {
+			t.Fatalf("expected ErrChallengeMismatch, got %v", err)
+		}
+	})
+
+	t.Run("rejects a foreign origin", func(t *testing.T) {
+		phishing := webauthntest.New(testRPID, "https://app.test.evil")
+		options := config.NewCreationOptions("challenge", webauthn.User{ID: []byte("user-1")}, nil)
+		response, err := phishing.Register(options)
+		if err != nil {
+			t.Fatalf("authenticator failed to register: %v", err)
+		}
+
+		if _, err := config.VerifyRegistration(response, "challenge"); !errors.Is(err, webauthn.ErrOriginMismatch) {
+			t.Fatalf("expected ErrOriginMismatch, got %v", err)
+		}
+	})
+
+	t.Run("requires user verification", func(t *testing.T) {
+		unverified := webauthntest.New(testRPID, testOrigin)
+		unverified.UserVerified = false
+		options := config.NewCreationOptions("challenge", webauthn.User{ID: []byte("user-1")}, nil)
+		response, err := unverified.Register(options)
+		if err != nil {
+			t.Fatalf("authenticator failed to register: %v", err)
+		}
+
+		if _, err := config.VerifyRegistration(response, "challenge"); !errors.Is(err, webauthn.ErrUserNotVerified) {
+			t.Fatalf("expected ErrUserNotVerified, got %v", err)
+		}
+	})
+}
+
+func TestAssertion(t *testing.T) {
+	config := newTestConfig()
+	authenticator := webauthntest.New(testRPID, testOrigin)
+	credential := register(t, config, authenticator)
+
+	assert := func(t *testing.T, challenge string) *webauthn.AssertionCredential {
+		t.Helper()
+		response, err := authenticator.Assert(config.NewRequestOptions(challenge))
+		if err != nil {
+			t.Fatalf("authenticator failed to assert: %v", err)
+		}
+		return response
+	}
+
+	t.Run("valid assertion", func(t *testing.T) {
+		signCount, err := config.VerifyAssertion(assert(t, "challenge"), "challenge", credential.PublicKey, credential.SignCount)
+		if err != nil {
+			t.Fatalf("expected assertion to verify, got %v", err)
+		}
+		if signCount != authenticator.SignCount {
+			t.Fatalf("expected sign count %d, got %d", authenticator.SignCount, signCount)
+		}
+	})
+
+	t.Run("tampered signature", func(t *testing.T) {
+		response := assert(t, "challenge")
+		response.Response.Signature[len(response.Response.Signature)-1] ^= 0xff
+
+		_, err := config.VerifyAssertion(response, "challenge", credential.PublicKey, 0)
+		if !errors.Is(err, webauthn.ErrInvalidSignature) {
+			t.Fatalf("expected ErrInvalidSignature, got %v", err)
+		}
+	})
+
+	t.Run("counter went backwards", func(t *testing.T) {
+		response := assert(t, "challenge")
+
+		_, err := config.VerifyAssertion(response, "challenge", credential.PublicKey, authenticator.SignCount+10)
+		if !errors.Is(err, webauthn.ErrSignCountRegression) {
+			t.Fatalf("expected ErrSignCountRegression, got %v", err)
+		}
+	})
+
+	t.Run("credential for another site", func(t *testing.T) {
+		other := &webauthn.Config{RPID: "other.test", Origins: []string{testOrigin}}
+
+		_, err := other.VerifyAssertion(assert(t, "challenge"), "challenge", credential.PublicKey, 0)
+		if !errors.Is(err, webauthn.ErrRPIDMismatch) {
+			t.Fatalf("expected ErrRPIDMismatch, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,221 @@
+// Package webauthntest provides a software authenticator for exercising
+// WebAuthn ceremonies in tests without a browser or security key
+package webauthntest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"todo-app/backend/internal/webauthn"
+)
+
+// Authenticator flags
+const (
+	flagUserPresent  = 0x01
+	flagUserVerified = 0x04
+	flagAttestedData = 0x40
+)
+
+// Authenticator holds one ES256 credential, like a passkey on a phone
+type Authenticator struct {
+	// Origin is reported in client data, as a browser would
+	Origin string
+	// UserVerified controls the UV flag, as if the user skipped the PIN or
+	// biometric check
+	UserVerified bool
+	// SignCount is the counter reported by the next assertion, incremented
+	// before use; counting starts after registration
+	SignCount uint32
+
+	rpID         string
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	userHandle   []byte
+}
+
+func New(rpID, origin string) *Authenticator {
+	return &Authenticator{Origin: origin, UserVerified: true, rpID: rpID}
+}
+
+// CredentialID returns the id of the registered credential
+func (a *Authenticator) CredentialID() []byte {
+	return a.credentialID
+}
+
+// Register creates a credential for the options, as
+// navigator.credentials.create would
+func (a *Authenticator) Register(options *webauthn.CreationOptions) (*webauthn.RegistrationCredential, error) {
+	if options.RP.ID != a.rpID {
+		return nil, fmt.Errorf("authenticator is for %q, not %q", a.rpID, options.RP.ID)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	credentialID := make([]byte, 16)
+	if _, err := rand.Read(credentialID); err != nil {
+		return nil, err
+	}
+
+	a.key = key
+	a.credentialID = credentialID
+	a.userHandle = options.User.ID
+
+	clientDataJSON, err := a.clientData("webauthn.create", options.Challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	authData := a.authenticatorData(flagAttestedData, 0)
+	var idLength [2]byte
+	binary.BigEndian.PutUint16(idLength[:], uint16(len(credentialID)))
+	authData = append(authData, make([]byte, 16)...) // AAGUID
+	authData = append(authData, idLength[:]...)
+	authData = append(authData, credentialID...)
+	authData = append(authData, a.coseKey()...)
+
+	attestation := encodeCBOR(map[interface{}]interface{}{
+		"fmt":      "none",
+		"attStmt":  map[interface{}]interface{}{},
+		"authData": authData,
+	})
+
+	credential := &webauthn.RegistrationCredential{
+		ID:    base64.RawURLEncoding.EncodeToString(credentialID),
+		RawID: credentialID,
+		Type:  "public-key",
+	}
+	credential.Response.ClientDataJSON = clientDataJSON
+	credential.Response.AttestationObject = attestation
+	return credential, nil
+}
+
+// Assert signs in with the registered credential, as
+// navigator.credentials.get would
+func (a *Authenticator) Assert(options *webauthn.RequestOptions) (*webauthn.AssertionCredential, error) {
+	if a.key == nil {
+		return nil, errors.New("no credential registered")
+	}
+	if options.RPID != a.rpID {
+		return nil, fmt.Errorf("authenticator is for %q, not %q", a.rpID, options.RPID)
+	}
+
+	clientDataJSON, err := a.clientData("webauthn.get", options.Challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	a.SignCount++
+	authData := a.authenticatorData(0, a.SignCount)
+
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	digest := sha256.Sum256(append(append([]byte(nil), authData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		return nil, err
+	}
+
+	credential := &webauthn.AssertionCredential{
+		ID:    base64.RawURLEncoding.EncodeToString(a.credentialID),
+		RawID: a.credentialID,
+		Type:  "public-key",
+	}
+	credential.Response.ClientDataJSON = clientDataJSON
+	credential.Response.AuthenticatorData = authData
+	credential.Response.Signature = signature
+	credential.Response.UserHandle = a.userHandle
+	return credential, nil
+}
+
+func (a *Authenticator) clientData(ceremony, challenge string) ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		"type":        ceremony,
+		"challenge":   challenge,
+		"origin":      a.Origin,
+		"crossOrigin": false,
+	})
+}
+
+func (a *Authenticator) authenticatorData(flags byte, signCount uint32) []byte {
+	flags |= flagUserPresent
+	if a.UserVerified {
+		flags |= flagUserVerified
+	}
+
+	rpIDHash := sha256.Sum256([]byte(a.rpID))
+	data := append([]byte(nil), rpIDHash[:]...)
+	data = append(data, flags)
+	return binary.BigEndian.AppendUint32(data, signCount)
+}
+
+// coseKey encodes the public key as an EC2 P-256 COSE_Key
+func (a *Authenticator) coseKey() []byte {
+	x := make([]byte, 32)
+	y := make([]byte, 32)
+	a.key.PublicKey.X.FillBytes(x)
+	a.key.PublicKey.Y.FillBytes(y)
+
+	return encodeCBOR(map[interface{}]interface{}{
+		int64(1):  int64(2),
+		int64(3):  webauthn.AlgES256,
+		int64(-1): int64(1),
+		int64(-2): x,
+		int64(-3): y,
+	})
+}
+
+// encodeCBOR encodes the few CBOR types authenticators emit
+func encodeCBOR(value interface{}) []byte {
+	switch v := value.(type) {
+	case int64:
+		if v < 0 {
+			return cborHead(1, uint64(-1-v))
+		}
+		return cborHead(0, uint64(v))
+	case string:
+		return append(cborHead(3, uint64(len(v))), v...)
+	case []byte:
+		return append(cborHead(2, uint64(len(v))), v...)
+	case map[interface{}]interface{}:
+		// Sort keys so the output is deterministic
+		keys := make([][]byte, 0, len(v))
+		encoded := make(map[string][]byte, len(v))
+		for key, item := range v {
+			k := encodeCBOR(key)
+			keys = append(keys, k)
+			encoded[string(k)] = encodeCBOR(item)
+		}
+		sort.Slice(keys, func(i, j int) bool { return string(keys[i]) < string(keys[j]) })
+
+		out := cborHead(5, uint64(len(v)))
+		for _, k := range keys {
+			out = append(out, k...)
+			out = append(out, encoded[string(k)]...)
+		}
+		return out
+	}
+	panic(fmt.Sprintf("webauthntest: cannot encode %T", value))
+}
+
+func cborHead(major byte, arg uint64) []byte {
+	switch {
+	case arg < 24:
+		return []byte{major<<5 | byte(arg)}
+	case arg <= 0xff:
+		return []byte{major<<5 | 24, byte(arg)}
+	case arg <= 0xffff:
+		return binary.BigEndian.AppendUint16([]byte{major<<5 | 25}, uint16(arg))
+	case arg <= 0xffffffff:
+		return binary.BigEndian.AppendUint32([]byte{major<<5 | 26}, uint32(arg))
+	default:
+		return binary.BigEndian.AppendUint64([]byte{major<<5 | 27}, arg)
+	}
+}
@@ -0,0 +1,59 @@
+'use client';
+
+import { useEffect, useRef, useState } from 'react';
+import { useRouter } from 'next/navigation';
+import { completeOIDCLogin, saveAuth } from '@/lib/auth';
+import styles from '../../../login/login.module.css';
+
+export default function OIDCCallbackPage() {
+  const router = useRouter();
+  const [error, setError] = useState('');
+  const started = useRef(false);
+
+  useEffect(() => {
+    // The code can only be redeemed once, so guard against a second run
+    if (started.current) {
+      return;
+    }
+    started.current = true;
+
+    const params = new URLSearchParams(window.location.search);
+    const code = params.get('code');
+    const state = params.get('state');
+    if (!code || !state) {
+      setError(params.get('error_description') || params.get('error') || 'ログインに失敗しました');
+      return;
+    }
+
+    completeOIDCLogin(code, state)
+      .then((response) => {
+        if ('mfa_required' in response) {
+          router.replace(`/login?mfa_token=${encodeURIComponent(response.mfa_token)}`);
+          return;
+        }
+        saveAuth(response.token, response.user, response.refresh_token);
+        router.replace('/dashboard');
+      })
+      .catch((err: any) => setError(err.message));
+  }, [router]);
+
+  return (
+    <div className={styles.container}>
+      <div className={styles.card}>
+        <h1 className={styles.title}>ログイン</h1>
+        {error ? (
+          <>
+            <div className={styles.error}>{error}</div>
+            <p className={styles.link}>
+              <a href="/login" style={{ color: '#0070f3', textDecoration: 'underline' }}>
+                ログイン画面に戻る
+              </a>
+            </p>
+          </>
+        ) : (
+          <p>ログイン中...</p>
+        )}
+      </div>
+    </div>
+  );
+}
@@ -1,16 +1,41 @@
 'use client';
 
-import { useState } from 'react';
+import { useEffect, useState } from 'react';
 import { useRouter } from 'next/navigation';
-import { login, saveAuth } from '@/lib/auth';
+import { beginOIDCLogin, getOIDCProviders, login, saveAuth, verifyMFA } from '@/lib/auth';
 import styles from './login.module.css';
 
 export default function LoginPage() {
   const router = useRouter();
   const [email, setEmail] = useState('');
   const [password, setPassword] = useState('');
+  const [mfaToken, setMfaToken] = useState('');
+  const [code, setCode] = useState('');
   const [error, setError] = useState('');
   const [loading, setLoading] = useState(false);
+  const [providers, setProviders] = useState<string[]>([]);
+
+  useEffect(() => {
+    getOIDCProviders().then(setProviders);
+
+    // An identity provider login that needs a second factor continues here
+    const token = new URLSearchParams(window.location.search).get('mfa_token');
+    if (token) {
+      setMfaToken(token);
+    }
+  }, []);
+
+  const handleProviderLogin = async (provider: string) => {
+    setError('');
+    setLoading(true);
+
+    try {
+      window.location.href = await beginOIDCLogin(provider);
+    } catch (err: any) {
+      setError(err.message);
+      setLoading(false);
+    }
+  };
 
   const handleSubmit = async (e: React.FormEvent) => {
     e.preventDefault();
@@ -18,8 +43,12 @@ export default function LoginPage() {
     setLoading(true);
 
     try {
-      const response = await login(email, password);
-      saveAuth(response.token, response.user);
+      const response = mfaToken ? await verifyMFA(mfaToken, code) : await login(email, password);
+      if ('mfa_required' in response) {
+        setMfaToken(response.mfa_token);
+        return;
+      }
+      saveAuth(response.token, response.user, response.refresh_token);
       router.push('/dashboard');
     } catch (err: any) {
       setError(err.message);
@@ -35,35 +64,66 @@ export default function LoginPage() {
         <form onSubmit={handleSubmit} className={styles.form}>
           {error && <div className={styles.error}>{error}</div>}
 
-          <div className={styles.formGroup}>
-            <label htmlFor="email">メールアドレス</label>
-            <input
-              id="email"
-              type="email"
-              value={email}
-              onChange={(e) => setEmail(e.target.value)}
-              required
-              disabled={loading}
-            />
-          </div>
+          {mfaToken ? (
+            <div className={styles.formGroup}>
+              <label htmlFor="code">認証コード（またはリカバリーコード）</label>
+              <input
+                id="code"
+                type="text"
+                autoComplete="one-time-code"
+                value={code}
+                onChange={(e) => setCode(e.target.value)}
+                required
+                disabled={loading}
+              />
+            </div>
+          ) : (
+            <>
+              <div className={styles.formGroup}>
+                <label htmlFor="email">メールアドレス</label>
+                <input
+                  id="email"
+                  type="email"
+                  value={email}
+                  onChange={(e) => setEmail(e.target.value)}
+                  required
+                  disabled={loading}
+                />
+              </div>
 
-          <div className={styles.formGroup}>
-            <label htmlFor="password">パスワード</label>
-            <input
-              id="password"
-              type="password"
-              value={password}
-              onChange={(e) => setPassword(e.target.value)}
-              required
-              disabled={loading}
-            />
-          </div>
+              <div className={styles.formGroup}>
+                <label htmlFor="password">パスワード</label>
+                <input
+                  id="password"
+                  type="password"
+                  value={password}
+                  onChange={(e) => setPassword(e.target.value)}
+                  required
+                  disabled={loading}
+                />
+              </div>
+            </>
+          )}
 
           <button type="submit" className={styles.button} disabled={loading}>
             {loading ? 'ログイン中...' : 'ログイン'}
           </button>
         </form>
 
+        {!mfaToken &&
+          providers.map((provider) => (
+            <button
+              key={provider}
+              type="button"
+              className={styles.button}
+              onClick={() => handleProviderLogin(provider)}
+              disabled={loading}
+              style={{ marginTop: '0.5rem', width: '100%' }}
+            >
+              {provider} でログイン
+            </button>
+          ))}
+
         <p className={styles.link}>
           アカウントをお持ちでない方は{' '}
           <a href="/register" style={{ color: '#0070f3', textDecoration: 'underline' }}>
@@ -22,8 +22,8 @@ export default function RegisterPage() {
       return;
     }
 
-    if (password.length < 6) {
-      setError('パスワードは6文字以上で入力してください');
+    if (password.length < 8) {
+      setError('パスワードは8文字以上で入力してください');
       return;
     }
 
@@ -31,7 +31,7 @@ export default function RegisterPage() {
 
     try {
       const response = await register(email, password);
-      saveAuth(response.token, response.user);
+      saveAuth(response.token, response.user, response.refresh_token);
       router.push('/dashboard');
     } catch (err: any) {
       setError(err.message);
@@ -60,7 +60,7 @@ export default function RegisterPage() {
           </div>
 
           <div className={styles.formGroup}>
-            <label htmlFor="password">パスワード (6文字以上)</label>
+            <label htmlFor="password">パスワード (8文字以上)</label>
             <input
               id="password"
               type="password"
@@ -68,7 +68,7 @@ export default function RegisterPage() {
               onChange={(e) => setPassword(e.target.value)}
               required
               disabled={loading}
-              minLength={6}
+              minLength={8}
             />
           </div>
 
@@ -1,4 +1,5 @@
 import { Todo } from '@/types';
+import { refreshAuth } from './auth';
 
 const API_URL = process.env.NEXT_PUBLIC_BACKEND_URL || 'http://localhost:8000';
 
@@ -10,10 +11,18 @@ function getHeaders() {
   };
 }
 
+// authFetch sends an authenticated request and retries it once with a renewed
+// access token when the current one has expired.
+async function authFetch(url: string, init: RequestInit = {}): Promise<Response> {
+  const response = await fetch(url, { ...init, headers: getHeaders() });
+  if (response.status !== 401 || !(await refreshAuth())) {
+    return response;
+  }
+  return fetch(url, { ...init, headers: getHeaders() });
+}
+
 export async function getTodos(): Promise<Todo[]> {
-  const response = await fetch(`${API_URL}/api/todos`, {
-    headers: getHeaders(),
-  });
+  const response = await authFetch(`${API_URL}/api/todos`);
 
   if (!response.ok) {
     throw new Error('Failed to fetch todos');
@@ -23,9 +32,8 @@ export async function getTodos(): Promise<Todo[]> {
 }
 
 export async function createTodo(title: string, description?: string): Promise<Todo> {
-  const response = await fetch(`${API_URL}/api/todos`, {
+  const response = await authFetch(`${API_URL}/api/todos`, {
     method: 'POST',
-    headers: getHeaders(),
     body: JSON.stringify({ title, description }),
   });
 
@@ -40,9 +48,8 @@ export async function updateTodo(
   id: string,
   updates: { title?: string; description?: string; completed?: boolean }
 ): Promise<Todo> {
-  const response = await fetch(`${API_URL}/api/todos/${id}`, {
+  const response = await authFetch(`${API_URL}/api/todos/${id}`, {
     method: 'PUT',
-    headers: getHeaders(),
     body: JSON.stringify(updates),
   });
 
@@ -54,9 +61,8 @@ export async function updateTodo(
 }
 
 export async function deleteTodo(id: string): Promise<void> {
-  const response = await fetch(`${API_URL}/api/todos/${id}`, {
+  const response = await authFetch(`${API_URL}/api/todos/${id}`, {
     method: 'DELETE',
-    headers: getHeaders(),
   });
 
   if (!response.ok) {
@@ -65,10 +71,25 @@ export async function deleteTodo(id: string): Promise<void> {
 }
 
 // Admin API
-export async function getAllUsers() {
-  const response = await fetch(`${API_URL}/api/admin/users`, {
-    headers: getHeaders(),
+export interface UserSearchParams {
+  q?: string;
+  role?: string;
+  status?: string;
+  created_after?: string;
+  sort?: string;
+  limit?: number;
+  offset?: number;
+}
+
+export async function getAllUsers(params: UserSearchParams = {}) {
+  const query = new URLSearchParams();
+  Object.entries(params).forEach(([key, value]) => {
+    if (value !== undefined && value !== '') {
+      query.set(key, String(value));
+    }
   });
+  const suffix = query.toString() ? `?${query}` : '';
+  const response = await authFetch(`${API_URL}/api/admin/users${suffix}`);
 
   if (!response.ok) {
     throw new Error('Failed to fetch users');
@@ -78,9 +99,8 @@ export async function getAllUsers() {
 }
 
 export async function updateUserRole(userId: string, role: string) {
-  const response = await fetch(`${API_URL}/api/admin/users/${userId}/role`, {
+  const response = await authFetch(`${API_URL}/api/admin/users/${userId}/role`, {
     method: 'PUT',
-    headers: getHeaders(),
     body: JSON.stringify({ role }),
   });
 
@@ -92,9 +112,8 @@ export async function updateUserRole(userId: string, role: string) {
 }
 
 export async function deleteUser(userId: string) {
-  const response = await fetch(`${API_URL}/api/admin/users/${userId}`, {
+  const response = await authFetch(`${API_URL}/api/admin/users/${userId}`, {
     method: 'DELETE',
-    headers: getHeaders(),
   });
 
   if (!response.ok) {
@@ -103,9 +122,7 @@ export async function deleteUser(userId: string) {
 }
 
 export async function getAllTodosAdmin() {
-  const response = await fetch(`${API_URL}/api/admin/todos`, {
-    headers: getHeaders(),
-  });
+  const response = await authFetch(`${API_URL}/api/admin/todos`);
 
   if (!response.ok) {
     throw new Error('Failed to fetch all todos');
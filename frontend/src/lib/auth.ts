@@ -1,4 +1,4 @@
-import { AuthResponse } from '@/types';
+import { AuthResponse, MFAChallenge } from '@/types';
 
 const API_URL = process.env.NEXT_PUBLIC_BACKEND_URL || 'http://localhost:8000';
 
@@ -13,13 +13,16 @@ export async function register(email: string, password: string): Promise<AuthRes
 
   if (!response.ok) {
     const error = await response.json();
+    if (error.violations) {
+      throw new Error(error.violations.map((v: { message: string }) => v.message).join('\n'));
+    }
     throw new Error(error.error || 'Registration failed');
   }
 
   return response.json();
 }
 
-export async function login(email: string, password: string): Promise<AuthResponse> {
+export async function login(email: string, password: string): Promise<AuthResponse | MFAChallenge> {
   const response = await fetch(`${API_URL}/api/login`, {
     method: 'POST',
     headers: {
@@ -36,11 +39,104 @@ export async function login(email: string, password: string): Promise<AuthRespon
   return response.json();
 }
 
-export function saveAuth(token: string, user: any) {
+// Completes a login that returned an MFA challenge with a TOTP or recovery code
+export async function verifyMFA(mfaToken: string, code: string): Promise<AuthResponse> {
+  const response = await fetch(`${API_URL}/api/login/mfa`, {
+    method: 'POST',
+    headers: {
+      'Content-Type': 'application/json',
+    },
+    body: JSON.stringify({ mfa_token: mfaToken, code }),
+  });
+
+  if (!response.ok) {
+    const error = await response.json();
+    throw new Error(error.error || 'Verification failed');
+  }
+
+  return response.json();
+}
+
+// Lists the identity providers offered on the login page
+export async function getOIDCProviders(): Promise<string[]> {
+  const response = await fetch(`${API_URL}/api/oidc/providers`);
+  if (!response.ok) {
+    return [];
+  }
+
+  const data = await response.json();
+  return data.providers;
+}
+
+// Returns the identity provider URL to send the browser to
+export async function beginOIDCLogin(provider: string): Promise<string> {
+  const response = await fetch(`${API_URL}/api/oidc/${encodeURIComponent(provider)}/login`);
+
+  if (!response.ok) {
+    const error = await response.json();
+    throw new Error(error.error || 'Login failed');
+  }
+
+  const data = await response.json();
+  return data.authorization_url;
+}
+
+// Completes an identity provider login with the parameters it redirected back with
+export async function completeOIDCLogin(code: string, state: string): Promise<AuthResponse | MFAChallenge> {
+  const response = await fetch(`${API_URL}/api/oidc/callback`, {
+    method: 'POST',
+    headers: {
+      'Content-Type': 'application/json',
+    },
+    body: JSON.stringify({ code, state }),
+  });
+
+  if (!response.ok) {
+    const error = await response.json();
+    throw new Error(error.error || 'Login failed');
+  }
+
+  return response.json();
+}
+
+export function saveAuth(token: string, user: any, refreshToken?: string) {
   if (typeof window !== 'undefined') {
     localStorage.setItem('token', token);
     localStorage.setItem('user', JSON.stringify(user));
+    if (refreshToken) {
+      localStorage.setItem('refreshToken', refreshToken);
+    }
+  }
+}
+
+// Exchanges the stored refresh token for a new token pair. Returns false when
+// the session can no longer be renewed.
+export async function refreshAuth(): Promise<boolean> {
+  if (typeof window === 'undefined') {
+    return false;
+  }
+
+  const refreshToken = localStorage.getItem('refreshToken');
+  if (!refreshToken) {
+    return false;
+  }
+
+  const response = await fetch(`${API_URL}/api/token/refresh`, {
+    method: 'POST',
+    headers: {
+      'Content-Type': 'application/json',
+    },
+    body: JSON.stringify({ refresh_token: refreshToken }),
+  });
+
+  if (!response.ok) {
+    logout();
+    return false;
   }
+
+  const data: AuthResponse = await response.json();
+  saveAuth(data.token, data.user, data.refresh_token);
+  return true;
 }
 
 export function getToken(): string | null {
@@ -60,7 +156,17 @@ export function getUser(): any | null {
 
 export function logout() {
   if (typeof window !== 'undefined') {
+    const token = localStorage.getItem('token');
+    if (token) {
+      // Best effort: the local session is cleared even if the server is unreachable
+      fetch(`${API_URL}/api/logout`, {
+        method: 'POST',
+        headers: { Authorization: `Bearer ${token}` },
+      }).catch(() => {});
+    }
+
     localStorage.removeItem('token');
+    localStorage.removeItem('refreshToken');
     localStorage.removeItem('user');
   }
 }
@@ -2,10 +2,20 @@ export interface User {
   id: string;
   email: string;
   role: string;
+  email_verified_at?: string | null;
+  status?: 'active' | 'suspended' | 'pending_deletion';
+  suspended_at?: string | null;
+  suspension_reason?: string;
   created_at: string;
   updated_at: string;
 }
 
+export interface UserSummary extends User {
+  todo_count: number;
+  completed_count: number;
+  last_login_at: string | null;
+}
+
 export interface Todo {
   id: string;
   user_id: string;
@@ -18,5 +28,16 @@ export interface Todo {
 
 export interface AuthResponse {
   token: string;
+  expires_at: string;
+  refresh_token: string;
+  refresh_expires_at: string;
   user: User;
 }
+
+// Returned by login instead of tokens when the account has two-factor
+// authentication enabled
+export interface MFAChallenge {
+  mfa_required: true;
+  mfa_token: string;
+  expires_at: string;
+}
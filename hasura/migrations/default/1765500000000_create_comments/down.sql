@@ -0,0 +1,3 @@
+-- Drop tables
+DROP TABLE IF EXISTS comment_mentions;
+DROP TABLE IF EXISTS comments;
@@ -0,0 +1,19 @@
+-- Create attachments table
+-- todo_id is set to NULL when the todo is deleted so that a cleanup job can
+-- remove the stored blob before the row itself is purged
+CREATE TABLE attachments (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    todo_id UUID REFERENCES todos(id) ON DELETE SET NULL,
+    uploader_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    filename VARCHAR(255) NOT NULL,
+    content_type VARCHAR(255) NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    storage_key TEXT UNIQUE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Create index on todo_id
+CREATE INDEX idx_attachments_todo_id ON attachments(todo_id);
+
+-- Create partial index for orphaned attachments awaiting cleanup
+CREATE INDEX idx_attachments_orphaned ON attachments(created_at) WHERE todo_id IS NULL;
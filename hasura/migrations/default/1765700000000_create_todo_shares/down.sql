@@ -0,0 +1,2 @@
+-- Drop tables
+DROP TABLE IF EXISTS todo_shares;
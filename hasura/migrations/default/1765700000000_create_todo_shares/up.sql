@@ -0,0 +1,13 @@
+-- Create todo shares table
+CREATE TABLE todo_shares (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    todo_id UUID NOT NULL REFERENCES todos(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(50) NOT NULL CHECK (role IN ('viewer', 'editor', 'owner')),
+    granted_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (todo_id, user_id)
+);
+
+-- Create index on user_id
+CREATE INDEX idx_todo_shares_user_id ON todo_shares(user_id);
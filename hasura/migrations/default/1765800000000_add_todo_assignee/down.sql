@@ -0,0 +1,5 @@
+-- Drop tables
+DROP TABLE IF EXISTS todo_assignment_events;
+
+-- Drop assignee from todos
+ALTER TABLE todos DROP COLUMN IF EXISTS assignee_id;
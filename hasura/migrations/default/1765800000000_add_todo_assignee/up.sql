@@ -0,0 +1,18 @@
+-- Add assignee to todos
+ALTER TABLE todos ADD COLUMN assignee_id UUID REFERENCES users(id) ON DELETE SET NULL;
+
+-- Create index on assignee_id
+CREATE INDEX idx_todos_assignee_id ON todos(assignee_id);
+
+-- Create todo assignment events table
+CREATE TABLE todo_assignment_events (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    todo_id UUID NOT NULL REFERENCES todos(id) ON DELETE CASCADE,
+    assignee_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    previous_assignee_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    assigned_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Create index on todo_id
+CREATE INDEX idx_todo_assignment_events_todo_id ON todo_assignment_events(todo_id);
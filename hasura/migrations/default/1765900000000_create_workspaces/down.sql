@@ -0,0 +1,6 @@
+-- Drop workspace from todos
+ALTER TABLE todos DROP COLUMN IF EXISTS workspace_id;
+
+-- Drop tables
+DROP TABLE IF EXISTS workspace_members;
+DROP TABLE IF EXISTS workspaces;
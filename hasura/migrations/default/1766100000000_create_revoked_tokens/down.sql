@@ -0,0 +1,5 @@
+-- Drop revocation cutoff from users
+ALTER TABLE users DROP COLUMN IF EXISTS tokens_revoked_before;
+
+-- Drop revoked tokens table
+DROP TABLE IF EXISTS revoked_tokens;
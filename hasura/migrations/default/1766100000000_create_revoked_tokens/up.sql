@@ -0,0 +1,13 @@
+-- Create revoked tokens table
+CREATE TABLE revoked_tokens (
+    jti VARCHAR(64) PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Create index on expires_at for cleanup
+CREATE INDEX idx_revoked_tokens_expires_at ON revoked_tokens(expires_at);
+
+-- Tokens issued to a user before this moment are revoked
+ALTER TABLE users ADD COLUMN tokens_revoked_before TIMESTAMP WITH TIME ZONE;
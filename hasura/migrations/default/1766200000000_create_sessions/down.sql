@@ -0,0 +1,5 @@
+-- Detach refresh tokens from sessions
+ALTER TABLE refresh_tokens DROP CONSTRAINT IF EXISTS refresh_tokens_family_id_fkey;
+
+-- Drop sessions table
+DROP TABLE IF EXISTS sessions;
@@ -0,0 +1,2 @@
+-- Drop email verification timestamp from users
+ALTER TABLE users DROP COLUMN IF EXISTS email_verified_at;
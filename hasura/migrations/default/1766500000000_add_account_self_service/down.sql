@@ -0,0 +1,6 @@
+-- Drop scheduled deletion time from users
+DROP INDEX IF EXISTS idx_users_deletion_scheduled_at;
+ALTER TABLE users DROP COLUMN IF EXISTS deletion_scheduled_at;
+
+-- Drop the pending email address from user_tokens
+ALTER TABLE user_tokens DROP COLUMN IF EXISTS email;
@@ -0,0 +1,8 @@
+-- Add the pending address of an email change to user_tokens
+ALTER TABLE user_tokens ADD COLUMN email VARCHAR(255);
+
+-- Add scheduled deletion time to users
+ALTER TABLE users ADD COLUMN deletion_scheduled_at TIMESTAMP WITH TIME ZONE;
+
+-- Create index on deletion_scheduled_at
+CREATE INDEX idx_users_deletion_scheduled_at ON users(deletion_scheduled_at) WHERE deletion_scheduled_at IS NOT NULL;
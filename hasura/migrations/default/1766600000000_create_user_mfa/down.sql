@@ -0,0 +1,9 @@
+-- Drop MFA challenge attempt counter
+ALTER TABLE user_tokens DROP COLUMN IF EXISTS attempts;
+
+-- Drop session MFA timestamp
+ALTER TABLE sessions DROP COLUMN IF EXISTS mfa_verified_at;
+
+-- Drop MFA tables
+DROP TABLE IF EXISTS mfa_recovery_codes;
+DROP TABLE IF EXISTS user_mfa;
@@ -0,0 +1,17 @@
+-- Create personal access tokens table. Tokens are stored hashed; only the
+-- display prefix is kept in the clear.
+CREATE TABLE personal_access_tokens (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    token_prefix VARCHAR(20) NOT NULL,
+    scopes JSONB NOT NULL DEFAULT '[]',
+    mfa_verified BOOLEAN NOT NULL DEFAULT FALSE,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Create index on user_id
+CREATE INDEX idx_personal_access_tokens_user_id ON personal_access_tokens(user_id);
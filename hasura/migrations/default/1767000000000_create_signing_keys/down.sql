@@ -0,0 +1,2 @@
+-- Drop signing keys table
+DROP TABLE IF EXISTS signing_keys;
@@ -0,0 +1,3 @@
+-- Drop roles table
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_fkey;
+DROP TABLE IF EXISTS roles;
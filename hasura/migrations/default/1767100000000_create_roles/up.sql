@@ -0,0 +1,24 @@
+-- Create roles table. Permissions of the built-in roles are fixed in the
+-- backend: admin holds every permission and user none.
+CREATE TABLE roles (
+    name VARCHAR(50) PRIMARY KEY,
+    description VARCHAR(500) NOT NULL DEFAULT '',
+    permissions JSONB NOT NULL DEFAULT '[]',
+    built_in BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+INSERT INTO roles (name, description, built_in) VALUES
+    ('user', 'Regular user', TRUE),
+    ('admin', 'Administrator with every permission', TRUE);
+
+-- Keep any other role already assigned to a user
+INSERT INTO roles (name)
+SELECT DISTINCT role FROM users
+ON CONFLICT (name) DO NOTHING;
+
+-- Users can only be given roles that exist, and roles in use cannot be
+-- deleted
+ALTER TABLE users
+    ADD CONSTRAINT users_role_fkey FOREIGN KEY (role) REFERENCES roles(name);
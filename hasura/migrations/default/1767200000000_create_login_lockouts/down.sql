@@ -0,0 +1,2 @@
+-- Drop login lockouts table
+DROP TABLE IF EXISTS login_lockouts;
@@ -0,0 +1,13 @@
+-- Create login lockouts table. Failed logins are counted per account
+-- (keyed by email) and per client address; only the backend reads it.
+CREATE TABLE login_lockouts (
+    scope VARCHAR(10) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    failures INTEGER NOT NULL DEFAULT 0,
+    last_failed_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    locked_until TIMESTAMP WITH TIME ZONE,
+    PRIMARY KEY (scope, subject)
+);
+
+CREATE INDEX idx_login_lockouts_locked_until ON login_lockouts(locked_until);
+CREATE INDEX idx_login_lockouts_last_failed_at ON login_lockouts(last_failed_at);
@@ -0,0 +1,19 @@
+-- Create audit logs table. Each row is something an actor did to or as a
+-- user; the actor's email is copied so the trail outlives their account.
+CREATE TABLE audit_logs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    action VARCHAR(100) NOT NULL,
+    actor_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    actor_email VARCHAR(255) NOT NULL DEFAULT '',
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    method VARCHAR(10) NOT NULL DEFAULT '',
+    path TEXT NOT NULL DEFAULT '',
+    ip_address VARCHAR(45) NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    details JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_audit_logs_user_id ON audit_logs(user_id, created_at);
+CREATE INDEX idx_audit_logs_actor_id ON audit_logs(actor_id, created_at);
+CREATE INDEX idx_audit_logs_action ON audit_logs(action, created_at);
@@ -0,0 +1,12 @@
+-- Add account status to users: active, suspended by an administrator, or
+-- pending deletion during the grace period
+ALTER TABLE users ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'active'
+    CHECK (status IN ('active', 'suspended', 'pending_deletion'));
+ALTER TABLE users ADD COLUMN suspended_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE users ADD COLUMN suspension_reason VARCHAR(500) NOT NULL DEFAULT '';
+
+-- Accounts already scheduled for deletion are pending deletion
+UPDATE users SET status = 'pending_deletion' WHERE deletion_scheduled_at IS NOT NULL;
+
+-- Create index on status
+CREATE INDEX idx_users_status ON users(status);
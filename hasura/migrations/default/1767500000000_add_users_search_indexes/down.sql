@@ -0,0 +1,3 @@
+-- Drop user search indexes
+DROP INDEX IF EXISTS idx_users_created_at;
+DROP INDEX IF EXISTS idx_users_email_trgm;
@@ -0,0 +1,8 @@
+-- Enable trigram matching for searching users by part of their email
+CREATE EXTENSION IF NOT EXISTS "pg_trgm";
+
+-- Create index for case-insensitive email search
+CREATE INDEX idx_users_email_trgm ON users USING GIN (email gin_trgm_ops);
+
+-- Create index on created_at
+CREATE INDEX idx_users_created_at ON users(created_at);
@@ -0,0 +1,2 @@
+-- Remove the user of linking logins
+ALTER TABLE oidc_login_states DROP COLUMN IF EXISTS user_id;
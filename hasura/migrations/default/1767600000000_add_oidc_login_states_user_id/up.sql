@@ -0,0 +1,3 @@
+-- Add the signed-in user a pending OpenID Connect login links its provider
+-- account to
+ALTER TABLE oidc_login_states ADD COLUMN user_id UUID REFERENCES users(id) ON DELETE CASCADE;